@@ -2,14 +2,19 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"reflect"
 	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
 	"github.com/mjmorales/daemon-control/internal/core"
 )
 
@@ -25,82 +30,137 @@ var configInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize configuration",
 	Long:  `Initialize the daemon-control configuration with default values.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		manager := core.NewManager()
+	RunE: locked(func(cmd *cobra.Command, args []string) error {
+		manager := newCoreManager(cmd)
 		if err := manager.Init(); err != nil {
-			log.Error().Err(err).Msg("Failed to initialize configuration")
-			os.Exit(1)
+			return fmt.Errorf("failed to initialize configuration: %w", err)
 		}
-		log.Info().Str("path", core.ConfigPath()).Msg("Configuration initialized")
-	},
+		log.Info().Str("path", selectedCoreConfigPath()).Msg("Configuration initialized")
+		return nil
+	}),
 }
 
+var configShowSources bool
+
 // configShowCmd represents the config show command
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
 	Long:  `Display the current daemon-control configuration.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		manager := core.NewManager()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager := newCoreManager(cmd)
 		if err := manager.Init(); err != nil {
-			log.Error().Err(err).Msg("Failed to load configuration")
-			os.Exit(1)
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
 		config := manager.GetConfig()
 		if config == nil {
-			log.Error().Msg("No configuration loaded")
-			os.Exit(1)
+			return fmt.Errorf("no configuration loaded")
 		}
 
-		// Marshal to YAML for display
-		data, err := yaml.Marshal(config)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to format configuration")
-			os.Exit(1)
+		if configShowSources {
+			return printResult(configSourcesResult{Settings: manager.Settings()})
 		}
-
-		fmt.Println("Current configuration:")
-		fmt.Println("---------------------")
-		fmt.Print(string(data))
+		return printResult((*configShowResult)(config))
 	},
 }
 
+// configShowResult is the result of the config show command
+type configShowResult core.CoreConfig
+
+func (r *configShowResult) writeText(w io.Writer) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "Current configuration:")
+	fmt.Fprintln(w, "---------------------")
+	_, err = w.Write(data)
+	return err
+}
+
+// configSourcesResult is the result of config show --sources
+type configSourcesResult struct {
+	Settings []core.Setting `json:"settings" yaml:"settings"`
+}
+
+func (r configSourcesResult) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, setting := range r.Settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", setting.Key, inlineValue(setting.Value), setting.Source)
+	}
+	return tw.Flush()
+}
+
+// configValueResult is the result of the config get command
+type configValueResult struct {
+	Key   string      `json:"key" yaml:"key"`
+	Value interface{} `json:"value" yaml:"value"`
+}
+
+func (r configValueResult) writeText(w io.Writer) error {
+	if !isCollection(r.Value) {
+		_, err := fmt.Fprintf(w, "%v\n", r.Value)
+		return err
+	}
+	data, err := yaml.Marshal(r.Value)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// isCollection reports whether v is a map or list, which %v prints as Go
+// syntax rather than as it is written in the config file
+func isCollection(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// inlineValue formats v for a table cell. Maps and lists are rendered as
+// one line of flow-style YAML.
+func inlineValue(v interface{}) string {
+	if !isCollection(v) {
+		return fmt.Sprint(v)
+	}
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	node.Style = yaml.FlowStyle
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // configGetCmd represents the config get command
 var configGetCmd = &cobra.Command{
 	Use:   "get <key>",
 	Short: "Get a configuration value",
 	Long:  `Get a specific configuration value by key.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		key := args[0]
 
-		manager := core.NewManager()
+		manager := newCoreManager(cmd)
 		if err := manager.Init(); err != nil {
-			log.Error().Err(err).Msg("Failed to load configuration")
-			os.Exit(1)
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
 		value, err := manager.Get(key)
 		if err != nil {
-			log.Error().Err(err).Str("key", key).Msg("Failed to get configuration value")
-			os.Exit(1)
-		}
-
-		// Format output based on type
-		switch v := value.(type) {
-		case string:
-			fmt.Println(v)
-		case bool:
-			fmt.Println(v)
-		case int, int64, float64:
-			fmt.Println(v)
-		case map[string]interface{}:
-			data, _ := yaml.Marshal(v)
-			fmt.Print(string(data))
-		default:
-			fmt.Printf("%v\n", v)
+			return fmt.Errorf("failed to get configuration value: %w", err)
 		}
+
+		return printResult(configValueResult{Key: key, Value: value})
 	},
 }
 
@@ -110,103 +170,263 @@ var configSetCmd = &cobra.Command{
 	Short: "Set a configuration value",
 	Long: `Set a configuration value by key.
 	
+Values are checked against the key's type before anything is written.
 For boolean values, use: true, false, yes, no, on, off
-For map values (like custom_env_vars), use key.subkey format`,
+For map values (like custom_env_vars), use key.subkey format
+For keys in a block (like notify), use block.key, e.g. notify.webhook
+
+Run 'daemon-control config list' to see every key and its allowed values.`,
 	Args: cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: locked(func(cmd *cobra.Command, args []string) error {
 		key := args[0]
 		value := args[1]
 
-		manager := core.NewManager()
+		manager := newCoreManager(cmd)
 		if err := manager.Init(); err != nil {
-			log.Error().Err(err).Msg("Failed to load configuration")
-			os.Exit(1)
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		// Parse value based on key type
-		var parsedValue interface{}
-
-		// Check if it's a boolean field
-		boolFields := []string{"auto_generate_plists", "backup_on_generate", "validate_plists", "use_system_launchd"}
-		isBoolField := false
-		for _, field := range boolFields {
-			if key == field {
-				isBoolField = true
-				break
-			}
+		if err := manager.SetValue(key, value); err != nil {
+			return fmt.Errorf("failed to set configuration value: %w", err)
 		}
 
-		if isBoolField {
-			// Parse boolean
-			switch strings.ToLower(value) {
-			case "true", "yes", "on", "1":
-				parsedValue = true
-			case "false", "no", "off", "0":
-				parsedValue = false
-			default:
-				log.Error().Str("value", value).Msg("Invalid boolean value")
-				os.Exit(1)
-			}
-		} else if strings.HasPrefix(key, "custom_env_vars.") {
-			// Handle map values
-			parts := strings.SplitN(key, ".", 2)
-			if len(parts) == 2 {
-				// Get current map
-				currentVal, _ := manager.Get("custom_env_vars")
-				envVars, ok := currentVal.(map[string]interface{})
-				if !ok {
-					envVars = make(map[string]interface{})
-				}
-
-				// Set the specific key
-				envVars[parts[1]] = value
-				key = "custom_env_vars"
-				parsedValue = envVars
-			}
-		} else {
-			// Use string value as-is
-			parsedValue = value
+		log.Info().Str("key", key).Str("value", value).Msg("Configuration updated")
+		return nil
+	}),
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Reset a configuration value to its default",
+	Long: `Remove a key from the core config file so its default applies again.
+
+Map entries are removed with key.name, for example:
+
+  daemon-control config unset custom_env_vars.API_TOKEN
+
+A block is removed with all its keys by its name:
+
+  daemon-control config unset notify`,
+	Args: cobra.ExactArgs(1),
+	RunE: locked(func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+
+		manager := newCoreManager(cmd)
+		if err := manager.Init(); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		if err := manager.Set(key, parsedValue); err != nil {
-			log.Error().Err(err).Msg("Failed to set configuration value")
-			os.Exit(1)
+		if err := manager.Unset(key); err != nil {
+			return fmt.Errorf("failed to unset configuration value: %w", err)
 		}
 
-		log.Info().Str("key", key).Interface("value", parsedValue).Msg("Configuration updated")
-	},
+		log.Info().Str("key", key).Msg("Configuration value reset")
+		return nil
+	}),
 }
 
 // configListCmd represents the config list command
 var configListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all configuration keys",
-	Long:  `List all available configuration keys.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		keys := core.ValidKeys()
-		sort.Strings(keys)
+	Long:  `List all available configuration keys with their type, current value and description.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager := newCoreManager(cmd)
+		if err := manager.Init(); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
 
-		fmt.Println("Available configuration keys:")
-		fmt.Println("----------------------------")
-		for _, key := range keys {
-			fmt.Printf("  %s\n", key)
+		result := configKeysResult{Keys: []configKey{}}
+		for _, info := range core.Keys() {
+			value, _ := manager.Get(info.Key)
+			result.Keys = append(result.Keys, configKey{KeyInfo: info, Value: value})
 		}
+		sort.Slice(result.Keys, func(i, j int) bool { return result.Keys[i].Key < result.Keys[j].Key })
 
-		fmt.Println("\nFor map values like custom_env_vars, use dot notation:")
-		fmt.Println("  custom_env_vars.KEY_NAME")
+		return printResult(result)
 	},
 }
 
+// configKey is a configuration key with its current value
+type configKey struct {
+	core.KeyInfo `yaml:",inline"`
+	Value        interface{} `json:"value" yaml:"value"`
+}
+
+// configKeysResult is the result of the config list command
+type configKeysResult struct {
+	Keys []configKey `json:"keys" yaml:"keys"`
+}
+
+func (r configKeysResult) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tTYPE\tVALUE\tDESCRIPTION")
+	for _, key := range r.Keys {
+		keyType := string(key.Type)
+		if len(key.Allowed) > 0 {
+			keyType = strings.Join(key.Allowed, "|")
+		}
+		value := "-"
+		if key.Value != nil {
+			value = inlineValue(key.Value)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", key.Key, keyType, value, key.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nFor map values like custom_env_vars, use dot notation:")
+	fmt.Fprintln(w, "  custom_env_vars.KEY_NAME")
+	fmt.Fprintln(w, "For list values like inherit_env_vars, use a comma-separated value:")
+	_, err := fmt.Fprintln(w, "  inherit_env_vars PATH,SSH_AUTH_SOCK")
+	return err
+}
+
 // configPathCmd represents the config path command
 var configPathCmd = &cobra.Command{
 	Use:   "path",
 	Short: "Show configuration file path",
 	Long:  `Display the path to the core configuration file.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(core.ConfigPath())
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printResult(configPathResult{Path: selectedCoreConfigPath()})
+	},
+}
+
+// configPathResult is the result of the config path command
+type configPathResult struct {
+	Path string `json:"path" yaml:"path"`
+}
+
+func (r configPathResult) writeText(w io.Writer) error {
+	_, err := fmt.Fprintln(w, r.Path)
+	return err
+}
+
+// configRenderCmd represents the config render command
+var configRenderCmd = &cobra.Command{
+	Use:   "render [daemon-name...]",
+	Short: "Show daemon definitions as they will be generated",
+	Long: `Show the daemon definitions with the core configuration applied, as
+generate would use them.
+
+Each daemon's environment_variables are merged from, lowest precedence first:
+  1. inherit_env_vars, copied from the current environment
+  2. custom_env_vars
+  3. the daemon's own environment_variables
+
+With no arguments every daemon is shown.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := renderDaemons(appFrom(cmd), args)
+		if err != nil {
+			return err
+		}
+		return printResult(result)
 	},
 }
 
+// configRenderResult is the result of the config render command
+type configRenderResult config.Config
+
+func (r *configRenderResult) writeText(w io.Writer) error {
+	data, err := config.Marshal((*config.Config)(r))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// renderDaemons returns the named daemons, or all of them, with the core
+// configuration's environment merged in
+func renderDaemons(a *app.App, names []string) (*configRenderResult, error) {
+	loader := config.NewLoader(a.DaemonConfigPath())
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	daemons := cfg.Daemons
+	if len(names) > 0 {
+		daemons = make([]config.Daemon, 0, len(names))
+		for _, name := range names {
+			daemon, err := loader.GetDaemon(name)
+			if err != nil {
+				return nil, err
+			}
+			daemons = append(daemons, *daemon)
+		}
+	}
+
+	global := a.Config.DaemonEnvironment()
+	result := &configRenderResult{Version: cfg.Version, Daemons: make([]config.Daemon, 0, len(daemons))}
+	for _, daemon := range daemons {
+		daemon.EnvironmentVariables = daemon.EffectiveEnvironment(global)
+		result.Daemons = append(result.Daemons, daemon)
+	}
+	return result, nil
+}
+
+var configMigrateDryRun bool
+
+// configMigrateCmd represents the config migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade configuration files to the current schema",
+	Long: `Upgrade the core configuration and the daemon configuration to the
+schema version understood by this build of daemon-control.
+
+Each migrated file is rewritten in place and the original is kept next to it
+as <file>.v<version>.bak. Use --dry-run to preview the changes as a diff.`,
+	RunE: locked(func(cmd *cobra.Command, args []string) error {
+		result := configMigrateResult{Files: []*core.MigrationResult{}}
+
+		migrated, err := core.MigrateCoreConfig(selectedCoreConfigPath(), configMigrateDryRun)
+		if err != nil {
+			return fmt.Errorf("failed to migrate core configuration: %w", err)
+		}
+		result.Files = append(result.Files, migrated)
+
+		daemonConfigPath := appFrom(cmd).DaemonConfigPath()
+		if _, err := os.Stat(daemonConfigPath); err != nil {
+			log.Info().Str("path", daemonConfigPath).Msg("No daemon configuration to migrate")
+			return printResult(result)
+		}
+
+		migrated, err = core.MigrateDaemonConfig(daemonConfigPath, configMigrateDryRun)
+		if err != nil {
+			return fmt.Errorf("failed to migrate daemon configuration: %w", err)
+		}
+		result.Files = append(result.Files, migrated)
+
+		return printResult(result)
+	}),
+}
+
+// configMigrateResult is the result of the config migrate command
+type configMigrateResult struct {
+	Files []*core.MigrationResult `json:"files" yaml:"files"`
+}
+
+func (r configMigrateResult) writeText(w io.Writer) error {
+	for _, file := range r.Files {
+		switch {
+		case !file.Changed():
+			fmt.Fprintf(w, "%s is up to date (version %d)\n", file.Path, file.FromVersion)
+		case file.DryRun:
+			fmt.Fprintf(w, "%s would be migrated from version %d to %d:\n", file.Path, file.FromVersion, file.ToVersion)
+			fmt.Fprint(w, file.Diff)
+		default:
+			fmt.Fprintf(w, "%s migrated from version %d to %d (backup: %s)\n", file.Path, file.FromVersion, file.ToVersion, file.Backup)
+		}
+		for _, applied := range file.Applied {
+			fmt.Fprintf(w, "  %s\n", applied)
+		}
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 
@@ -215,6 +435,12 @@ func init() {
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
 	configCmd.AddCommand(configListCmd)
 	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configRenderCmd)
+
+	configShowCmd.Flags().BoolVar(&configShowSources, "sources", false, "Show where each value came from")
+	configMigrateCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "Show the changes without writing them")
 }
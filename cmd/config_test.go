@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/core"
+)
+
+func TestConfigValueResult_WriteText(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "string", value: "info", want: "info\n"},
+		{name: "bool", value: true, want: "true\n"},
+		{name: "string map", value: map[string]string{"FOO": "bar", "BAZ": "qux"}, want: "BAZ: qux\nFOO: bar\n"},
+		{name: "any map", value: map[string]interface{}{"FOO": "bar"}, want: "FOO: bar\n"},
+		{name: "list", value: []string{"PATH", "HOME"}, want: "- PATH\n- HOME\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, configValueResult{Key: "key", Value: tt.value}.writeText(&buf))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestConfigSourcesResult_WriteText(t *testing.T) {
+	result := configSourcesResult{Settings: []core.Setting{
+		{Key: "custom_env_vars", Value: map[string]string{"FOO": "bar"}, Source: core.Source{Kind: core.SourceFile}},
+		{Key: "inherit_env_vars", Value: []string{"PATH", "HOME"}, Source: core.Source{Kind: core.SourceDefault}},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, result.writeText(&buf))
+	assert.Equal(t, "KEY               VALUE         SOURCE\n"+
+		"custom_env_vars   {FOO: bar}    file\n"+
+		"inherit_env_vars  [PATH, HOME]  default\n", buf.String())
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/plist"
+)
+
+// Exit codes for the diff command, following diff(1)
+const (
+	diffExitSame    = 0
+	diffExitDiffers = 1
+	diffExitError   = 2
+)
+
+var (
+	diffUnified bool
+	diffContext int
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <daemon-name>",
+	Short: "Show differences between generated and deployed plists",
+	Long: `Compare the plist generated from the current configuration with the
+copy in the daemons directory and the installed copy in LaunchAgents.
+
+Differences are reported per key path, for example:
+
+  KeepAlive.Crashed: true → false
+
+Use --unified for a text diff instead. The exit status is 0 when all copies
+match, 1 when any differ or are missing, and 2 on error.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		differs, err := runDiff(appFrom(cmd), args[0])
+		if err != nil {
+			return errs.WithExitCode(fmt.Errorf("failed to diff daemon: %w", err), diffExitError)
+		}
+		if differs {
+			return errs.Silent(diffExitDiffers)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().BoolVarP(&diffUnified, "unified", "u", false, "Show a unified text diff")
+	diffCmd.Flags().IntVarP(&diffContext, "context", "U", 3, "Lines of context for unified diffs")
+}
+
+// diffTarget is an on-disk plist compared against the generated one
+type diffTarget struct {
+	name string
+	path string
+}
+
+// runDiff prints the differences for a daemon and reports whether any exist
+func runDiff(a *app.App, daemonName string) (bool, error) {
+	configPath := a.DaemonConfigPath()
+
+	loader := config.NewLoader(configPath)
+	if _, err := loader.Load(); err != nil {
+		return false, err
+	}
+	daemon, err := loader.GetDaemon(daemonName)
+	if err != nil {
+		return false, err
+	}
+
+	generated := plist.NewGenerator("").WithEnvironment(a.Config.DaemonEnvironment()).Build(daemon)
+
+	daemonsPath := a.PlistPath(daemonName)
+	daemonsCopy, err := readPlistIfExists(daemonsPath)
+	if err != nil {
+		return false, err
+	}
+
+	// The installed copy is named after the label it was installed with
+	label := daemon.Label
+	if daemonsCopy != nil {
+		if installedLabel, ok := daemonsCopy.Dict.GetString("Label"); ok && installedLabel != "" {
+			label = installedLabel
+		}
+	}
+
+	differs := false
+	for _, target := range []diffTarget{
+		{name: "daemons directory", path: daemonsPath},
+		{name: "installed", path: a.InstalledPath(label)},
+	} {
+		targetDiffers, err := printDiff(target, generated)
+		if err != nil {
+			return false, err
+		}
+		differs = differs || targetDiffers
+	}
+
+	return differs, nil
+}
+
+// printDiff compares a target against the generated plist
+func printDiff(target diffTarget, generated *plist.Plist) (bool, error) {
+	current, err := readPlistIfExists(target.path)
+	if err != nil {
+		return false, err
+	}
+
+	if diffUnified {
+		text, err := plist.UnifiedDiff(current, generated, target.path, "generated", diffContext)
+		if err != nil {
+			return false, err
+		}
+		fmt.Print(text)
+		return text != "", nil
+	}
+
+	fmt.Printf("%s (%s) → generated:\n", target.name, target.path)
+	if current == nil {
+		fmt.Println("  not present")
+		return true, nil
+	}
+
+	changes := plist.Diff(current.Dict, generated.Dict)
+	if len(changes) == 0 {
+		fmt.Println("  identical")
+		return false, nil
+	}
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+	return true, nil
+}
+
+// readPlistIfExists parses a plist file, returning nil if it does not exist
+func readPlistIfExists(path string) (*plist.Plist, error) {
+	p, err := plist.ParseFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return p, err
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/app"
 )
 
 var (
@@ -26,11 +26,8 @@ var editCmd = &cobra.Command{
 	
 The editor is determined by the EDITOR environment variable,
 or falls back to common editors based on your platform.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := runEdit(); err != nil {
-			log.Error().Err(err).Msg("Failed to open editor")
-			os.Exit(1)
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEdit(appFrom(cmd))
 	},
 }
 
@@ -40,18 +37,17 @@ func init() {
 	editCmd.Flags().BoolVar(&editCore, "core", false, "Edit core configuration instead of daemon configuration")
 }
 
-func runEdit() error {
+func runEdit(a *app.App) error {
 	// Determine which config file to edit
 	var configPath string
 
 	if editCore {
 		// Edit core config
-		configPath = core.ConfigPath()
+		configPath = selectedCoreConfigPath()
 		log.Info().Str("path", configPath).Msg("Opening core configuration")
 	} else {
 		// Edit daemon config
-		manager := core.GetManager()
-		configPath = manager.GetDaemonConfigPath()
+		configPath = a.DaemonConfigPath()
 
 		// Check if file exists, create if not
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -61,6 +57,8 @@ func runEdit() error {
 			exampleConfig := `# Daemon configuration file
 # Define your daemons here
 
+version: 1
+
 daemons:
   # Example daemon
   # - name: my-daemon
@@ -104,8 +102,7 @@ daemons:
 
 	// If editing daemon config, offer to generate plists
 	if !editCore {
-		fmt.Println("\nDaemon configuration edited.")
-		fmt.Println("Run 'daemon-control generate' to generate/update plist files.")
+		log.Info().Msg("Daemon configuration edited. Run 'daemon-control generate' to generate/update plist files.")
 	}
 
 	return nil
@@ -161,12 +158,9 @@ var editDaemonCmd = &cobra.Command{
 	Use:   "edit-daemon",
 	Short: "Open daemon configuration in editor (alias for 'edit')",
 	Long:  `Open the daemon configuration file in your default editor.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		editCore = false
-		if err := runEdit(); err != nil {
-			log.Error().Err(err).Msg("Failed to open editor")
-			os.Exit(1)
-		}
+		return runEdit(appFrom(cmd))
 	},
 }
 
@@ -174,12 +168,9 @@ var editCoreCmd = &cobra.Command{
 	Use:   "edit-core",
 	Short: "Open core configuration in editor (alias for 'edit --core')",
 	Long:  `Open the core configuration file in your default editor.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		editCore = true
-		if err := runEdit(); err != nil {
-			log.Error().Err(err).Msg("Failed to open editor")
-			os.Exit(1)
-		}
+		return runEdit(appFrom(cmd))
 	},
 }
 
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/plist"
+	"github.com/mjmorales/daemon-control/internal/utils"
+)
+
+var (
+	exportInstalled bool
+	exportPrefix    string
+	exportFile      string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export plists back to a daemon configuration",
+	Long: `Read plist files and write a daemons.yaml that reproduces them.
+
+By default the plists in the daemons directory are exported. With
+--installed, every plist in LaunchAgents is exported instead, which is
+useful for auditing what is actually loaded on a machine. Keys the schema
+does not model are kept under extra_plist_keys. A plist whose label was
+already exported is skipped with a warning, and the output is validated as
+load would before it is written.
+
+Running generate on the output reproduces the same plists. Any value that
+cannot be reproduced exactly is reported as a warning.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := runExport(appFrom(cmd)); err != nil {
+			return fmt.Errorf("failed to export daemons: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().BoolVar(&exportInstalled, "installed", false, "Export installed plists from LaunchAgents")
+	exportCmd.Flags().StringVar(&exportPrefix, "prefix", "", "Only export daemons whose label starts with this prefix")
+	exportCmd.Flags().StringVarP(&exportFile, "file", "f", "", "Write the configuration to a file instead of stdout")
+}
+
+func runExport(a *app.App) error {
+	dir := a.DaemonsDir
+	if exportInstalled {
+		dir = a.LaunchAgentsDir
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.plist"))
+	if err != nil {
+		return err
+	}
+
+	cfg := &config.Config{Version: config.SchemaVersion}
+	names := make(map[string]bool)
+	labels := make(map[string]string)
+	for _, file := range files {
+		p, err := plist.ParseFile(file)
+		if err != nil {
+			log.Warn().Err(err).Str("file", file).Msg("Skipping unreadable plist")
+			continue
+		}
+
+		label, _ := p.Dict.GetString("Label")
+		if !strings.HasPrefix(label, exportPrefix) {
+			continue
+		}
+		// launchd loads one job per label, so a second plist with the same
+		// label cannot be generated alongside the first
+		if other, ok := labels[label]; ok {
+			log.Warn().Str("file", file).Str("label", label).Str("daemon", other).Msg("Skipping plist with a duplicate label")
+			continue
+		}
+
+		name := uniqueName(exportName(strings.TrimSuffix(filepath.Base(file), ".plist")), label, names)
+
+		daemon, notes, err := plist.ToDaemon(name, p)
+		if err != nil {
+			log.Warn().Err(err).Str("file", file).Msg("Skipping plist")
+			continue
+		}
+		for _, note := range notes {
+			log.Warn().Str("daemon", name).Str("change", note).Msg("Value will not be reproduced by generate")
+		}
+
+		names[name] = true
+		labels[label] = name
+		cfg.Daemons = append(cfg.Daemons, *daemon)
+	}
+
+	if len(cfg.Daemons) == 0 {
+		log.Warn().Str("path", dir).Msg("No plists to export")
+	}
+
+	// Write only what load and generate accept
+	if err := config.Validate(cfg); err != nil {
+		return err
+	}
+
+	data, err := config.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	if exportFile == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+
+	if err := utils.WriteFileAtomic(exportFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", exportFile, err)
+	}
+	log.Info().
+		Int("count", len(cfg.Daemons)).
+		Str("file", exportFile).
+		Msg("Exported daemon configuration")
+	return nil
+}
+
+// uniqueName returns name, or the label when another daemon already has
+// that name, numbered if the label is taken too
+func uniqueName(name, label string, names map[string]bool) string {
+	if !names[name] {
+		return name
+	}
+	if label != "" && !names[label] {
+		return label
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !names[candidate] {
+			return candidate
+		}
+	}
+}
+
+// exportName derives a daemon name from a plist file name, dropping the
+// label prefix used to select it
+func exportName(base string) string {
+	name := strings.TrimLeft(strings.TrimPrefix(base, exportPrefix), ".-_")
+	if name == "" {
+		return base
+	}
+	return name
+}
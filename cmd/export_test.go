@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/launchd/launchdtest"
+)
+
+const exportTestPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/usr/bin/true</string>
+	</array>
+</dict>
+</plist>
+`
+
+func TestRunExport_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	coreCfg := core.DefaultConfig()
+	coreCfg.DaemonsDir = filepath.Join(dir, "daemons")
+	a := app.New(coreCfg, &launchdtest.Backend{}, zerolog.Nop())
+
+	// Both com.example.web and web export as web, and copy repeats a label
+	plists := map[string]string{
+		"com.example.web": "com.example.web",
+		"web":             "com.example.web",
+		"copy":            "com.example.web",
+		"com.example.api": "com.example.api",
+		"api":             "com.example.api-2",
+	}
+	require.NoError(t, os.MkdirAll(a.DaemonsDir, 0755))
+	for name, label := range plists {
+		data := []byte(fmt.Sprintf(exportTestPlist, label))
+		require.NoError(t, os.WriteFile(a.PlistPath(name), data, 0644))
+	}
+
+	path := filepath.Join(dir, "daemons.yaml")
+	exportPrefix, exportFile = "com.example", path
+	t.Cleanup(func() { exportPrefix, exportFile = "", "" })
+	require.NoError(t, runExport(a))
+
+	cfg, err := config.NewLoader(path).Load()
+	require.NoError(t, err)
+
+	labels := map[string]string{}
+	for _, daemon := range cfg.Daemons {
+		labels[daemon.Name] = daemon.Label
+	}
+	assert.Equal(t, map[string]string{
+		"api":             "com.example.api-2",
+		"com.example.api": "com.example.api",
+		"web":             "com.example.web",
+	}, labels)
+}
@@ -1,126 +1,225 @@
 package cmd
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/mjmorales/daemon-control/internal/app"
 	"github.com/mjmorales/daemon-control/internal/config"
-	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/history"
 	"github.com/mjmorales/daemon-control/internal/plist"
 	"github.com/mjmorales/daemon-control/internal/utils"
 )
 
 var (
-	configFile string
-	outputDir  string
+	generateForce bool
+	// generateLegacyOutput is set when the output directory was given with
+	// the deprecated -o/--output
+	generateLegacyOutput bool
 )
 
 // generateCmd represents the generate command
 var generateCmd = &cobra.Command{
-	Use:   "generate",
+	Use:   "generate [daemon-name...]",
 	Short: "Generate plist files from configuration",
 	Long: `Generate macOS plist files from a daemon configuration file.
 	
 This command reads a YAML configuration file containing daemon definitions
-and generates corresponding plist files that can be used with launchd.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := runGenerate(); err != nil {
-			log.Error().Err(err).Msg("Failed to generate plist files")
-			os.Exit(1)
+and generates corresponding plist files that can be used with launchd.
+
+Only plists whose daemon definition changed since the last run are
+written, so unchanged files keep their modification times. Pass daemon
+names to generate only those daemons, or --force to rewrite every plist.
+
+-o/--output selects the result format. For compatibility with earlier
+versions, generate -o <dir> with a value other than text, json or yaml
+still sets the output directory, with a deprecation warning; use
+-d/--output-dir instead.`,
+	RunE: locked(func(cmd *cobra.Command, args []string) error {
+		if generateLegacyOutput {
+			log.Warn().Msg("generate -o/--output <dir> is deprecated, use -d/--output-dir")
 		}
-	},
+		result, err := runGenerate(appFrom(cmd), args, generateForce)
+		if err != nil {
+			return fmt.Errorf("failed to generate plist files: %w", err)
+		}
+		return printResult(result)
+	}),
 }
 
 func init() {
 	rootCmd.AddCommand(generateCmd)
 
-	generateCmd.Flags().StringVarP(&configFile, "config", "c", "", "Configuration file path (default: from core config)")
-	generateCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory for generated plist files (default: from core config)")
+	generateCmd.Flags().StringP("output-dir", "d", "", "Output directory for generated plist files (env DAEMON_CONTROL_OUTPUT_DIR)")
+	generateCmd.Flags().BoolVar(&generateForce, "force", false, "Rewrite every plist, even if its definition has not changed")
 }
 
-func runGenerate() error {
-	// Get core config
-	coreManager := core.GetManager()
-	coreConfig := coreManager.GetConfig()
-
-	// Determine config file path
-	configPath := configFile
-	if configPath == "" {
-		configPath = coreManager.GetDaemonConfigPath()
+// legacyOutputDir accepts generate -o/--output <dir>, which set the output
+// directory before --output selected the result format. A value that is
+// not a format is taken as the directory, with a deprecation warning.
+func legacyOutputDir(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("output-dir") {
+		return fmt.Errorf("invalid output format %q: must be text, json or yaml", outputFormat)
 	}
+	if err := cmd.Flags().Set("output-dir", outputFormat); err != nil {
+		return err
+	}
+	outputFormat = outputText
+	generateLegacyOutput = true
+	return nil
+}
 
-	// Determine output directory
-	outDir := outputDir
-	if outDir == "" && coreConfig != nil {
-		outDir = coreConfig.OutputDir
+// Generated plist statuses
+const (
+	generateAdded     = "added"
+	generateChanged   = "changed"
+	generateUnchanged = "unchanged"
+)
+
+// generatedPlist is a plist written by the generate command
+type generatedPlist struct {
+	Daemon string `json:"daemon" yaml:"daemon"`
+	Path   string `json:"path" yaml:"path"`
+	Status string `json:"status" yaml:"status"`
+	Copy   string `json:"copy,omitempty" yaml:"copy,omitempty"`
+}
+
+// generateResult is the result of the generate command
+type generateResult struct {
+	OutputDir string           `json:"output_dir" yaml:"output_dir"`
+	Added     int              `json:"added" yaml:"added"`
+	Changed   int              `json:"changed" yaml:"changed"`
+	Unchanged int              `json:"unchanged" yaml:"unchanged"`
+	Plists    []generatedPlist `json:"plists" yaml:"plists"`
+}
+
+func (r generateResult) writeText(w io.Writer) error {
+	for _, p := range r.Plists {
+		line := fmt.Sprintf("%s: %s (%s", p.Daemon, p.Path, p.Status)
+		if p.Copy != "" {
+			line += fmt.Sprintf(", copied to %s", p.Copy)
+		}
+		if _, err := fmt.Fprintln(w, line+")"); err != nil {
+			return err
+		}
 	}
-	if outDir == "" {
-		outDir = "out"
+	_, err := fmt.Fprintf(w, "%d added, %d changed, %d unchanged\n", r.Added, r.Changed, r.Unchanged)
+	return err
+}
+
+// changed returns the daemons whose plist was added or changed
+func (r *generateResult) changed() []string {
+	names := []string{}
+	for _, p := range r.Plists {
+		if p.Status != generateUnchanged {
+			names = append(names, p.Daemon)
+		}
 	}
+	return names
+}
 
+// runGenerate generates the named daemons, or all of them, writing only
+// the plists whose definition changed unless force is set
+func runGenerate(a *app.App, names []string, force bool) (*generateResult, error) {
 	// Load daemon configuration
-	loader := config.NewLoader(configPath)
+	loader := config.NewLoader(a.DaemonConfigPath())
 	cfg, err := loader.Load()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	daemons := cfg.Daemons
+	if len(names) > 0 {
+		daemons = make([]config.Daemon, 0, len(names))
+		for _, name := range names {
+			daemon, err := loader.GetDaemon(name)
+			if err != nil {
+				return nil, err
+			}
+			daemons = append(daemons, *daemon)
+		}
 	}
 
-	if len(cfg.Daemons) == 0 {
+	return generateDaemons(a, daemons, len(names) == 0, force)
+}
+
+// generateDaemons writes the plists for daemons and copies changed plists
+// to the daemons directory when auto-generation is enabled. complete means
+// daemons is the whole configuration.
+func generateDaemons(a *app.App, daemons []config.Daemon, complete, force bool) (*generateResult, error) {
+	coreConfig := a.Config
+
+	// Determine output directory
+	outDir := a.OutputDir()
+
+	result := &generateResult{OutputDir: outDir, Plists: []generatedPlist{}}
+	if len(daemons) == 0 {
 		log.Warn().Msg("No daemons defined in configuration")
-		return nil
+		return result, nil
 	}
 
 	log.Info().
-		Int("count", len(cfg.Daemons)).
+		Int("count", len(daemons)).
 		Str("output", outDir).
 		Msg("Generating plist files")
 
 	// Create generator
-	generator := plist.NewGenerator(outDir)
+	generator := plist.NewGenerator(outDir).
+		WithEnvironment(coreConfig.DaemonEnvironment()).
+		WithValidation(coreConfig.ValidatePlists).
+		WithForce(force).
+		WithComplete(complete)
 
 	// Generate plist files
-	if err := generator.GenerateAll(cfg.Daemons); err != nil {
-		return err
+	summary, err := generator.GenerateAll(daemons)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := map[string]string{}
+	for _, name := range summary.Added {
+		statuses[name] = generateAdded
+	}
+	for _, name := range summary.Changed {
+		statuses[name] = generateChanged
+	}
+	for _, name := range summary.Unchanged {
+		statuses[name] = generateUnchanged
+	}
+	for _, daemon := range daemons {
+		result.Plists = append(result.Plists, generatedPlist{
+			Daemon: daemon.Name,
+			Path:   filepath.Join(outDir, daemon.Name+".plist"),
+			Status: statuses[daemon.Name],
+		})
 	}
+	result.Added, result.Changed, result.Unchanged = len(summary.Added), len(summary.Changed), len(summary.Unchanged)
 
 	log.Info().
-		Int("count", len(cfg.Daemons)).
+		Int("added", result.Added).
+		Int("changed", result.Changed).
+		Int("unchanged", result.Unchanged).
 		Str("directory", outDir).
 		Msg("Successfully generated plist files")
 
+	if coreConfig.BackupOnGenerate {
+		recordGenerated(a, generator, daemons, outDir)
+	}
+
 	// Also update the daemons directory if configured
-	daemonsDir := utils.GetDaemonsDir()
-	if coreConfig != nil && coreConfig.AutoGeneratePlists {
+	daemonsDir := a.DaemonsDir
+	if coreConfig.AutoGeneratePlists {
 		if _, err := os.Stat(daemonsDir); err == nil {
 			log.Info().Msg("Updating daemons directory...")
 
-			// Backup existing plists if configured
-			if coreConfig.BackupOnGenerate {
-				backupDir := filepath.Join(daemonsDir, ".backup")
-				if err := os.MkdirAll(backupDir, 0750); err == nil {
-					// Copy existing plists to backup
-					files, _ := filepath.Glob(filepath.Join(daemonsDir, "*.plist"))
-					for _, file := range files {
-						base := filepath.Base(file)
-						dst := filepath.Join(backupDir, base+".bak")
-						data, err := os.ReadFile(file)
-						if err != nil {
-							log.Warn().Err(err).Str("file", file).Msg("Failed to read file for backup")
-							continue
-						}
-						if err := os.WriteFile(dst, data, 0600); err != nil {
-							log.Warn().Err(err).Str("dst", dst).Msg("Failed to write backup file")
-						}
-					}
-					log.Info().Str("dir", backupDir).Msg("Backed up existing plists")
-				}
-			}
-
-			// Copy generated plists
-			for _, daemon := range cfg.Daemons {
+			// Copy generated plists that differ from the daemons directory
+			for i, daemon := range daemons {
 				src := filepath.Join(outDir, daemon.Name+".plist")
 				dst := filepath.Join(daemonsDir, daemon.Name+".plist")
 
@@ -134,8 +233,12 @@ func runGenerate() error {
 					continue
 				}
 
+				if current, err := os.ReadFile(dst); err == nil && bytes.Equal(current, data) {
+					continue
+				}
+
 				// Write to daemons directory
-				if err := os.WriteFile(dst, data, 0600); err != nil {
+				if err := utils.WriteFileAtomic(dst, data, 0600); err != nil {
 					log.Error().
 						Err(err).
 						Str("daemon", daemon.Name).
@@ -143,6 +246,7 @@ func runGenerate() error {
 					continue
 				}
 
+				result.Plists[i].Copy = dst
 				log.Info().
 					Str("daemon", daemon.Name).
 					Str("path", dst).
@@ -151,5 +255,25 @@ func runGenerate() error {
 		}
 	}
 
-	return nil
+	return result, nil
+}
+
+// recordGenerated adds each generated plist to the daemon's history. When
+// generate is about to replace the plists in the daemons directory, their
+// current contents are recorded first so they can be rolled back to.
+func recordGenerated(a *app.App, generator *plist.Generator, daemons []config.Daemon, outDir string) {
+	for _, daemon := range daemons {
+		if a.Config.AutoGeneratePlists {
+			if data, err := os.ReadFile(a.PlistPath(daemon.Name)); err == nil {
+				recordRevision(a, daemon.Name, data, "", history.SourceBackup)
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join(outDir, daemon.Name+".plist"))
+		if err != nil {
+			log.Warn().Err(err).Str("daemon", daemon.Name).Msg("Failed to read generated plist for history")
+			continue
+		}
+		recordRevision(a, daemon.Name, data, generator.ConfigHash(&daemon), history.SourceGenerate)
+	}
 }
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/history"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <daemon-name>",
+	Short: "List recorded revisions of a daemon's plist",
+	Long: `List the revisions of a daemon's plist recorded by generate, install and
+rollback, oldest first.
+
+Each revision is stored by content hash along with the hash of the daemon
+definition it was generated from. The revision matching the plist in the
+daemons directory is marked with *. Restore one with rollback.
+
+How many revisions are kept is set by history_max_revisions and
+history_max_age_days in the core configuration.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := daemonHistory(appFrom(cmd), args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+// historyResult is the result of the history command
+type historyResult struct {
+	Daemon    string             `json:"daemon" yaml:"daemon"`
+	Current   int                `json:"current,omitempty" yaml:"current,omitempty"`
+	Revisions []history.Revision `json:"revisions" yaml:"revisions"`
+}
+
+func (r historyResult) writeText(w io.Writer) error {
+	if len(r.Revisions) == 0 {
+		_, err := fmt.Fprintf(w, "No history recorded for %s\n", r.Daemon)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REV\tTIME\tSOURCE\tHASH\tCONFIG")
+	for _, revision := range r.Revisions {
+		marker := " "
+		if revision.Number == r.Current {
+			marker = "*"
+		}
+		fmt.Fprintf(tw, "%s%d\t%s\t%s\t%s\t%s\n",
+			marker,
+			revision.Number,
+			revision.Time.Local().Format(time.DateTime),
+			revision.Source,
+			shortHash(revision.Hash),
+			shortHash(revision.ConfigHash))
+	}
+	return tw.Flush()
+}
+
+func daemonHistory(a *app.App, daemonName string) (*historyResult, error) {
+	revisions, err := a.History().Revisions(daemonName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &historyResult{Daemon: daemonName, Revisions: revisions}
+	if current := currentRevision(a, daemonName, revisions); current != nil {
+		result.Current = current.Number
+	}
+	return result, nil
+}
+
+// currentRevision returns the newest revision matching the daemon's plist
+// in the daemons directory, if any
+func currentRevision(a *app.App, daemonName string, revisions []history.Revision) *history.Revision {
+	data, err := os.ReadFile(a.PlistPath(daemonName))
+	if err != nil {
+		return nil
+	}
+
+	hash := history.Hash(data)
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].Hash == hash {
+			return &revisions[i]
+		}
+	}
+	return nil
+}
+
+// recordRevision adds a plist to the daemon's history. History is a safety
+// net, so failing to record it is logged rather than failing the command.
+func recordRevision(a *app.App, daemonName string, data []byte, configHash, source string) {
+	revision, added, err := a.History().Record(daemonName, data, configHash, source)
+	if err != nil {
+		log.Warn().Err(err).Str("daemon", daemonName).Msg("Failed to record plist history")
+		return
+	}
+	if added {
+		log.Debug().
+			Str("daemon", daemonName).
+			Int("revision", revision.Number).
+			Str("source", source).
+			Msg("Recorded plist revision")
+	}
+}
+
+// shortHash abbreviates a hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/mjmorales/daemon-control/internal/utils"
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/state"
 )
 
 // installCmd represents the install command
@@ -16,62 +21,77 @@ var installCmd = &cobra.Command{
 	Short: "Install a daemon",
 	Long:  `Install a daemon by copying its plist file to LaunchAgents directory.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := installDaemon(daemonName); err != nil {
-			os.Exit(1)
-		}
-	},
+	RunE:  runOperation(installDaemon),
 }
 
 func init() {
 	rootCmd.AddCommand(installCmd)
 }
 
-func installDaemon(daemonName string) error {
-	if err := utils.CheckPlistExists(daemonName); err != nil {
-		return err
+func installDaemon(a *app.App, daemonName string) (*operationResult, error) {
+	if err := a.CheckPlistExists(daemonName); err != nil {
+		return nil, err
 	}
 
-	plistPath := utils.GetPlistPath(daemonName)
-	label, err := utils.GetDaemonLabel(plistPath)
+	plistPath := a.PlistPath(daemonName)
+	label, err := a.Label(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to read daemon label")
-		return err
+		return nil, fmt.Errorf("failed to read daemon label: %w", err)
 	}
 
-	installed, err := utils.IsInstalled(daemonName)
+	installed, err := a.IsInstalled(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check installation status")
-		return err
+		return nil, fmt.Errorf("failed to check installation status: %w", err)
 	}
 
 	if installed {
-		log.Warn().Str("daemon", daemonName).Msg("Daemon already installed")
-		return nil
+		return nil, fmt.Errorf("%w: %s", errs.ErrAlreadyInstalled, daemonName)
 	}
 
 	log.Info().Str("daemon", daemonName).Msg("Installing daemon")
 
-	// Create LaunchAgents directory if it doesn't exist
-	if err := os.MkdirAll(utils.LaunchAgentsDir, 0750); err != nil {
-		log.Error().Err(err).Msg("Failed to create LaunchAgents directory")
-		return err
+	data, err := os.ReadFile(plistPath) // #nosec G304 - path is in the daemons directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plist file: %w", err)
 	}
 
-	// Copy plist file
-	destPath := filepath.Join(utils.LaunchAgentsDir, label+".plist")
-	if err := utils.CopyFile(plistPath, destPath); err != nil {
-		log.Error().Err(err).Msg("Failed to copy plist file")
-		return err
+	// Write and load the plist, removing it again if launchd rejects it
+	if err := a.Install(label, data); err != nil {
+		return nil, err
 	}
 
-	// Load the daemon
-	if err := utils.RunLaunchctl("load", destPath); err != nil {
-		log.Error().Err(err).Msg("Failed to load daemon")
-		return err
-	}
+	recordRevision(a, daemonName, data, "", history.SourceInstall)
+	recordInstall(a, daemonName, label, data)
 
 	log.Info().Str("daemon", daemonName).Msg("Daemon installed successfully")
-	return nil
+	return &operationResult{Daemon: daemonName, Action: "install", Changed: true, Status: "installed"}, nil
+}
+
+// recordInstall adds an installed daemon to the state file. The daemon is
+// already loaded, so failing to record it is logged rather than failing
+// the command.
+func recordInstall(a *app.App, daemonName, label string, data []byte) {
+	entry := state.Entry{
+		Daemon:      daemonName,
+		Label:       label,
+		ConfigPath:  absDaemonConfigPath(a),
+		PlistPath:   a.InstalledPath(label),
+		PlistHash:   history.Hash(data),
+		InstalledAt: time.Now().UTC(),
+		Backend:     a.Backend.Name(),
+		Domain:      a.Domain(),
+	}
+	if err := a.State().Update(func(s *state.State) { s.Record(entry) }); err != nil {
+		log.Warn().Err(err).Str("daemon", daemonName).Msg("Failed to record install in state file")
+	}
+}
+
+// absDaemonConfigPath returns the daemon config path as recorded in the
+// state file
+func absDaemonConfigPath(a *app.App) string {
+	path, err := filepath.Abs(a.DaemonConfigPath())
+	if err != nil {
+		return a.DaemonConfigPath()
+	}
+	return path
 }
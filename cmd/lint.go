@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/lint"
+)
+
+var (
+	lintStrict    bool
+	lintListRules bool
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint [daemon-name...]",
+	Short: "Check daemon definitions for semantic problems",
+	Long: `Check daemon definitions for problems that launchd will not report
+until the daemon fails to start, such as missing programs, unwritable log
+directories, unknown users and contradictory launch settings.
+
+Each finding has a severity and a rule ID. Rules can be suppressed per
+daemon with the lint_ignore key:
+
+  - name: my-daemon
+    lint_ignore: [no-trigger]`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lintListRules {
+			return printResult(newLintRulesResult())
+		}
+
+		failed, err := runLint(appFrom(cmd), args)
+		if err != nil {
+			return fmt.Errorf("failed to lint daemon configuration: %w", err)
+		}
+		if failed {
+			return errs.Silent(errs.ExitValidation)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().BoolVar(&lintStrict, "strict", false, "Treat warnings as failures")
+	lintCmd.Flags().BoolVar(&lintListRules, "rules", false, "List available lint rules")
+}
+
+// runLint lints the configured daemons and reports whether the run failed
+func runLint(a *app.App, names []string) (bool, error) {
+	configPath := a.DaemonConfigPath()
+
+	loader := config.NewLoader(configPath)
+	cfg, err := loader.Load()
+	if err != nil {
+		return false, err
+	}
+
+	daemons := cfg.Daemons
+	if len(names) > 0 {
+		daemons = make([]config.Daemon, 0, len(names))
+		for _, name := range names {
+			daemon, err := loader.GetDaemon(name)
+			if err != nil {
+				return false, err
+			}
+			daemons = append(daemons, *daemon)
+		}
+	}
+
+	for _, daemon := range daemons {
+		for _, id := range daemon.LintIgnore {
+			if _, ok := lint.LookupRule(id); !ok {
+				log.Warn().Str("daemon", daemon.Name).Str("rule", id).Msg("Unknown rule in lint_ignore")
+			}
+		}
+	}
+
+	findings := lint.Lint(daemons)
+	if len(findings) == 0 {
+		log.Info().Int("daemons", len(daemons)).Msg("No problems found")
+		findings = []lint.Finding{}
+	}
+	if err := printResult(lintResult{Findings: findings}); err != nil {
+		return false, err
+	}
+
+	threshold := lint.SeverityError
+	if lintStrict {
+		threshold = lint.SeverityWarning
+	}
+
+	return lint.HasSeverity(findings, threshold), nil
+}
+
+// lintResult is the result of the lint command
+type lintResult struct {
+	Findings []lint.Finding `json:"findings" yaml:"findings"`
+}
+
+func (r lintResult) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range r.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, f.Daemon, f.RuleID, f.Message)
+	}
+	return tw.Flush()
+}
+
+// lintRuleSummary describes a lint rule without its check function
+type lintRuleSummary struct {
+	ID          string        `json:"id" yaml:"id"`
+	Severity    lint.Severity `json:"severity" yaml:"severity"`
+	Description string        `json:"description" yaml:"description"`
+}
+
+// lintRulesResult is the result of lint --rules
+type lintRulesResult struct {
+	Rules []lintRuleSummary `json:"rules" yaml:"rules"`
+}
+
+func newLintRulesResult() lintRulesResult {
+	result := lintRulesResult{Rules: []lintRuleSummary{}}
+	for _, rule := range lint.Rules() {
+		result.Rules = append(result.Rules, lintRuleSummary{
+			ID:          rule.ID,
+			Severity:    rule.Severity,
+			Description: rule.Description,
+		})
+	}
+	return result
+}
+
+func (r lintRulesResult) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, rule := range r.Rules {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", rule.ID, rule.Severity, rule.Description)
+	}
+	return tw.Flush()
+}
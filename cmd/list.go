@@ -2,59 +2,161 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/mjmorales/daemon-control/internal/utils"
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/plist"
+	"github.com/mjmorales/daemon-control/internal/state"
 )
 
+var listManaged bool
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all available daemons",
-	Long:  `List all available daemons in the ./daemons directory.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		listDaemons()
+	Long: `List all available daemons in the ./daemons directory.
+
+With --managed, list the daemons daemon-control installed instead, as
+recorded in its state file, and whether each has drifted:
+
+  in-sync   the installed plist is the one daemon-control installed
+  outdated  the plist in the daemons directory changed since it was installed
+  modified  the installed plist was changed outside daemon-control
+  missing   the installed plist was removed outside daemon-control`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if listManaged {
+			result, err := listManagedDaemons(appFrom(cmd))
+			if err != nil {
+				return err
+			}
+			return printResult(result)
+		}
+
+		result, err := listDaemons(appFrom(cmd))
+		if err != nil {
+			return err
+		}
+		return printResult(result)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().BoolVar(&listManaged, "managed", false, "List daemons installed by daemon-control and their drift")
 }
 
-func listDaemons() {
-	log.Info().Msg("Available daemons:")
-	fmt.Println("-----------------")
+// daemonSummary is a daemon found in the daemons directory
+type daemonSummary struct {
+	Name  string `json:"name" yaml:"name"`
+	Label string `json:"label" yaml:"label"`
+	Path  string `json:"path" yaml:"path"`
+}
 
-	if _, err := os.Stat(utils.DaemonsDir); os.IsNotExist(err) {
-		log.Error().Str("path", utils.DaemonsDir).Msg("Daemons directory not found")
-		os.Exit(1)
+// listResult is the result of the list command
+type listResult struct {
+	Daemons []daemonSummary `json:"daemons" yaml:"daemons"`
+}
+
+func (r listResult) writeText(w io.Writer) error {
+	if len(r.Daemons) == 0 {
+		_, err := fmt.Fprintln(w, "No daemons found")
+		return err
 	}
 
-	files, err := filepath.Glob(filepath.Join(utils.DaemonsDir, "*.plist"))
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to read daemons directory")
-		os.Exit(1)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tLABEL")
+	for _, daemon := range r.Daemons {
+		fmt.Fprintf(tw, "%s\t%s\n", daemon.Name, daemon.Label)
 	}
+	return tw.Flush()
+}
 
-	if len(files) == 0 {
-		log.Warn().Str("path", utils.DaemonsDir).Msg("No daemons found")
-		return
+func listDaemons(a *app.App) (*listResult, error) {
+	if _, err := os.Stat(a.DaemonsDir); os.IsNotExist(err) {
+		log.Error().Str("path", a.DaemonsDir).Msg("Daemons directory not found")
+		return nil, err
 	}
 
+	files, err := filepath.Glob(filepath.Join(a.DaemonsDir, "*.plist"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read daemons directory")
+		return nil, err
+	}
+
+	result := &listResult{Daemons: []daemonSummary{}}
 	for _, file := range files {
 		base := filepath.Base(file)
 		daemonName := strings.TrimSuffix(base, ".plist")
 
-		label, err := utils.GetDaemonLabel(file)
+		label, err := plist.ReadLabel(file)
 		if err != nil {
 			label = "Unknown"
 		}
 
-		log.Info().Str("daemon", daemonName).Str("label", label).Msg("")
+		result.Daemons = append(result.Daemons, daemonSummary{Name: daemonName, Label: label, Path: file})
+	}
+
+	return result, nil
+}
+
+// managedDaemon is an installed daemon recorded in the state file
+type managedDaemon struct {
+	state.Entry `yaml:",inline"`
+	Drift       state.Drift `json:"drift" yaml:"drift"`
+}
+
+// listManagedResult is the result of the list --managed command
+type listManagedResult struct {
+	Daemons []managedDaemon `json:"daemons" yaml:"daemons"`
+}
+
+func (r listManagedResult) writeText(w io.Writer) error {
+	if len(r.Daemons) == 0 {
+		_, err := fmt.Fprintln(w, "No managed daemons")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tLABEL\tINSTALLED\tDRIFT")
+	for _, daemon := range r.Daemons {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			daemon.Daemon,
+			daemon.Label,
+			daemon.InstalledAt.Local().Format(time.DateTime),
+			daemon.Drift)
+	}
+	return tw.Flush()
+}
+
+func listManagedDaemons(a *app.App) (*listManagedResult, error) {
+	st, err := a.State().Load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &listManagedResult{Daemons: []managedDaemon{}}
+	for _, entry := range st.Entries() {
+		result.Daemons = append(result.Daemons, managedDaemon{Entry: entry, Drift: driftOf(a, entry)})
+	}
+	return result, nil
+}
+
+// driftOf checks an installed daemon against its recorded state and its
+// plist in the daemons directory
+func driftOf(a *app.App, entry state.Entry) state.Drift {
+	source, err := os.ReadFile(a.PlistPath(entry.Daemon))
+	if err != nil {
+		source = nil
 	}
+	return state.CheckDrift(entry, source)
 }
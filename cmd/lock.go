@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/lock"
+)
+
+// lockPath returns the lock file shared by every command that changes
+// plists, installed daemons or the core configuration
+func lockPath() string {
+	return filepath.Join(core.ConfigDir(), "daemon-control.lock")
+}
+
+// acquireLock takes the shared lock, waiting for it if wait is set
+func acquireLock(wait bool) (*lock.Lock, error) {
+	return lock.Acquire(lockPath(), lock.Options{
+		Wait:    wait,
+		Timeout: lockWaitTimeout,
+		OnWait: func(pid int) {
+			log.Info().Int("pid", pid).Str("lock", lockPath()).Msg("Waiting for another daemon-control process to finish")
+		},
+	})
+}
+
+// locked wraps a command's RunE so that it holds the shared lock while it
+// runs. Concurrent invocations would otherwise race on the output and
+// daemons directories and the core config file.
+func locked(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		_, err := withLock(func() (any, error) {
+			return nil, run(cmd, args)
+		})
+		return err
+	}
+}
+
+// withLock runs fn while holding the shared lock
+func withLock(fn func() (any, error)) (any, error) {
+	l, err := acquireLock(lockWait)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warn().Err(err).Msg("Failed to release lock")
+		}
+	}()
+	return fn()
+}
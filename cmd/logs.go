@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/mjmorales/daemon-control/internal/app"
 	"github.com/mjmorales/daemon-control/internal/utils"
 )
 
@@ -17,11 +18,8 @@ var logsCmd = &cobra.Command{
 	Short: "Show daemon logs",
 	Long:  `Show recent logs from the daemon's stdout and stderr.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := showLogs(daemonName); err != nil {
-			os.Exit(1)
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showLogs(appFrom(cmd), args[0])
 	},
 }
 
@@ -29,26 +27,10 @@ func init() {
 	rootCmd.AddCommand(logsCmd)
 }
 
-func showLogs(daemonName string) error {
-	if err := utils.CheckPlistExists(daemonName); err != nil {
-		return err
-	}
-
-	plistPath := utils.GetPlistPath(daemonName)
-
-	stdoutPath, err := utils.GetStdoutPath(plistPath)
+func showLogs(a *app.App, daemonName string) error {
+	stdoutPath, stderrPath, err := logPaths(a, daemonName)
 	if err != nil {
-		stdoutPath = ""
-	}
-
-	stderrPath, err := utils.GetStderrPath(plistPath)
-	if err != nil {
-		stderrPath = ""
-	}
-
-	if stdoutPath == "" && stderrPath == "" {
-		log.Error().Msg("No log paths configured in plist")
-		return nil
+		return err
 	}
 
 	if stdoutPath != "" {
@@ -69,6 +51,31 @@ func showLogs(daemonName string) error {
 	return nil
 }
 
+// logPaths returns the stdout and stderr log paths from a daemon's plist.
+// Either may be empty, but not both.
+func logPaths(a *app.App, daemonName string) (string, string, error) {
+	if err := a.CheckPlistExists(daemonName); err != nil {
+		return "", "", err
+	}
+
+	plistPath := a.PlistPath(daemonName)
+
+	stdoutPath, err := utils.GetStdoutPath(plistPath)
+	if err != nil {
+		stdoutPath = ""
+	}
+
+	stderrPath, err := utils.GetStderrPath(plistPath)
+	if err != nil {
+		stderrPath = ""
+	}
+
+	if stdoutPath == "" && stderrPath == "" {
+		return "", "", fmt.Errorf("no log paths configured in plist")
+	}
+	return stdoutPath, stderrPath, nil
+}
+
 func showLogFile(path string) error {
 	file, err := os.Open(path)
 	if err != nil {
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/metrics"
+	"github.com/mjmorales/daemon-control/internal/server"
+)
+
+var (
+	metricsListen    string
+	metricsTokenFile string
+	metricsOnce      bool
+)
+
+// metricsCmd represents the metrics command
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Serve Prometheus metrics for the configured daemons",
+	Long: `Serve Prometheus metrics on /metrics for every daemon in the daemon
+configuration, collected every metrics_interval_seconds (15 by default):
+
+  daemon_control_up                     1 while the daemon's process runs
+  daemon_control_loaded                 1 while the daemon is loaded
+  daemon_control_pid                    PID of the running process
+  daemon_control_restarts_total         restarts after failed exits of a
+                                        keep_alive daemon since metrics
+                                        started
+  daemon_control_last_exit_code         exit status of the last exit
+  daemon_control_uptime_seconds         age of the running process
+  daemon_control_health_check_up        result of the daemon's health_check
+  daemon_control_resident_memory_bytes  RSS of the running process
+  daemon_control_cpu_seconds_total      CPU time of the running process
+  daemon_control_log_size_bytes         size of each log file, by stream
+
+Each series is labelled with the daemon's name, launchd label and tags,
+sorted and joined with commas. Use serve --metrics to serve the metrics
+next to the control API instead. --listen, --token-file and
+DAEMON_CONTROL_API_TOKEN work as for serve. --once prints the metrics and
+exits.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runMetrics(ctx, appFrom(cmd))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+
+	metricsCmd.Flags().StringVar(&metricsListen, "listen", "127.0.0.1:9469", "Address to listen on: unix:///path.sock or host:port")
+	metricsCmd.Flags().StringVar(&metricsTokenFile, "token-file", "", "File holding the bearer token clients must send (env "+tokenEnv+")")
+	metricsCmd.Flags().BoolVar(&metricsOnce, "once", false, "Collect once, print the metrics and exit")
+}
+
+func runMetrics(ctx context.Context, a *app.App) error {
+	collector := newCollector(a)
+	if metricsOnce {
+		if err := collector.Collect(ctx); err != nil {
+			return err
+		}
+		return collector.Write(os.Stdout)
+	}
+
+	interval, err := metricsInterval(a)
+	if err != nil {
+		return err
+	}
+	token, err := apiToken(metricsTokenFile)
+	if err != nil {
+		return err
+	}
+	listener, err := server.Listen(metricsListen, token)
+	if err != nil {
+		return err
+	}
+
+	go collector.Run(ctx, interval)
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", collector)
+
+	log.Info().Str("listen", metricsListen).Dur("interval", interval).Msg("Serving metrics (Ctrl+C to stop)...")
+	return serveHTTP(ctx, listener, server.RequireToken(token, mux))
+}
+
+// metricsInterval returns how often metrics are collected
+func metricsInterval(a *app.App) (time.Duration, error) {
+	if a.Config.MetricsIntervalSeconds < 1 {
+		return 0, fmt.Errorf("metrics_interval_seconds must be at least 1, got %d", a.Config.MetricsIntervalSeconds)
+	}
+	return time.Duration(a.Config.MetricsIntervalSeconds) * time.Second, nil
+}
+
+// newCollector creates a metrics collector for the configured daemons
+func newCollector(a *app.App) *metrics.Collector {
+	return metrics.NewCollector(configuredDaemons(a), a.Backend)
+}
+
+// configuredDaemons returns a function listing the daemons in the daemon
+// configuration. The file is only loaded again when it changes, and if it
+// becomes invalid the last good daemons are returned.
+func configuredDaemons(a *app.App) func() ([]config.Daemon, error) {
+	var (
+		loaded  bool
+		modTime time.Time
+		daemons []config.Daemon
+	)
+	return func() ([]config.Daemon, error) {
+		path := a.DaemonConfigPath()
+		info, err := os.Stat(path)
+		if err == nil && loaded && info.ModTime().Equal(modTime) {
+			return daemons, nil
+		}
+
+		cfg, err := config.NewLoader(path).Load()
+		if err != nil {
+			if loaded {
+				log.Warn().Err(err).Msg("Daemon configuration is invalid, keeping the last good one")
+				return daemons, nil
+			}
+			return nil, err
+		}
+		if info != nil {
+			modTime = info.ModTime()
+		}
+		loaded, daemons = true, cfg.Daemons
+		return daemons, nil
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/monitor"
+	"github.com/mjmorales/daemon-control/internal/notify"
+)
+
+var monitorInterval time.Duration
+
+// monitorCmd represents the monitor command
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Send notifications when daemons crash, flap or fail health checks",
+	Long: `Poll launchd for the configured daemons and send a notification when:
+
+  crash         a daemon exits with a failure status or is killed by a
+                signal other than SIGTERM
+  flapping      launchd restarts a keep_alive daemon after flap_restarts
+                failed exits (3) within flap_window_minutes (10); scheduled
+                runs and clean exits are not restarts
+  health_check  a running daemon's health_check fails; reported again only
+                after it has passed
+
+Notifications are configured in a notify block in the core config, which
+applies to every daemon, and in each daemon's configuration, whose fields
+override it. A notify block can POST the event as JSON to a webhook, run a
+command with the event as JSON on its standard input and in
+DAEMON_CONTROL_* environment variables, and show a desktop notification.
+Daemons without anywhere to send events are not monitored.
+
+Exits are counted with launchd's run count, so a daemon that fails right
+after every launch is caught even though no poll sees it running. Exits that
+happened before monitor started are not reported. Each event is
+printed as it is sent; monitor runs until interrupted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runMonitor(ctx, appFrom(cmd))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(monitorCmd)
+
+	monitorCmd.Flags().DurationVar(&monitorInterval, "interval", 10*time.Second, "How often to poll daemon status")
+}
+
+// monitorEvent is an event monitor detected and the result of sending it
+type monitorEvent struct {
+	notify.Event `yaml:",inline"`
+	Error        string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (e monitorEvent) writeText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%s %s: %s: %s\n", e.Time.Format("15:04:05"), e.Daemon, e.Event.Event, e.Message); err != nil {
+		return err
+	}
+	if e.Error != "" {
+		_, err := fmt.Fprintf(w, "%s %s: notification failed: %s\n", e.Time.Format("15:04:05"), e.Daemon, e.Error)
+		return err
+	}
+	return nil
+}
+
+func runMonitor(ctx context.Context, a *app.App) error {
+	if monitorInterval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s, got %s", monitorInterval)
+	}
+	if a.Config.Notify != nil {
+		if err := config.ValidateNotify(a.Config.Notify); err != nil {
+			return fmt.Errorf("%w: core config notify: %v", errs.ErrInvalidConfig, err)
+		}
+	}
+
+	// Load once up front so a broken configuration fails fast
+	daemons := configuredDaemons(a)
+	if _, err := daemons(); err != nil {
+		return err
+	}
+
+	m := monitor.New(daemons, a.Backend, a.Config.Notify)
+	log.Info().Dur("interval", monitorInterval).Msg("Monitoring daemons (Ctrl+C to stop)...")
+	m.Run(ctx, monitorInterval, func(d monitor.Delivery) {
+		event := monitorEvent{Event: d.Event}
+		if d.Err != nil {
+			event.Error = d.Err.Error()
+		}
+		if err := printResult(event); err != nil {
+			log.Error().Err(err).Msg("Failed to write monitor event")
+		}
+	})
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+)
+
+// Output formats accepted by --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// outputFormat is the value of the global --output flag
+var outputFormat string
+
+// textResult is a command result that can also be written for humans
+type textResult interface {
+	writeText(w io.Writer) error
+}
+
+// validateOutputFormat rejects unknown --output values before a command runs
+func validateOutputFormat(cmd *cobra.Command, args []string) error {
+	switch outputFormat {
+	case outputText, outputJSON, outputYAML:
+		return nil
+	default:
+		if cmd == generateCmd {
+			return legacyOutputDir(cmd)
+		}
+		return fmt.Errorf("invalid output format %q: must be text, json or yaml", outputFormat)
+	}
+}
+
+// printResult writes a command result to stdout in the selected format.
+// Logs go to stderr, so stdout only ever carries results.
+func printResult(result textResult) error {
+	return writeResult(os.Stdout, outputFormat, result)
+}
+
+// writeResult writes a result to w in the given format
+func writeResult(w io.Writer, format string, result textResult) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case outputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return result.writeText(w)
+	}
+}
+
+// operationResult is the result of a lifecycle command such as install or start
+type operationResult struct {
+	Daemon  string `json:"daemon" yaml:"daemon"`
+	Action  string `json:"action" yaml:"action"`
+	Changed bool   `json:"changed" yaml:"changed"`
+	Status  string `json:"status" yaml:"status"`
+}
+
+func (r operationResult) writeText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s: %s\n", r.Daemon, r.Status)
+	return err
+}
+
+// runOperation adapts a lifecycle operation to a cobra RunE function that
+// prints its result
+func runOperation(operation func(*app.App, string) (*operationResult, error)) func(*cobra.Command, []string) error {
+	return locked(func(cmd *cobra.Command, args []string) error {
+		result, err := operation(appFrom(cmd), args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	})
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+	"github.com/mjmorales/daemon-control/internal/state"
+)
+
+var (
+	pruneDryRun bool
+	pruneYes    bool
+	pruneForce  bool
+)
+
+// Kinds of orphaned item found by prune
+const (
+	pruneInstalled = "installed"
+	pruneDaemons   = "daemons-dir"
+	pruneGenerated = "generated"
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove daemons that are no longer in the configuration",
+	Long: `Find and remove daemons that were deleted from the daemon configuration:
+
+  installed    daemons installed by daemon-control from this configuration
+               file, which are unloaded and removed from LaunchAgents
+  daemons-dir  plists in the daemons directory that generate copied there
+  generated    plists in the output directory
+
+Plists in the daemons directory that daemon-control did not generate or
+install are left alone. prune asks for confirmation on stdin before
+removing anything; use --dry-run to only list what would be removed, or
+--yes to skip the question.
+
+If no configuration file is found, or it defines no daemons, every daemon
+would look orphaned, so prune refuses to remove anything unless --force is
+given.`,
+	Args: cobra.NoArgs,
+	RunE: locked(func(cmd *cobra.Command, args []string) error {
+		result, err := runPrune(appFrom(cmd), cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "List orphaned daemons without removing them")
+	pruneCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Remove without asking for confirmation")
+	pruneCmd.Flags().BoolVar(&pruneForce, "force", false, "Prune even when the configuration defines no daemons")
+}
+
+// prunedItem is an orphaned plist or installed daemon
+type prunedItem struct {
+	Daemon string `json:"daemon" yaml:"daemon"`
+	Kind   string `json:"kind" yaml:"kind"`
+	Path   string `json:"path" yaml:"path"`
+	Label  string `json:"label,omitempty" yaml:"label,omitempty"`
+}
+
+// pruneResult is the result of the prune command
+type pruneResult struct {
+	DryRun    bool         `json:"dry_run" yaml:"dry_run"`
+	Cancelled bool         `json:"cancelled" yaml:"cancelled"`
+	Items     []prunedItem `json:"items" yaml:"items"`
+}
+
+func (r pruneResult) writeText(w io.Writer) error {
+	if r.Cancelled {
+		_, err := fmt.Fprintln(w, "Prune cancelled, nothing was removed")
+		return err
+	}
+	if len(r.Items) == 0 {
+		_, err := fmt.Fprintln(w, "Nothing to prune")
+		return err
+	}
+
+	verb := "removed"
+	if r.DryRun {
+		verb = "would remove"
+	}
+	for _, item := range r.Items {
+		if _, err := fmt.Fprintf(w, "%s: %s %s plist %s\n", item.Daemon, verb, item.Kind, item.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runPrune(a *app.App, in io.Reader) (*pruneResult, error) {
+	cfg, err := config.NewLoader(a.DaemonConfigPath()).Load()
+	if err != nil {
+		return nil, err
+	}
+	items, err := findOrphans(a, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pruneResult{DryRun: pruneDryRun, Items: items}
+	if len(items) == 0 || pruneDryRun {
+		return result, nil
+	}
+
+	// A missing or emptied configuration file would prune everything
+	if len(cfg.Daemons) == 0 && !pruneForce {
+		return nil, fmt.Errorf("%w: no daemons are defined in %s, so every daemon-control plist would be pruned; check the file or use --force",
+			errs.ErrInvalidConfig, daemonConfigName(a))
+	}
+
+	if !pruneYes {
+		confirmed, err := confirmPrune(items, in)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			result.Cancelled = true
+			return result, nil
+		}
+	}
+
+	for _, item := range items {
+		if err := pruneItem(a, item); err != nil {
+			return nil, err
+		}
+		log.Info().Str("daemon", item.Daemon).Str("kind", item.Kind).Str("path", item.Path).Msg("Pruned daemon")
+	}
+	return result, nil
+}
+
+// daemonConfigName describes the daemon configuration file for messages
+func daemonConfigName(a *app.App) string {
+	path := a.DaemonConfigPath()
+	if path == "" {
+		return "the daemon configuration (no file found)"
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return path + " (file not found)"
+	}
+	return path
+}
+
+// findOrphans returns the installed daemons and plists whose daemon is not
+// defined in cfg
+func findOrphans(a *app.App, cfg *config.Config) ([]prunedItem, error) {
+	defined := make(map[string]bool, len(cfg.Daemons))
+	for _, daemon := range cfg.Daemons {
+		defined[daemon.Name] = true
+	}
+
+	st, err := a.State().Load()
+	if err != nil {
+		return nil, err
+	}
+	managed := map[string]bool{}
+	foreign := map[string]bool{}
+	items := []prunedItem{}
+
+	// Daemons installed from this configuration file. Entries from other
+	// configuration files, which may share the daemons directory, belong
+	// to them.
+	configPath := absDaemonConfigPath(a)
+	for _, entry := range st.Entries() {
+		if entry.ConfigPath != configPath {
+			foreign[entry.Daemon] = true
+			continue
+		}
+		managed[entry.Daemon] = true
+		if !defined[entry.Daemon] {
+			items = append(items, prunedItem{Daemon: entry.Daemon, Kind: pruneInstalled, Path: entry.PlistPath, Label: entry.Label})
+		}
+	}
+
+	// The daemons directory may hold hand-written plists, so only those
+	// daemon-control installed or generated are orphans
+	store := a.History()
+	for _, name := range a.DaemonNames() {
+		if defined[name] || (foreign[name] && !managed[name]) {
+			continue
+		}
+		generated, err := wasGenerated(store, name)
+		if err != nil {
+			return nil, err
+		}
+		if managed[name] || generated {
+			items = append(items, prunedItem{Daemon: name, Kind: pruneDaemons, Path: a.PlistPath(name)})
+		}
+	}
+
+	// Everything in the output directory was generated
+	files, err := filepath.Glob(filepath.Join(a.OutputDir(), "*.plist"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".plist")
+		if !defined[name] {
+			items = append(items, prunedItem{Daemon: name, Kind: pruneGenerated, Path: file})
+		}
+	}
+
+	return items, nil
+}
+
+// wasGenerated reports whether generate ever recorded a plist for daemon
+func wasGenerated(store *history.Store, daemon string) (bool, error) {
+	revisions, err := store.Revisions(daemon)
+	if err != nil {
+		return false, err
+	}
+	for _, revision := range revisions {
+		if revision.Source == history.SourceGenerate {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// confirmPrune lists the items on stderr and asks whether to remove them.
+// Anything but yes, including no input at all, declines.
+func confirmPrune(items []prunedItem, in io.Reader) (bool, error) {
+	fmt.Fprintln(os.Stderr, "The following will be removed:")
+	for _, item := range items {
+		fmt.Fprintf(os.Stderr, "  %s: %s plist %s\n", item.Daemon, item.Kind, item.Path)
+	}
+	fmt.Fprint(os.Stderr, "Continue? [y/N] ")
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// pruneItem removes an orphaned plist, unloading it first if it is
+// installed
+func pruneItem(a *app.App, item prunedItem) error {
+	if item.Kind == pruneInstalled {
+		jobs, err := a.Backend.List()
+		if err != nil {
+			return errs.Backend("launchctl list", err)
+		}
+		if _, loaded := launchd.Find(jobs, item.Label); loaded {
+			if err := a.Backend.Unload(item.Path); err != nil {
+				return errs.Backend("launchctl unload", err)
+			}
+		}
+	}
+
+	if err := os.Remove(item.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", item.Path, err)
+	}
+
+	if item.Kind == pruneInstalled {
+		if err := a.State().Update(func(s *state.State) { s.Remove(item.Label) }); err != nil {
+			return fmt.Errorf("failed to update state file: %w", err)
+		}
+	}
+	return nil
+}
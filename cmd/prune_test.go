@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/launchd/launchdtest"
+	"github.com/mjmorales/daemon-control/internal/state"
+)
+
+func TestFindOrphans_SharedDaemonsDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	// Two configuration files generate into the same daemons directory
+	coreCfg := core.DefaultConfig()
+	coreCfg.DaemonsDir = filepath.Join(dir, "daemons")
+	coreCfg.LaunchAgentsDir = filepath.Join(dir, "agents")
+	coreCfg.OutputDir = filepath.Join(dir, "out")
+	coreCfg.DaemonConfigPath = filepath.Join(dir, "a.yaml")
+	a := app.New(coreCfg, &launchdtest.Backend{}, zerolog.Nop())
+	otherPath := filepath.Join(dir, "b.yaml")
+
+	require.NoError(t, os.MkdirAll(a.DaemonsDir, 0755))
+	for _, name := range []string{"web", "old", "api"} {
+		data := []byte("<plist/>")
+		require.NoError(t, os.WriteFile(a.PlistPath(name), data, 0644))
+		_, _, err := a.History().Record(name, data, "", history.SourceGenerate)
+		require.NoError(t, err)
+	}
+	require.NoError(t, a.State().Update(func(st *state.State) {
+		st.Record(state.Entry{Daemon: "old", Label: "com.example.old", ConfigPath: a.DaemonConfigPath(), PlistPath: a.InstalledPath("com.example.old")})
+		st.Record(state.Entry{Daemon: "api", Label: "com.example.api", ConfigPath: otherPath, PlistPath: a.InstalledPath("com.example.api")})
+	}))
+
+	cfg := &config.Config{Daemons: []config.Daemon{{Name: "web"}}}
+	items, err := findOrphans(a, cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, []prunedItem{
+		{Daemon: "old", Kind: pruneInstalled, Path: a.InstalledPath("com.example.old"), Label: "com.example.old"},
+		{Daemon: "old", Kind: pruneDaemons, Path: a.PlistPath("old")},
+	}, items)
+}
@@ -1,46 +1,49 @@
 package cmd
 
 import (
-	"os"
+	"errors"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/errs"
 )
 
 // restartCmd represents the restart command
 var restartCmd = &cobra.Command{
 	Use:   "restart <daemon-name>",
 	Short: "Restart a daemon",
-	Long:  `Stop and then start a daemon.`,
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := restartDaemon(daemonName); err != nil {
-			os.Exit(1)
-		}
-	},
+	Long: `Stop and then start a daemon. A daemon that is not running is
+simply started.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runOperation(restartDaemon),
 }
 
 func init() {
 	rootCmd.AddCommand(restartCmd)
 }
 
-func restartDaemon(daemonName string) error {
+func restartDaemon(a *app.App, daemonName string) (*operationResult, error) {
 	log.Info().Str("daemon", daemonName).Msg("Restarting daemon")
 
 	// Stop the daemon
-	if err := stopDaemon(daemonName); err != nil {
-		return err
+	_, err := stopDaemon(a, daemonName)
+	switch {
+	case errors.Is(err, errs.ErrNotRunning):
+		log.Info().Str("daemon", daemonName).Msg("Daemon not running, starting it")
+	case err != nil:
+		return nil, err
+	default:
+		// Wait a moment
+		time.Sleep(2 * time.Second)
 	}
 
-	// Wait a moment
-	time.Sleep(2 * time.Second)
-
 	// Start the daemon
-	if err := startDaemon(daemonName); err != nil {
-		return err
+	if _, err := startDaemon(a, daemonName); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &operationResult{Daemon: daemonName, Action: "restart", Changed: true, Status: "restarted"}, nil
 }
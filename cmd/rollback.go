@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+	"github.com/mjmorales/daemon-control/internal/plist"
+	"github.com/mjmorales/daemon-control/internal/utils"
+)
+
+var rollbackTo int
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <daemon-name>",
+	Short: "Restore an earlier revision of a daemon's plist",
+	Long: `Restore a revision from the daemon's history into the daemons directory.
+If the daemon is installed, the installed plist is replaced and reloaded.
+
+Without --to, the newest revision that differs from the current plist is
+restored. The restored plist is recorded as a new revision, so a rollback
+can itself be rolled back. See history for the revision numbers.`,
+	Args: cobra.ExactArgs(1),
+	RunE: locked(func(cmd *cobra.Command, args []string) error {
+		result, err := rollbackDaemon(appFrom(cmd), args[0], rollbackTo)
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().IntVar(&rollbackTo, "to", 0, "Revision to restore (default: the newest revision that differs from the current plist)")
+}
+
+func rollbackDaemon(a *app.App, daemonName string, rev int) (*operationResult, error) {
+	store := a.History()
+	revisions, err := store.Revisions(daemonName)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("no history recorded for %s", daemonName)
+	}
+
+	target, err := rollbackTarget(a, store, daemonName, revisions, rev)
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.Read(target)
+	if err != nil {
+		return nil, err
+	}
+	p, err := plist.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d is not a valid plist: %w", target.Number, err)
+	}
+	label, ok := p.Dict.GetString("Label")
+	if !ok || label == "" {
+		return nil, fmt.Errorf("revision %d has no Label", target.Number)
+	}
+
+	// The label may differ between revisions, so find the installed copy
+	// from the plist being replaced
+	previousLabel := label
+	if current, err := plist.ParseFile(a.PlistPath(daemonName)); err == nil {
+		if l, ok := current.Dict.GetString("Label"); ok && l != "" {
+			previousLabel = l
+		}
+	}
+	_, statErr := os.Stat(a.InstalledPath(previousLabel))
+	installed := statErr == nil
+
+	log.Info().
+		Str("daemon", daemonName).
+		Int("revision", target.Number).
+		Msg("Rolling back daemon plist")
+
+	// Reload first, so a plist launchd rejects is not left in the daemons
+	// directory either
+	status := fmt.Sprintf("rolled back to revision %d", target.Number)
+	if installed {
+		if err := reinstallPlist(a, daemonName, previousLabel, label, data); err != nil {
+			return nil, err
+		}
+		status += " and reloaded"
+	}
+
+	if err := os.MkdirAll(a.DaemonsDir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create daemons directory: %w", err)
+	}
+	if err := utils.WriteFileAtomic(a.PlistPath(daemonName), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to restore plist: %w", err)
+	}
+
+	recordRevision(a, daemonName, data, target.ConfigHash, history.SourceRollback)
+
+	log.Info().Str("daemon", daemonName).Msg("Daemon rolled back successfully")
+	return &operationResult{Daemon: daemonName, Action: "rollback", Changed: true, Status: status}, nil
+}
+
+// rollbackTarget returns revision rev, or when rev is 0 the newest revision
+// that differs from the daemon's current plist
+func rollbackTarget(a *app.App, store *history.Store, daemonName string, revisions []history.Revision, rev int) (*history.Revision, error) {
+	if rev != 0 {
+		return store.Revision(daemonName, rev)
+	}
+
+	current := currentRevision(a, daemonName, revisions)
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if current == nil || revisions[i].Hash != current.Hash {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no earlier revision of %s to roll back to", daemonName)
+}
+
+// reinstallPlist replaces an installed plist with data and reloads it. If
+// launchd rejects the new plist, the previous one is loaded again.
+func reinstallPlist(a *app.App, daemonName, previousLabel, label string, data []byte) error {
+	previousPath := a.InstalledPath(previousLabel)
+
+	jobs, err := a.Backend.List()
+	if err != nil {
+		return errs.Backend("launchctl list", err)
+	}
+	if _, loaded := launchd.Find(jobs, previousLabel); loaded {
+		if err := a.Backend.Unload(previousPath); err != nil {
+			return errs.Backend("launchctl unload", err)
+		}
+	}
+
+	if err := a.Install(label, data); err != nil {
+		if previousLabel != label {
+			// The previous plist is still in place under its own label
+			if loadErr := a.Backend.Load(previousPath); loadErr != nil {
+				log.Warn().Err(loadErr).Str("path", previousPath).Msg("Failed to reload previous plist")
+			}
+		}
+		return err
+	}
+
+	if previousLabel != label {
+		if err := os.Remove(previousPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove plist file: %w", err)
+		}
+	}
+	recordInstall(a, daemonName, label, data)
+	return nil
+}
@@ -2,27 +2,151 @@ package cmd
 
 import (
 	"os"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+)
+
+var (
+	coreConfigPath string
+
+	// lockWait and lockWaitTimeout control waiting for the lock taken by
+	// mutating commands
+	lockWait        bool
+	lockWaitTimeout time.Duration
 )
 
+// flagKeys maps flags to the core config keys they override
+var flagKeys = map[string]string{
+	"config":            "daemon_config_path",
+	"daemons-dir":       "daemons_dir",
+	"launch-agents-dir": "launch_agents_dir",
+	"output-dir":        "output_dir",
+	"log-level":         "log_level",
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "daemon-control",
 	Short: "Manage macOS LaunchAgent daemons",
 	Long: `A generic daemon control tool for managing macOS LaunchAgent daemons.
 	
 This tool allows you to install, uninstall, start, stop, and monitor
-daemons defined as plist files in the ./daemons directory.`,
+daemons defined as plist files in the ./daemons directory.
+
+Results are written to stdout in the format chosen with --output; logs are
+always written to stderr.
+
+Settings are resolved in this order, highest precedence first:
+  1. command line flags, such as --daemons-dir
+  2. DAEMON_CONTROL_* environment variables, such as DAEMON_CONTROL_DAEMONS_DIR
+  3. the core config file (--core-config or DAEMON_CONTROL_CORE_CONFIG)
+  4. built-in defaults
+
+DAEMON_CONTROL_CONFIG sets the daemon configuration file.
+
+Exit codes:
+  0   success
+  1   unexpected failure
+  2   invalid usage
+  3   daemon not found
+  4   daemon not installed
+  5   daemon already installed
+  6   daemon already running
+  7   daemon not running
+  8   launchctl failed
+  9   daemon did not come up after starting
+  10  invalid configuration
+  11  validation reported problems`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(cmd, args); err != nil {
+			return err
+		}
+		// Flags and arguments were accepted, so later errors are not
+		// usage errors
+		cmd.SilenceUsage = true
+
+		cmd.SetContext(app.NewContext(cmd.Context(), newApp(cmd)))
+		return nil
+	},
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+
+	if !errs.IsSilent(err) {
+		log.Error().Err(err).Msg("Command failed")
+	}
+
+	code := errs.ExitCode(err)
+	if code == errs.ExitFailure && !cmd.SilenceUsage {
+		code = errs.ExitUsage
+	}
+	os.Exit(code)
+}
+
+// selectedCoreConfigPath returns the core config file chosen with
+// --core-config, DAEMON_CONTROL_CORE_CONFIG or the default location
+func selectedCoreConfigPath() string {
+	if coreConfigPath != "" {
+		return core.ExpandPath(coreConfigPath)
+	}
+	return core.ConfigPath()
+}
+
+// newCoreManager creates a core config manager for the selected core config
+// file with the command's override flags bound
+func newCoreManager(cmd *cobra.Command) *core.Manager {
+	manager := core.NewManagerAt(selectedCoreConfigPath())
+	for name, key := range flagKeys {
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			if err := manager.BindFlag(key, flag); err != nil {
+				log.Warn().Err(err).Str("flag", name).Msg("Failed to bind flag")
+			}
+		}
+	}
+	return manager
+}
+
+// appFrom returns the App the root command built for cmd once its flags
+// were parsed
+func appFrom(cmd *cobra.Command) *app.App {
+	return app.FromContext(cmd.Context())
+}
+
+// newApp builds the App from the resolved core configuration
+func newApp(cmd *cobra.Command) *app.App {
+	manager := newCoreManager(cmd)
+	if err := manager.Init(); err != nil {
+		log.Warn().Err(err).Msg("Failed to initialize core config, using defaults")
+	} else if manager.GetConfig().Version < core.CurrentVersion {
+		log.Warn().
+			Int("version", manager.GetConfig().Version).
+			Msg("Core config uses an older schema version, run 'daemon-control config migrate' to upgrade it")
 	}
+	cfg := manager.GetConfig()
+	return app.New(cfg, launchd.NewLaunchctl(launchd.Domain(cfg.UseSystemLaunchd)), log.Logger)
 }
 
 func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVarP(&outputFormat, "output", "o", outputText, "Output format: text, json or yaml")
+	flags.StringVar(&coreConfigPath, "core-config", "", "Core config file (env DAEMON_CONTROL_CORE_CONFIG)")
+	flags.StringP("config", "c", "", "Daemon configuration file (env DAEMON_CONTROL_CONFIG)")
+	flags.String("daemons-dir", "", "Daemons directory (env DAEMON_CONTROL_DAEMONS_DIR)")
+	flags.String("launch-agents-dir", "", "LaunchAgents directory (env DAEMON_CONTROL_LAUNCH_AGENTS_DIR)")
+	flags.String("log-level", "", "Log level: debug, info, warn or error (env DAEMON_CONTROL_LOG_LEVEL)")
+	flags.BoolVar(&lockWait, "wait", false, "Wait for another daemon-control process to finish instead of failing")
+	flags.DurationVar(&lockWaitTimeout, "wait-timeout", 0, "Give up waiting after this long, e.g. 30s (default: no limit)")
 }
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/server"
+)
+
+// tokenEnv holds the API token when --token-file is not given
+const tokenEnv = "DAEMON_CONTROL_API_TOKEN"
+
+var (
+	serveListen    string
+	serveTokenFile string
+	serveMetrics   bool
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an HTTP/JSON API for controlling daemons",
+	Long: `Serve a local HTTP/JSON API that runs the same operations as the
+commands:
+
+  GET  /v1/daemons                     list (?managed=true for list --managed)
+  GET  /v1/daemons/{name}              status
+  POST /v1/daemons/{name}/start        start
+  POST /v1/daemons/{name}/stop         stop
+  POST /v1/daemons/{name}/restart      restart
+  GET  /v1/daemons/{name}/logs         last lines of the logs (?lines=N, 1000)
+  GET  /v1/daemons/{name}/logs/stream  new log lines as server-sent events
+  POST /v1/generate                    generate, body {"daemons": [...], "force": false}
+  GET  /metrics                        Prometheus metrics, with --metrics (see metrics)
+
+--listen takes unix:///path/to.sock or host:port, and defaults to
+daemon-control.sock in ~/.daemon-control. The socket is only accessible to
+the current user. If DAEMON_CONTROL_API_TOKEN is set, or
+--token-file names a file holding a token, every request must send it as
+"Authorization: Bearer <token>". A token is required to listen on anything
+but the loopback interface. Without one, requests from web pages (with an
+Origin header) and requests for a Host other than 127.0.0.1, ::1 or
+localhost are refused. POST requests must have Content-Type:
+application/json.
+
+Failed requests return {"error": ..., "exit_code": ...} with the exit code
+the equivalent command would have.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runServe(ctx, appFrom(cmd))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "Address to listen on: unix:///path.sock or host:port")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "File holding the bearer token clients must send (env "+tokenEnv+")")
+	serveCmd.Flags().BoolVar(&serveMetrics, "metrics", false, "Also serve Prometheus metrics on /metrics")
+
+	// The default socket is under the home directory, which is only looked
+	// up when it is needed
+	help := serveCmd.HelpFunc()
+	serveCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if flag := cmd.Flags().Lookup("listen"); flag != nil {
+			flag.DefValue = defaultServeListen()
+		}
+		help(cmd, args)
+	})
+}
+
+// defaultServeListen is the socket serve listens on without --listen
+func defaultServeListen() string {
+	return "unix://" + filepath.Join(core.ConfigDir(), "daemon-control.sock")
+}
+
+func runServe(ctx context.Context, a *app.App) error {
+	if serveListen == "" {
+		serveListen = defaultServeListen()
+	}
+	token, err := apiToken(serveTokenFile)
+	if err != nil {
+		return err
+	}
+
+	listener, err := server.Listen(serveListen, token)
+	if err != nil {
+		return err
+	}
+
+	api := server.New(apiOperations(a), token)
+	if serveMetrics {
+		interval, err := metricsInterval(a)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		collector := newCollector(a)
+		go collector.Run(ctx, interval)
+		api.Handle("GET /metrics", collector)
+	}
+
+	log.Info().Str("listen", serveListen).Bool("auth", token != "").Bool("metrics", serveMetrics).Msg("Serving API (Ctrl+C to stop)...")
+	return serveHTTP(ctx, listener, api)
+}
+
+// serveHTTP serves handler on listener until ctx is done, then shuts down
+// gracefully
+func serveHTTP(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Requests end with the server, which closes open log streams
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- httpServer.Serve(listener) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info().Msg("Shutting down HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// apiToken reads the token from tokenFile, the command's --token-file, or
+// the environment
+func apiToken(tokenFile string) (string, error) {
+	if tokenFile == "" {
+		return strings.TrimSpace(os.Getenv(tokenEnv)), nil
+	}
+	data, err := os.ReadFile(core.ExpandPath(tokenFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// apiOperations exposes the command functions to the API. Operations that
+// change daemons take the shared lock, as their commands do.
+func apiOperations(a *app.App) server.Operations {
+	return server.Operations{
+		Daemons: a.DaemonNames,
+		List: func(managed bool) (any, error) {
+			if managed {
+				return listManagedDaemons(a)
+			}
+			return listDaemons(a)
+		},
+		Status: func(daemon string) (any, error) {
+			return checkStatus(a, daemon)
+		},
+		Start:   lockedOperation(a, startDaemon),
+		Stop:    lockedOperation(a, stopDaemon),
+		Restart: lockedOperation(a, restartDaemon),
+		Generate: func(daemons []string, force bool) (any, error) {
+			return withLock(func() (any, error) {
+				return runGenerate(a, daemons, force)
+			})
+		},
+		LogFiles: func(daemon string) (server.LogFiles, error) {
+			stdout, stderr, err := logPaths(a, daemon)
+			return server.LogFiles{Stdout: stdout, Stderr: stderr}, err
+		},
+	}
+}
+
+// lockedOperation adapts a lifecycle operation to the API
+func lockedOperation(a *app.App, operation func(*app.App, string) (*operationResult, error)) func(string) (any, error) {
+	return func(daemon string) (any, error) {
+		return withLock(func() (any, error) {
+			return operation(a, daemon)
+		})
+	}
+}
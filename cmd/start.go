@@ -2,13 +2,13 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/mjmorales/daemon-control/internal/utils"
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/errs"
 )
 
 // startCmd represents the start command
@@ -17,74 +17,61 @@ var startCmd = &cobra.Command{
 	Short: "Start a daemon",
 	Long:  `Start a daemon that has been installed.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := startDaemon(daemonName); err != nil {
-			os.Exit(1)
-		}
-	},
+	RunE:  runOperation(startDaemon),
 }
 
 func init() {
 	rootCmd.AddCommand(startCmd)
 }
 
-func startDaemon(daemonName string) error {
-	if err := utils.CheckPlistExists(daemonName); err != nil {
-		return err
+func startDaemon(a *app.App, daemonName string) (*operationResult, error) {
+	if err := a.CheckPlistExists(daemonName); err != nil {
+		return nil, err
 	}
 
-	plistPath := utils.GetPlistPath(daemonName)
-	label, err := utils.GetDaemonLabel(plistPath)
+	label, err := a.Label(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to read daemon label")
-		return err
+		return nil, fmt.Errorf("failed to read daemon label: %w", err)
 	}
 
-	installed, err := utils.IsInstalled(daemonName)
+	installed, err := a.IsInstalled(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check installation status")
-		return err
+		return nil, fmt.Errorf("failed to check installation status: %w", err)
 	}
 
 	if !installed {
-		log.Error().Str("daemon", daemonName).Msg("Daemon not installed. Run 'daemon-control install' first")
-		return fmt.Errorf("daemon not installed")
+		log.Info().Msg("Run 'daemon-control install' first")
+		return nil, fmt.Errorf("%w: %s", errs.ErrNotInstalled, daemonName)
 	}
 
-	running, err := utils.IsRunning(daemonName)
+	running, err := a.IsRunning(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check running status")
-		return err
+		return nil, err
 	}
 
 	if running {
-		log.Warn().Str("daemon", daemonName).Msg("Daemon already running")
-		return nil
+		return nil, fmt.Errorf("%w: %s", errs.ErrAlreadyRunning, daemonName)
 	}
 
 	log.Info().Str("daemon", daemonName).Msg("Starting daemon")
 
-	if err := utils.RunLaunchctl("start", label); err != nil {
-		log.Error().Err(err).Msg("Failed to start daemon")
-		return err
+	if err := a.Backend.Start(label); err != nil {
+		return nil, errs.Backend("launchctl start", err)
 	}
 
 	// Wait a moment and check status
 	time.Sleep(2 * time.Second)
 
-	running, err = utils.IsRunning(daemonName)
+	running, err = a.IsRunning(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check running status after start")
-		return err
+		return nil, err
 	}
 
-	if running {
-		log.Info().Str("daemon", daemonName).Msg("Daemon started successfully")
-	} else {
-		log.Error().Str("daemon", daemonName).Msg("Failed to start daemon. Check logs with 'daemon-control logs'")
-		return fmt.Errorf("daemon failed to start")
+	if !running {
+		log.Info().Msg("Check logs with 'daemon-control logs'")
+		return nil, fmt.Errorf("%w: %s is not running after start", errs.ErrHealthCheck, daemonName)
 	}
 
-	return nil
+	log.Info().Str("daemon", daemonName).Msg("Daemon started successfully")
+	return &operationResult{Daemon: daemonName, Action: "start", Changed: true, Status: "started"}, nil
 }
@@ -1,14 +1,13 @@
 package cmd
 
 import (
-	"context"
-	"os"
-	"os/exec"
-	"strings"
+	"fmt"
+	"io"
+	"text/tabwriter"
 
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/mjmorales/daemon-control/internal/app"
 	"github.com/mjmorales/daemon-control/internal/utils"
 )
 
@@ -18,11 +17,12 @@ var statusCmd = &cobra.Command{
 	Short: "Check daemon status",
 	Long:  `Check the status of a daemon including installation and running state.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := checkStatus(daemonName); err != nil {
-			os.Exit(1)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := checkStatus(appFrom(cmd), args[0])
+		if err != nil {
+			return err
 		}
+		return printResult(result)
 	},
 }
 
@@ -30,64 +30,84 @@ func init() {
 	rootCmd.AddCommand(statusCmd)
 }
 
-func checkStatus(daemonName string) error {
-	if err := utils.CheckPlistExists(daemonName); err != nil {
-		return err
-	}
+// statusResult is the result of the status command
+type statusResult struct {
+	Daemon           string `json:"daemon" yaml:"daemon"`
+	Label            string `json:"label" yaml:"label"`
+	Installed        bool   `json:"installed" yaml:"installed"`
+	Managed          bool   `json:"managed" yaml:"managed"`
+	Drift            string `json:"drift,omitempty" yaml:"drift,omitempty"`
+	Loaded           bool   `json:"loaded" yaml:"loaded"`
+	Running          bool   `json:"running" yaml:"running"`
+	PID              *int   `json:"pid,omitempty" yaml:"pid,omitempty"`
+	LastExitStatus   *int   `json:"last_exit_status,omitempty" yaml:"last_exit_status,omitempty"`
+	WorkingDirectory string `json:"working_directory,omitempty" yaml:"working_directory,omitempty"`
+}
 
-	plistPath := utils.GetPlistPath(daemonName)
-	label, err := utils.GetDaemonLabel(plistPath)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to read daemon label")
-		return err
+func (r statusResult) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Daemon:\t%s\n", r.Daemon)
+	fmt.Fprintf(tw, "Label:\t%s\n", r.Label)
+	fmt.Fprintf(tw, "Installed:\t%t\n", r.Installed)
+	fmt.Fprintf(tw, "Managed:\t%t\n", r.Managed)
+	if r.Drift != "" {
+		fmt.Fprintf(tw, "Drift:\t%s\n", r.Drift)
+	}
+	fmt.Fprintf(tw, "Loaded:\t%t\n", r.Loaded)
+	fmt.Fprintf(tw, "Running:\t%t\n", r.Running)
+	if r.PID != nil {
+		fmt.Fprintf(tw, "PID:\t%d\n", *r.PID)
 	}
+	if r.LastExitStatus != nil {
+		fmt.Fprintf(tw, "Last exit status:\t%d\n", *r.LastExitStatus)
+	}
+	if r.WorkingDirectory != "" {
+		fmt.Fprintf(tw, "Working directory:\t%s\n", r.WorkingDirectory)
+	}
+	return tw.Flush()
+}
 
-	log.Info().Str("daemon", daemonName).Msg("Daemon status")
-	log.Info().Str("label", label).Msg("Label")
+func checkStatus(a *app.App, daemonName string) (*statusResult, error) {
+	if err := a.CheckPlistExists(daemonName); err != nil {
+		return nil, err
+	}
 
-	installed, err := utils.IsInstalled(daemonName)
+	plistPath := a.PlistPath(daemonName)
+	label, err := a.Label(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check installation status")
-		return err
+		return nil, fmt.Errorf("failed to read daemon label: %w", err)
 	}
 
-	if installed {
-		log.Info().Bool("installed", true).Msg("Installation status")
-	} else {
-		log.Warn().Bool("installed", false).Msg("Installation status")
-	}
+	result := &statusResult{Daemon: daemonName, Label: label}
 
-	running, err := utils.IsRunning(daemonName)
+	result.Installed, err = a.IsInstalled(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check running status")
-		return err
+		return nil, fmt.Errorf("failed to check installation status: %w", err)
 	}
 
-	if running {
-		log.Info().Bool("running", true).Msg("Running status")
+	st, err := a.State().Load()
+	if err != nil {
+		return nil, err
+	}
+	if entry, ok := st.Daemons[label]; ok {
+		result.Managed = true
+		result.Drift = string(driftOf(a, entry))
+	}
 
-		// Get process info
-		ctx := context.Background()
-		cmd := exec.CommandContext(ctx, "launchctl", "list")
-		output, err := cmd.Output()
-		if err == nil {
-			lines := strings.Split(string(output), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, label) {
-					log.Info().Str("process_info", strings.TrimSpace(line)).Msg("Process details")
-					break
-				}
-			}
-		}
-	} else {
-		log.Warn().Bool("running", false).Msg("Running status")
+	job, loaded, err := a.Job(daemonName)
+	if err != nil {
+		return nil, err
 	}
+	result.Loaded = loaded
+	result.Running = job.Running()
+	result.PID = job.PID
+	result.LastExitStatus = job.LastExitStatus
 
 	// Show additional info from plist
 	workingDir, err := utils.GetWorkingDirectory(plistPath)
 	if err == nil && workingDir != "" {
-		log.Info().Str("working_directory", workingDir).Msg("Working directory")
+		result.WorkingDirectory = workingDir
 	}
 
-	return nil
+	return result, nil
 }
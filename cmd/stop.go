@@ -1,12 +1,13 @@
 package cmd
 
 import (
-	"os"
+	"fmt"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/mjmorales/daemon-control/internal/utils"
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/errs"
 )
 
 // stopCmd represents the stop command
@@ -15,48 +16,38 @@ var stopCmd = &cobra.Command{
 	Short: "Stop a daemon",
 	Long:  `Stop a running daemon.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := stopDaemon(daemonName); err != nil {
-			os.Exit(1)
-		}
-	},
+	RunE:  runOperation(stopDaemon),
 }
 
 func init() {
 	rootCmd.AddCommand(stopCmd)
 }
 
-func stopDaemon(daemonName string) error {
-	if err := utils.CheckPlistExists(daemonName); err != nil {
-		return err
+func stopDaemon(a *app.App, daemonName string) (*operationResult, error) {
+	if err := a.CheckPlistExists(daemonName); err != nil {
+		return nil, err
 	}
 
-	plistPath := utils.GetPlistPath(daemonName)
-	label, err := utils.GetDaemonLabel(plistPath)
+	label, err := a.Label(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to read daemon label")
-		return err
+		return nil, fmt.Errorf("failed to read daemon label: %w", err)
 	}
 
-	running, err := utils.IsRunning(daemonName)
+	running, err := a.IsRunning(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check running status")
-		return err
+		return nil, err
 	}
 
 	if !running {
-		log.Warn().Str("daemon", daemonName).Msg("Daemon not running")
-		return nil
+		return nil, fmt.Errorf("%w: %s", errs.ErrNotRunning, daemonName)
 	}
 
 	log.Info().Str("daemon", daemonName).Msg("Stopping daemon")
 
-	if err := utils.RunLaunchctl("stop", label); err != nil {
-		log.Error().Err(err).Msg("Failed to stop daemon")
-		return err
+	if err := a.Backend.Stop(label); err != nil {
+		return nil, errs.Backend("launchctl stop", err)
 	}
 
 	log.Info().Str("daemon", daemonName).Msg("Daemon stopped")
-	return nil
+	return &operationResult{Daemon: daemonName, Action: "stop", Changed: true, Status: "stopped"}, nil
 }
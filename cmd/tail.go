@@ -2,13 +2,14 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/mjmorales/daemon-control/internal/utils"
+	"github.com/mjmorales/daemon-control/internal/app"
 )
 
 // tailCmd represents the tail command
@@ -17,11 +18,8 @@ var tailCmd = &cobra.Command{
 	Short: "Tail daemon logs",
 	Long:  `Tail daemon logs in real-time.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := tailLogs(daemonName); err != nil {
-			os.Exit(1)
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return tailLogs(appFrom(cmd), args[0])
 	},
 }
 
@@ -29,26 +27,10 @@ func init() {
 	rootCmd.AddCommand(tailCmd)
 }
 
-func tailLogs(daemonName string) error {
-	if err := utils.CheckPlistExists(daemonName); err != nil {
-		return err
-	}
-
-	plistPath := utils.GetPlistPath(daemonName)
-
-	stdoutPath, err := utils.GetStdoutPath(plistPath)
+func tailLogs(a *app.App, daemonName string) error {
+	stdoutPath, stderrPath, err := logPaths(a, daemonName)
 	if err != nil {
-		stdoutPath = ""
-	}
-
-	stderrPath, err := utils.GetStderrPath(plistPath)
-	if err != nil {
-		stderrPath = ""
-	}
-
-	if stdoutPath == "" && stderrPath == "" {
-		log.Error().Msg("No log paths configured in plist")
-		return nil
+		return err
 	}
 
 	log.Info().Str("daemon", daemonName).Msg("Tailing logs (Ctrl+C to stop)...")
@@ -67,8 +49,7 @@ func tailLogs(daemonName string) error {
 	}
 
 	if len(files) == 0 {
-		log.Error().Msg("No log files found")
-		return nil
+		return fmt.Errorf("no log files found")
 	}
 
 	// Use tail command with context
@@ -1,13 +1,15 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/mjmorales/daemon-control/internal/utils"
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/state"
 )
 
 // uninstallCmd represents the uninstall command
@@ -16,65 +18,57 @@ var uninstallCmd = &cobra.Command{
 	Short: "Uninstall a daemon",
 	Long:  `Uninstall a daemon by removing its plist file from LaunchAgents directory.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		daemonName := args[0]
-		if err := uninstallDaemon(daemonName); err != nil {
-			os.Exit(1)
-		}
-	},
+	RunE:  runOperation(uninstallDaemon),
 }
 
 func init() {
 	rootCmd.AddCommand(uninstallCmd)
 }
 
-func uninstallDaemon(daemonName string) error {
-	if err := utils.CheckPlistExists(daemonName); err != nil {
-		return err
+func uninstallDaemon(a *app.App, daemonName string) (*operationResult, error) {
+	if err := a.CheckPlistExists(daemonName); err != nil {
+		return nil, err
 	}
 
-	plistPath := utils.GetPlistPath(daemonName)
-	label, err := utils.GetDaemonLabel(plistPath)
+	label, err := a.Label(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to read daemon label")
-		return err
+		return nil, fmt.Errorf("failed to read daemon label: %w", err)
 	}
 
-	installed, err := utils.IsInstalled(daemonName)
+	installed, err := a.IsInstalled(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check installation status")
-		return err
+		return nil, fmt.Errorf("failed to check installation status: %w", err)
 	}
 
 	if !installed {
-		log.Warn().Str("daemon", daemonName).Msg("Daemon not installed")
-		return nil
+		return nil, fmt.Errorf("%w: %s", errs.ErrNotInstalled, daemonName)
 	}
 
 	log.Info().Str("daemon", daemonName).Msg("Uninstalling daemon")
 
 	// Stop if running
-	running, err := utils.IsRunning(daemonName)
+	running, err := a.IsRunning(daemonName)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check running status")
-		return err
+		return nil, err
 	}
 
-	installedPath := filepath.Join(utils.LaunchAgentsDir, label+".plist")
+	installedPath := a.InstalledPath(label)
 
 	if running {
-		if err := utils.RunLaunchctl("unload", installedPath); err != nil {
-			log.Error().Err(err).Msg("Failed to unload daemon")
-			return err
+		if err := a.Backend.Unload(installedPath); err != nil {
+			return nil, errs.Backend("launchctl unload", err)
 		}
 	}
 
 	// Remove plist
 	if err := os.Remove(installedPath); err != nil {
-		log.Error().Err(err).Msg("Failed to remove plist file")
-		return err
+		return nil, fmt.Errorf("failed to remove plist file: %w", err)
+	}
+
+	if err := a.State().Update(func(s *state.State) { s.Remove(label) }); err != nil {
+		log.Warn().Err(err).Str("daemon", daemonName).Msg("Failed to remove daemon from state file")
 	}
 
 	log.Info().Str("daemon", daemonName).Msg("Daemon uninstalled successfully")
-	return nil
+	return &operationResult{Daemon: daemonName, Action: "uninstall", Changed: true, Status: "uninstalled"}, nil
 }
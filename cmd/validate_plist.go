@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/plist"
+)
+
+var (
+	validateAllowKeys    []string
+	validateAllowUnknown bool
+)
+
+// validatePlistCmd represents the validate-plist command
+var validatePlistCmd = &cobra.Command{
+	Use:   "validate-plist <file>...",
+	Short: "Check plist files against launchd's rules",
+	Long: `Check plist files against the keys documented in launchd.plist(5).
+
+Every key must have the type launchd expects, Label and Program or
+ProgramArguments are required, and keys that depend on or exclude each
+other must be combined correctly. Keys launchd does not document are
+rejected unless allowed with --allow-key or --allow-unknown.
+
+generate runs the same checks when validate_plists is enabled. The exit
+status is 11 when any file has problems.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result := validatePlists(args)
+		if err := printResult(result); err != nil {
+			return err
+		}
+		for _, file := range result.Files {
+			if len(file.Problems) > 0 {
+				return errs.Silent(errs.ExitValidation)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validatePlistCmd)
+
+	validatePlistCmd.Flags().StringSliceVar(&validateAllowKeys, "allow-key", nil, "Accept an undocumented top-level key (repeatable)")
+	validatePlistCmd.Flags().BoolVar(&validateAllowUnknown, "allow-unknown", false, "Accept every undocumented top-level key")
+}
+
+// validatedPlist is a plist file checked by validate-plist
+type validatedPlist struct {
+	Path     string          `json:"path" yaml:"path"`
+	Problems []plist.Problem `json:"problems" yaml:"problems"`
+}
+
+// validatePlistResult is the result of the validate-plist command
+type validatePlistResult struct {
+	Files []validatedPlist `json:"files" yaml:"files"`
+}
+
+func (r validatePlistResult) writeText(w io.Writer) error {
+	for _, file := range r.Files {
+		if len(file.Problems) == 0 {
+			if _, err := fmt.Fprintf(w, "%s: ok\n", file.Path); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, problem := range file.Problems {
+			if _, err := fmt.Fprintf(w, "%s: %s\n", file.Path, problem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validatePlists checks each file, reporting files that cannot be parsed
+// as a problem
+func validatePlists(paths []string) validatePlistResult {
+	validator := plist.Validator{AllowedKeys: validateAllowKeys, AllowUnknown: validateAllowUnknown}
+
+	result := validatePlistResult{Files: make([]validatedPlist, 0, len(paths))}
+	for _, path := range paths {
+		file := validatedPlist{Path: path, Problems: []plist.Problem{}}
+
+		p, err := plist.ParseFile(path)
+		if err != nil {
+			file.Problems = append(file.Problems, plist.Problem{Message: err.Error()})
+		} else if problems := validator.Validate(p.Dict); len(problems) > 0 {
+			file.Problems = problems
+		}
+		result.Files = append(result.Files, file)
+	}
+	return result
+}
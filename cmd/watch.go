@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mjmorales/daemon-control/internal/app"
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/plist"
+	"github.com/mjmorales/daemon-control/internal/watch"
+)
+
+var watchDebounce time.Duration
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Regenerate and reload daemons when the configuration changes",
+	Long: `Watch the daemon configuration file. The configuration is a single file
+with no includes, so it is the only file watched. If it is a symlink, the
+file it points to is watched too. After each change has settled, the
+configuration is validated, the plists whose definition changed are
+regenerated, and installed daemons whose plist changed are reloaded.
+
+If the configuration or a generated plist is invalid, the error is logged
+and nothing is written or reloaded, so the daemons keep running from the
+last good configuration until the file is fixed.
+
+watch regenerates once at startup and then runs until interrupted. It
+takes the shared lock only while regenerating, waiting for other
+daemon-control commands to finish.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runWatch(ctx, appFrom(cmd))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 500*time.Millisecond, "How long the configuration must be unchanged before regenerating")
+}
+
+// watchFailure is a daemon that could not be reloaded
+type watchFailure struct {
+	Daemon string `json:"daemon" yaml:"daemon"`
+	Error  string `json:"error" yaml:"error"`
+}
+
+// watchResult is the outcome of regenerating after a configuration change
+type watchResult struct {
+	Time      time.Time       `json:"time" yaml:"time"`
+	Error     string          `json:"error,omitempty" yaml:"error,omitempty"`
+	Generated *generateResult `json:"generated,omitempty" yaml:"generated,omitempty"`
+	Reloaded  []string        `json:"reloaded" yaml:"reloaded"`
+	Failed    []watchFailure  `json:"failed,omitempty" yaml:"failed,omitempty"`
+}
+
+func (r watchResult) writeText(w io.Writer) error {
+	stamp := r.Time.Format("15:04:05")
+	if r.Error != "" {
+		_, err := fmt.Fprintf(w, "%s configuration rejected, keeping the last good state: %s\n", stamp, r.Error)
+		return err
+	}
+
+	changed := r.Generated.changed()
+	if len(changed) == 0 {
+		_, err := fmt.Fprintf(w, "%s no plists changed\n", stamp)
+		return err
+	}
+	for _, p := range r.Generated.Plists {
+		if p.Status == generateUnchanged {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s %s: plist %s\n", stamp, p.Daemon, p.Status); err != nil {
+			return err
+		}
+	}
+	for _, name := range r.Reloaded {
+		if _, err := fmt.Fprintf(w, "%s %s: reloaded\n", stamp, name); err != nil {
+			return err
+		}
+	}
+	for _, failure := range r.Failed {
+		if _, err := fmt.Fprintf(w, "%s %s: reload failed: %s\n", stamp, failure.Daemon, failure.Error); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runWatch regenerates once and then after every change to the daemon
+// configuration until ctx is done
+func runWatch(ctx context.Context, a *app.App) error {
+	path := a.DaemonConfigPath()
+	if path == "" {
+		return fmt.Errorf("daemon_config_path is not set, watch needs a configuration file to watch")
+	}
+
+	watcher, err := watch.New([]string{path}, watchDebounce)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	log.Info().Str("config", path).Msg("Watching daemon configuration (Ctrl+C to stop)...")
+
+	regenerate := func() {
+		if err := printResult(watchRegenerate(a)); err != nil {
+			log.Error().Err(err).Msg("Failed to write watch result")
+		}
+	}
+	regenerate()
+	return watcher.Run(ctx, regenerate)
+}
+
+// watchRegenerate validates the configuration, regenerates changed plists
+// and reloads the installed daemons whose plist changed. Errors are
+// reported in the result so that watch keeps running.
+func watchRegenerate(a *app.App) *watchResult {
+	result := &watchResult{Time: time.Now(), Reloaded: []string{}}
+
+	l, err := acquireLock(true)
+	if err != nil {
+		result.Error = err.Error()
+		log.Error().Err(err).Msg("Failed to take the lock, skipping regeneration")
+		return result
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warn().Err(err).Msg("Failed to release lock")
+		}
+	}()
+
+	generated, err := regenerateValid(a)
+	if err != nil {
+		result.Error = err.Error()
+		log.Error().Err(err).Msg("Configuration rejected, keeping the last good state")
+		return result
+	}
+	result.Generated = generated
+
+	for _, p := range generated.Plists {
+		if p.Status == generateUnchanged {
+			continue
+		}
+		reloaded, err := reloadGenerated(a, p)
+		if err != nil {
+			result.Failed = append(result.Failed, watchFailure{Daemon: p.Daemon, Error: err.Error()})
+			log.Error().Err(err).Str("daemon", p.Daemon).Msg("Failed to reload daemon")
+			continue
+		}
+		if reloaded {
+			result.Reloaded = append(result.Reloaded, p.Daemon)
+			log.Info().Str("daemon", p.Daemon).Msg("Reloaded daemon")
+		}
+	}
+	return result
+}
+
+// regenerateValid loads the daemon configuration and generates its plists.
+// Every plist is validated before any is written, so an invalid
+// definition leaves all of them as they were.
+func regenerateValid(a *app.App) (*generateResult, error) {
+	cfg, err := config.NewLoader(a.DaemonConfigPath()).Load()
+	if err != nil {
+		return nil, err
+	}
+
+	validator := plist.NewGenerator(a.OutputDir()).WithEnvironment(a.Config.DaemonEnvironment())
+	for _, daemon := range cfg.Daemons {
+		if err := validator.Validate(&daemon); err != nil {
+			return nil, fmt.Errorf("invalid plist for %s: %w", daemon.Name, err)
+		}
+	}
+
+	return generateDaemons(a, cfg.Daemons, true, false)
+}
+
+// reloadGenerated replaces and reloads a daemon's installed plist with the
+// newly generated one. Daemons that are not installed, or whose installed
+// plist already matches, are left alone.
+func reloadGenerated(a *app.App, p generatedPlist) (bool, error) {
+	data, err := os.ReadFile(p.Path) // #nosec G304 - path is in the output directory
+	if err != nil {
+		return false, fmt.Errorf("failed to read generated plist: %w", err)
+	}
+	parsed, err := plist.Parse(data)
+	if err != nil {
+		return false, err
+	}
+	label, ok := parsed.Dict.GetString("Label")
+	if !ok || label == "" {
+		return false, fmt.Errorf("generated plist has no Label")
+	}
+
+	// The label may have changed, so find the installed copy from the
+	// state file
+	previousLabel := label
+	st, err := a.State().Load()
+	if err != nil {
+		return false, err
+	}
+	if entry, ok := st.Find(p.Daemon); ok {
+		previousLabel = entry.Label
+	}
+
+	installed, err := os.ReadFile(a.InstalledPath(previousLabel))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read installed plist: %w", err)
+	}
+	if bytes.Equal(installed, data) {
+		return false, nil
+	}
+
+	if err := reinstallPlist(a, p.Daemon, previousLabel, label, data); err != nil {
+		return false, err
+	}
+	recordRevision(a, p.Daemon, data, "", history.SourceInstall)
+	return true, nil
+}
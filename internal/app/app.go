@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+	"github.com/mjmorales/daemon-control/internal/plist"
+	"github.com/mjmorales/daemon-control/internal/state"
+	"github.com/mjmorales/daemon-control/internal/utils"
+)
+
+// App carries the resolved configuration and dependencies shared by commands
+type App struct {
+	Config          *core.CoreConfig
+	DaemonsDir      string
+	LaunchAgentsDir string
+	Backend         launchd.Backend
+	Logger          zerolog.Logger
+}
+
+// New creates an App from a resolved core configuration. Paths missing
+// from the configuration fall back to defaults.
+func New(cfg *core.CoreConfig, backend launchd.Backend, logger zerolog.Logger) *App {
+	if cfg == nil {
+		cfg = core.DefaultConfig()
+	}
+
+	daemonsDir := cfg.DaemonsDir
+	if daemonsDir == "" {
+		daemonsDir = filepath.Join(executableDir(), "daemons")
+	}
+
+	launchAgentsDir := cfg.LaunchAgentsDir
+	if launchAgentsDir == "" {
+		home, _ := os.UserHomeDir()
+		launchAgentsDir = filepath.Join(home, "Library", "LaunchAgents")
+	}
+
+	return &App{
+		Config:          cfg,
+		DaemonsDir:      core.ExpandPath(daemonsDir),
+		LaunchAgentsDir: core.ExpandPath(launchAgentsDir),
+		Backend:         backend,
+		Logger:          logger,
+	}
+}
+
+// contextKey is the key of the App in a context
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying a
+func NewContext(ctx context.Context, a *App) context.Context {
+	return context.WithValue(ctx, contextKey{}, a)
+}
+
+// FromContext returns the App carried by ctx, or nil if there is none
+func FromContext(ctx context.Context) *App {
+	a, _ := ctx.Value(contextKey{}).(*App)
+	return a
+}
+
+// DaemonConfigPath returns the resolved daemon config path
+func (a *App) DaemonConfigPath() string {
+	return core.ExpandPath(a.Config.DaemonConfigPath)
+}
+
+// OutputDir returns the directory generate writes plists to
+func (a *App) OutputDir() string {
+	if a.Config.OutputDir == "" {
+		return "out"
+	}
+	return core.ExpandPath(a.Config.OutputDir)
+}
+
+// PlistPath returns the path to a daemon's plist in the daemons directory
+func (a *App) PlistPath(daemonName string) string {
+	return filepath.Join(a.DaemonsDir, daemonName+".plist")
+}
+
+// InstalledPath returns the path a daemon with the given label is
+// installed at
+func (a *App) InstalledPath(label string) string {
+	return filepath.Join(a.LaunchAgentsDir, label+".plist")
+}
+
+// History returns the plist history store, kept in the daemons directory
+// and limited by the configured retention
+func (a *App) History() *history.Store {
+	return history.NewStore(filepath.Join(a.DaemonsDir, ".history"), history.Retention{
+		MaxRevisions: a.Config.HistoryMaxRevisions,
+		MaxAge:       time.Duration(a.Config.HistoryMaxAgeDays) * 24 * time.Hour,
+	})
+}
+
+// State returns the store recording the daemons daemon-control installed
+func (a *App) State() *state.Store {
+	return state.NewStore(filepath.Join(core.ConfigDir(), "state.json"))
+}
+
+// Domain returns the launchd domain daemons are installed into
+func (a *App) Domain() string {
+	return launchd.Domain(a.Config.UseSystemLaunchd)
+}
+
+// CheckPlistExists verifies that a daemon's plist file exists
+func (a *App) CheckPlistExists(daemonName string) error {
+	if _, err := os.Stat(a.PlistPath(daemonName)); os.IsNotExist(err) {
+		a.Logger.Info().Msg("Available daemons:")
+		for _, name := range a.DaemonNames() {
+			a.Logger.Info().Str("daemon", name).Msg("")
+		}
+		return fmt.Errorf("%w: %s", errs.ErrNotFound, daemonName)
+	}
+	return nil
+}
+
+// DaemonNames returns the names of the daemons in the daemons directory
+func (a *App) DaemonNames() []string {
+	files, _ := filepath.Glob(filepath.Join(a.DaemonsDir, "*.plist"))
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".plist"))
+	}
+	return names
+}
+
+// Label returns the launchd label of a daemon
+func (a *App) Label(daemonName string) (string, error) {
+	return plist.ReadLabel(a.PlistPath(daemonName))
+}
+
+// IsInstalled checks if a daemon is installed in LaunchAgents
+func (a *App) IsInstalled(daemonName string) (bool, error) {
+	label, err := a.Label(daemonName)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(a.InstalledPath(label)); err == nil {
+		return true, nil
+	}
+	return false, nil
+}
+
+// Install writes data as the installed plist for label and loads it. The
+// write is atomic, and if launchd fails to load the new plist the one
+// installed before, if any, is put back and reloaded.
+func (a *App) Install(label string, data []byte) error {
+	if err := os.MkdirAll(a.LaunchAgentsDir, 0750); err != nil {
+		return fmt.Errorf("failed to create LaunchAgents directory: %w", err)
+	}
+
+	path := a.InstalledPath(label)
+	previous, readErr := os.ReadFile(path) // #nosec G304 - path is in the LaunchAgents directory
+	if err := utils.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write plist file: %w", err)
+	}
+
+	loadErr := a.Backend.Load(path)
+	if loadErr == nil {
+		return nil
+	}
+	loadErr = errs.Backend("launchctl load", loadErr)
+
+	if readErr != nil {
+		if err := os.Remove(path); err != nil {
+			a.Logger.Warn().Err(err).Str("path", path).Msg("Failed to remove plist that could not be loaded")
+		}
+		return loadErr
+	}
+	if err := utils.WriteFileAtomic(path, previous, 0600); err != nil {
+		a.Logger.Warn().Err(err).Str("path", path).Msg("Failed to restore previous plist")
+		return loadErr
+	}
+	if err := a.Backend.Load(path); err != nil {
+		a.Logger.Warn().Err(err).Str("path", path).Msg("Failed to reload previous plist")
+	} else {
+		a.Logger.Info().Str("path", path).Msg("Restored previous plist")
+	}
+	return loadErr
+}
+
+// Job returns the launchd job for a daemon, if it is loaded
+func (a *App) Job(daemonName string) (launchd.Job, bool, error) {
+	label, err := a.Label(daemonName)
+	if err != nil {
+		return launchd.Job{}, false, err
+	}
+
+	jobs, err := a.Backend.List()
+	if err != nil {
+		return launchd.Job{}, false, errs.Backend("launchctl list", err)
+	}
+
+	job, ok := launchd.Find(jobs, label)
+	return job, ok, nil
+}
+
+// IsRunning checks if a daemon is currently running
+func (a *App) IsRunning(daemonName string) (bool, error) {
+	job, ok, err := a.Job(daemonName)
+	if err != nil {
+		return false, err
+	}
+	return ok && job.Running(), nil
+}
+
+func executableDir() string {
+	ex, err := os.Executable()
+	if err != nil {
+		return "."
+	}
+	return filepath.Dir(ex)
+}
@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/core"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+	"github.com/mjmorales/daemon-control/internal/launchd/launchdtest"
+)
+
+const testPlistContent = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.test</string>
+</dict>
+</plist>`
+
+func newTestApp(t *testing.T, backend launchd.Backend) *App {
+	t.Helper()
+	cfg := core.DefaultConfig()
+	cfg.DaemonsDir = t.TempDir()
+	cfg.LaunchAgentsDir = t.TempDir()
+	return New(cfg, backend, zerolog.Nop())
+}
+
+func TestNew(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                string
+		config              *core.CoreConfig
+		wantDaemonsDir      string
+		wantLaunchAgentsDir string
+	}{
+		{
+			name:                "paths from config",
+			config:              &core.CoreConfig{DaemonsDir: "/srv/daemons", LaunchAgentsDir: "/srv/agents"},
+			wantDaemonsDir:      "/srv/daemons",
+			wantLaunchAgentsDir: "/srv/agents",
+		},
+		{
+			name:                "home directory is expanded",
+			config:              &core.CoreConfig{DaemonsDir: "~/daemons", LaunchAgentsDir: "~/agents"},
+			wantDaemonsDir:      filepath.Join(home, "daemons"),
+			wantLaunchAgentsDir: filepath.Join(home, "agents"),
+		},
+		{
+			name:                "defaults when unset",
+			config:              &core.CoreConfig{},
+			wantLaunchAgentsDir: filepath.Join(home, "Library", "LaunchAgents"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(tt.config, &launchdtest.Backend{}, zerolog.Nop())
+			if tt.wantDaemonsDir != "" {
+				assert.Equal(t, tt.wantDaemonsDir, a.DaemonsDir)
+			} else {
+				assert.Equal(t, "daemons", filepath.Base(a.DaemonsDir))
+			}
+			assert.Equal(t, tt.wantLaunchAgentsDir, a.LaunchAgentsDir)
+		})
+	}
+}
+
+func TestNew_DoesNotTouchConfigDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	New(nil, &launchdtest.Backend{}, zerolog.Nop())
+
+	_, err := os.Stat(core.ConfigDir())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApp_OutputDir(t *testing.T) {
+	a := New(&core.CoreConfig{}, &launchdtest.Backend{}, zerolog.Nop())
+	assert.Equal(t, "out", a.OutputDir())
+
+	a = New(&core.CoreConfig{OutputDir: "/srv/out"}, &launchdtest.Backend{}, zerolog.Nop())
+	assert.Equal(t, "/srv/out", a.OutputDir())
+}
+
+func TestApp_PlistPath(t *testing.T) {
+	a := New(&core.CoreConfig{DaemonsDir: "/srv/daemons"}, &launchdtest.Backend{}, zerolog.Nop())
+	assert.Equal(t, "/srv/daemons/com.example.daemon.plist", a.PlistPath("com.example.daemon"))
+}
+
+func TestApp_CheckPlistExists(t *testing.T) {
+	a := newTestApp(t, &launchdtest.Backend{})
+	require.NoError(t, os.WriteFile(a.PlistPath("existing-daemon"), []byte("test"), 0600))
+
+	tests := []struct {
+		name       string
+		daemonName string
+		wantError  bool
+	}{
+		{
+			name:       "existing daemon",
+			daemonName: "existing-daemon",
+			wantError:  false,
+		},
+		{
+			name:       "non-existent daemon",
+			daemonName: "non-existent",
+			wantError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.CheckPlistExists(tt.daemonName)
+
+			if tt.wantError {
+				assert.ErrorIs(t, err, errs.ErrNotFound)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, []string{"existing-daemon"}, a.DaemonNames())
+}
+
+func TestApp_IsInstalled(t *testing.T) {
+	a := newTestApp(t, &launchdtest.Backend{})
+	require.NoError(t, os.WriteFile(a.PlistPath("test-daemon"), []byte(testPlistContent), 0600))
+
+	installed, err := a.IsInstalled("test-daemon")
+	assert.NoError(t, err)
+	assert.False(t, installed)
+
+	require.NoError(t, os.WriteFile(a.InstalledPath("com.example.test"), []byte(testPlistContent), 0600))
+
+	installed, err = a.IsInstalled("test-daemon")
+	assert.NoError(t, err)
+	assert.True(t, installed)
+}
+
+func TestApp_IsRunning(t *testing.T) {
+	pid := 42
+	backend := &launchdtest.Backend{}
+	a := newTestApp(t, backend)
+	require.NoError(t, os.WriteFile(a.PlistPath("test-daemon"), []byte(testPlistContent), 0600))
+
+	running, err := a.IsRunning("test-daemon")
+	assert.NoError(t, err)
+	assert.False(t, running)
+
+	// Loaded but not running
+	backend.Jobs = []launchd.Job{{Label: "com.example.test"}}
+	running, err = a.IsRunning("test-daemon")
+	assert.NoError(t, err)
+	assert.False(t, running)
+
+	backend.Jobs = []launchd.Job{{Label: "com.example.test", PID: &pid}}
+	running, err = a.IsRunning("test-daemon")
+	assert.NoError(t, err)
+	assert.True(t, running)
+}
+
+func TestApp_Label(t *testing.T) {
+	a := newTestApp(t, &launchdtest.Backend{})
+	require.NoError(t, os.WriteFile(a.PlistPath("test-daemon"), []byte(testPlistContent), 0600))
+
+	label, err := a.Label("test-daemon")
+	assert.NoError(t, err)
+	assert.Equal(t, "com.example.test", label)
+
+	_, err = a.Label("missing")
+	assert.Error(t, err)
+}
+
+func TestContext(t *testing.T) {
+	a := newTestApp(t, &launchdtest.Backend{})
+	assert.Same(t, a, FromContext(NewContext(context.Background(), a)))
+	assert.Nil(t, FromContext(context.Background()))
+}
+
+func TestApp_Install(t *testing.T) {
+	tests := []struct {
+		name      string
+		previous  string
+		failLoads int
+		wantError bool
+		want      string
+		wantLoads int
+	}{
+		{name: "new plist", want: "new", wantLoads: 1},
+		{name: "replaces plist", previous: "old", want: "new", wantLoads: 1},
+		{name: "load failure removes new plist", failLoads: 1, wantError: true, wantLoads: 1},
+		{name: "load failure restores previous plist", previous: "old", failLoads: 1, wantError: true, want: "old", wantLoads: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &launchdtest.Backend{FailLoads: tt.failLoads}
+			a := newTestApp(t, backend)
+			path := a.InstalledPath("com.example.test")
+			if tt.previous != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tt.previous), 0600))
+			}
+
+			err := a.Install("com.example.test", []byte("new"))
+			if tt.wantError {
+				assert.ErrorIs(t, err, errs.ErrBackend)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantLoads, backend.Loads)
+
+			data, err := os.ReadFile(path)
+			if tt.want == "" {
+				assert.True(t, os.IsNotExist(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+// EffectiveEnvironment returns the environment variables for the daemon:
+// global provides values for every daemon and the daemon's own
+// environment_variables override them.
+func (d *Daemon) EffectiveEnvironment(global map[string]string) map[string]string {
+	if len(global) == 0 && len(d.EnvironmentVariables) == 0 {
+		return nil
+	}
+
+	env := make(map[string]string, len(global)+len(d.EnvironmentVariables))
+	for name, value := range global {
+		env[name] = value
+	}
+	for name, value := range d.EnvironmentVariables {
+		env[name] = value
+	}
+	return env
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemon_EffectiveEnvironment(t *testing.T) {
+	tests := []struct {
+		name   string
+		daemon Daemon
+		global map[string]string
+		want   map[string]string
+	}{
+		{
+			name:   "no environment",
+			daemon: Daemon{},
+			want:   nil,
+		},
+		{
+			name:   "global only",
+			daemon: Daemon{},
+			global: map[string]string{"PATH": "/usr/bin"},
+			want:   map[string]string{"PATH": "/usr/bin"},
+		},
+		{
+			name:   "daemon only",
+			daemon: Daemon{EnvironmentVariables: map[string]string{"PORT": "8080"}},
+			want:   map[string]string{"PORT": "8080"},
+		},
+		{
+			name: "daemon overrides global",
+			daemon: Daemon{EnvironmentVariables: map[string]string{
+				"PATH": "/opt/app/bin",
+				"PORT": "8080",
+			}},
+			global: map[string]string{"PATH": "/usr/bin", "LANG": "en_US.UTF-8"},
+			want: map[string]string{
+				"PATH": "/opt/app/bin",
+				"PORT": "8080",
+				"LANG": "en_US.UTF-8",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global := maps.Clone(tt.global)
+			assert.Equal(t, tt.want, tt.daemon.EffectiveEnvironment(tt.global))
+			// The inputs are left untouched
+			assert.Equal(t, global, tt.global)
+		})
+	}
+}
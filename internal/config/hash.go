@@ -0,0 +1,24 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Hash returns a digest of the whole daemon definition, including fields
+// that do not affect the rendered plist, so it identifies the config a
+// plist was generated from.
+func (d *Daemon) Hash() string {
+	data, err := json.Marshal(d)
+	if err != nil {
+		// Extra plist keys may hold NaN or infinite reals, which JSON
+		// cannot encode. YAML can, and sorts map keys as JSON does, so the
+		// digest is still stable.
+		data, _ = yaml.Marshal(d)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemon_Hash(t *testing.T) {
+	daemon := Daemon{
+		Name:                 "test",
+		Label:                "com.example.test",
+		Program:              "/usr/bin/test",
+		EnvironmentVariables: map[string]string{"B": "2", "A": "1"},
+	}
+	same := daemon
+	same.EnvironmentVariables = map[string]string{"A": "1", "B": "2"}
+	changed := daemon
+	changed.Program = "/usr/bin/other"
+
+	assert.Len(t, daemon.Hash(), 64)
+	assert.Equal(t, daemon.Hash(), same.Hash())
+	assert.NotEqual(t, daemon.Hash(), changed.Hash())
+}
+
+func TestDaemon_HashNotJSON(t *testing.T) {
+	// JSON cannot encode NaN, so the digest falls back to YAML, which must
+	// not depend on where the definition is in memory
+	daemon := func() *Daemon {
+		return &Daemon{
+			Name:           "test",
+			KeepAlive:      &KeepAlive{SuccessfulExit: boolPtr(false)},
+			ExtraPlistKeys: map[string]interface{}{"Ratio": math.NaN(), "B": 2, "A": 1},
+		}
+	}
+	changed := daemon()
+	changed.ExtraPlistKeys["B"] = 3
+
+	assert.Len(t, daemon().Hash(), 64)
+	assert.Equal(t, daemon().Hash(), daemon().Hash())
+	assert.NotEqual(t, daemon().Hash(), changed.Hash())
+}
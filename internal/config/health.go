@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// DefaultHealthCheckTimeout bounds a health check without a timeout
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// TimeoutDuration returns how long the check may take
+func (h *HealthCheck) TimeoutDuration() time.Duration {
+	if h.Timeout <= 0 {
+		return DefaultHealthCheckTimeout
+	}
+	return time.Duration(h.Timeout) * time.Second
+}
+
+// validateHealthCheck checks that exactly one kind of check is set and
+// that it is well formed
+func validateHealthCheck(h *HealthCheck) error {
+	kinds := 0
+	if len(h.Command) > 0 {
+		kinds++
+	}
+	if h.HTTP != "" {
+		kinds++
+		u, err := url.Parse(h.HTTP)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("http must be an http or https URL: %q", h.HTTP)
+		}
+	}
+	if h.TCP != "" {
+		kinds++
+		if _, _, err := net.SplitHostPort(h.TCP); err != nil {
+			return fmt.Errorf("tcp must be host:port: %q", h.TCP)
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("exactly one of command, http or tcp is required")
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHealthCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   HealthCheck
+		wantErr string
+	}{
+		{name: "command", check: HealthCheck{Command: []string{"/usr/bin/true"}}},
+		{name: "http", check: HealthCheck{HTTP: "https://localhost:8443/health"}},
+		{name: "tcp", check: HealthCheck{TCP: "127.0.0.1:5432", Timeout: 1}},
+		{name: "none", check: HealthCheck{}, wantErr: "exactly one of command, http or tcp is required"},
+		{name: "two kinds", check: HealthCheck{HTTP: "http://localhost/", TCP: "localhost:80"}, wantErr: "exactly one"},
+		{name: "not a URL", check: HealthCheck{HTTP: "localhost:8080"}, wantErr: "http must be an http or https URL"},
+		{name: "tcp without port", check: HealthCheck{TCP: "localhost"}, wantErr: "tcp must be host:port"},
+		{name: "negative timeout", check: HealthCheck{TCP: "localhost:80", Timeout: -1}, wantErr: "timeout must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHealthCheck(&tt.check)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHealthCheck_TimeoutDuration(t *testing.T) {
+	assert.Equal(t, DefaultHealthCheckTimeout, (&HealthCheck{}).TimeoutDuration())
+	assert.Equal(t, 3*time.Second, (&HealthCheck{Timeout: 3}).TimeoutDuration())
+}
@@ -0,0 +1,19 @@
+package config
+
+// KeptAlive reports whether launchd restarts the daemon after it exits:
+// keep_alive is true or sets any condition
+func (d *Daemon) KeptAlive() bool {
+	k := d.KeepAlive
+	if k == nil {
+		return false
+	}
+	if k.Always != nil {
+		return *k.Always
+	}
+	return k.SuccessfulExit != nil ||
+		k.NetworkState != nil ||
+		k.Crashed != nil ||
+		k.AfterInitialDemand != nil ||
+		len(k.PathState) > 0 ||
+		len(k.OtherJobEnabled) > 0
+}
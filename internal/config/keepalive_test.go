@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemon_KeptAlive(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepAlive *KeepAlive
+		want      bool
+	}{
+		{name: "unset", want: false},
+		{name: "true", keepAlive: &KeepAlive{Always: boolPtr(true)}, want: true},
+		{name: "false", keepAlive: &KeepAlive{Always: boolPtr(false)}, want: false},
+		{name: "condition", keepAlive: &KeepAlive{SuccessfulExit: boolPtr(false)}, want: true},
+		{name: "path condition", keepAlive: &KeepAlive{PathState: map[string]bool{"/tmp/run": true}}, want: true},
+		{name: "no conditions", keepAlive: &KeepAlive{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Daemon{KeepAlive: tt.keepAlive}
+			assert.Equal(t, tt.want, d.KeptAlive())
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import "fmt"
+
+// EffectiveResourceLimits returns the soft and hard limits for the daemon.
+// resource_limits provides values for both; soft_resource_limits and
+// hard_resource_limits override it field by field.
+func (d *Daemon) EffectiveResourceLimits() (soft, hard *ResourceLimits) {
+	return mergeResourceLimits(d.ResourceLimits, d.SoftResourceLimits),
+		mergeResourceLimits(d.ResourceLimits, d.HardResourceLimits)
+}
+
+// mergeResourceLimits overlays the set fields of override onto base
+func mergeResourceLimits(base, override *ResourceLimits) *ResourceLimits {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	var merged ResourceLimits
+	if base != nil {
+		merged = *base
+	}
+	if override == nil {
+		return &merged
+	}
+
+	if override.CPU != nil {
+		merged.CPU = override.CPU
+	}
+	if override.FileSize != nil {
+		merged.FileSize = override.FileSize
+	}
+	if override.NumberOfFiles != nil {
+		merged.NumberOfFiles = override.NumberOfFiles
+	}
+	if override.Core != nil {
+		merged.Core = override.Core
+	}
+	if override.Data != nil {
+		merged.Data = override.Data
+	}
+	if override.MemoryLock != nil {
+		merged.MemoryLock = override.MemoryLock
+	}
+	if override.NumberOfProcesses != nil {
+		merged.NumberOfProcesses = override.NumberOfProcesses
+	}
+	if override.ResidentSetSize != nil {
+		merged.ResidentSetSize = override.ResidentSetSize
+	}
+	if override.Stack != nil {
+		merged.Stack = override.Stack
+	}
+
+	return &merged
+}
+
+// resourceLimitValue is a single named limit
+type resourceLimitValue struct {
+	key   string
+	value *int64
+	size  bool
+}
+
+// values lists every limit by its config key
+func (r *ResourceLimits) values() []resourceLimitValue {
+	if r == nil {
+		return nil
+	}
+
+	intValue := func(v *int) *int64 {
+		if v == nil {
+			return nil
+		}
+		n := int64(*v)
+		return &n
+	}
+	sizeValue := func(v *ByteSize) *int64 {
+		if v == nil {
+			return nil
+		}
+		n := int64(*v)
+		return &n
+	}
+
+	return []resourceLimitValue{
+		{"cpu", intValue(r.CPU), false},
+		{"file_size", sizeValue(r.FileSize), true},
+		{"number_of_files", intValue(r.NumberOfFiles), false},
+		{"core", sizeValue(r.Core), true},
+		{"data", sizeValue(r.Data), true},
+		{"memory_lock", sizeValue(r.MemoryLock), true},
+		{"number_of_processes", intValue(r.NumberOfProcesses), false},
+		{"resident_set_size", sizeValue(r.ResidentSetSize), true},
+		{"stack", sizeValue(r.Stack), true},
+	}
+}
+
+// validateResourceLimits checks that limits are non-negative and that
+// every soft limit is at most the matching hard limit
+func validateResourceLimits(daemon *Daemon) error {
+	soft, hard := daemon.EffectiveResourceLimits()
+
+	for _, limits := range []*ResourceLimits{soft, hard} {
+		for _, limit := range limits.values() {
+			if limit.value != nil && *limit.value < 0 {
+				return fmt.Errorf("resource limit %s must not be negative", limit.key)
+			}
+		}
+	}
+
+	if soft == nil || hard == nil {
+		return nil
+	}
+
+	hardValues := hard.values()
+	for i, limit := range soft.values() {
+		hardLimit := hardValues[i]
+		if limit.value == nil || hardLimit.value == nil || *limit.value <= *hardLimit.value {
+			continue
+		}
+
+		softText, hardText := fmt.Sprint(*limit.value), fmt.Sprint(*hardLimit.value)
+		if limit.size {
+			softText, hardText = ByteSize(*limit.value).String(), ByteSize(*hardLimit.value).String()
+		}
+		return fmt.Errorf("soft resource limit %s (%s) exceeds hard limit (%s)", limit.key, softText, hardText)
+	}
+
+	return nil
+}
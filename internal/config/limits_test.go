@@ -0,0 +1,124 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemon_EffectiveResourceLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		daemon   Daemon
+		validate func(*testing.T, *ResourceLimits, *ResourceLimits)
+	}{
+		{
+			name:   "no limits",
+			daemon: Daemon{},
+			validate: func(t *testing.T, soft, hard *ResourceLimits) {
+				assert.Nil(t, soft)
+				assert.Nil(t, hard)
+			},
+		},
+		{
+			name: "legacy block applies to both",
+			daemon: Daemon{
+				ResourceLimits: &ResourceLimits{NumberOfFiles: intPtr(256)},
+			},
+			validate: func(t *testing.T, soft, hard *ResourceLimits) {
+				require.NotNil(t, soft)
+				require.NotNil(t, hard)
+				assert.Equal(t, 256, *soft.NumberOfFiles)
+				assert.Equal(t, 256, *hard.NumberOfFiles)
+			},
+		},
+		{
+			name: "soft and hard override legacy block",
+			daemon: Daemon{
+				ResourceLimits:     &ResourceLimits{ResidentSetSize: sizePtr(128 << 20), Stack: sizePtr(8 << 20)},
+				SoftResourceLimits: &ResourceLimits{ResidentSetSize: sizePtr(256 << 20)},
+				HardResourceLimits: &ResourceLimits{ResidentSetSize: sizePtr(512 << 20)},
+			},
+			validate: func(t *testing.T, soft, hard *ResourceLimits) {
+				assert.Equal(t, ByteSize(256<<20), *soft.ResidentSetSize)
+				assert.Equal(t, ByteSize(512<<20), *hard.ResidentSetSize)
+				assert.Equal(t, ByteSize(8<<20), *soft.Stack)
+				assert.Equal(t, ByteSize(8<<20), *hard.Stack)
+			},
+		},
+		{
+			name: "hard only",
+			daemon: Daemon{
+				HardResourceLimits: &ResourceLimits{CPU: intPtr(60)},
+			},
+			validate: func(t *testing.T, soft, hard *ResourceLimits) {
+				assert.Nil(t, soft)
+				assert.Equal(t, 60, *hard.CPU)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			soft, hard := tt.daemon.EffectiveResourceLimits()
+			tt.validate(t, soft, hard)
+		})
+	}
+}
+
+func TestValidateResourceLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		daemon   Daemon
+		errorMsg string
+	}{
+		{
+			name: "soft below hard",
+			daemon: Daemon{
+				SoftResourceLimits: &ResourceLimits{NumberOfFiles: intPtr(256)},
+				HardResourceLimits: &ResourceLimits{NumberOfFiles: intPtr(1024)},
+			},
+		},
+		{
+			name: "soft equal to hard",
+			daemon: Daemon{
+				ResourceLimits: &ResourceLimits{ResidentSetSize: sizePtr(512 << 20)},
+			},
+		},
+		{
+			name: "soft above hard",
+			daemon: Daemon{
+				SoftResourceLimits: &ResourceLimits{NumberOfFiles: intPtr(2048)},
+				HardResourceLimits: &ResourceLimits{NumberOfFiles: intPtr(1024)},
+			},
+			errorMsg: "soft resource limit number_of_files (2048) exceeds hard limit (1024)",
+		},
+		{
+			name: "soft above legacy hard",
+			daemon: Daemon{
+				ResourceLimits:     &ResourceLimits{Stack: sizePtr(8 << 20)},
+				SoftResourceLimits: &ResourceLimits{Stack: sizePtr(16 << 20)},
+			},
+			errorMsg: "soft resource limit stack (16MiB) exceeds hard limit (8MiB)",
+		},
+		{
+			name: "negative limit",
+			daemon: Daemon{
+				HardResourceLimits: &ResourceLimits{CPU: intPtr(-1)},
+			},
+			errorMsg: "resource limit cpu must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResourceLimits(&tt.daemon)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.errorMsg)
+		})
+	}
+}
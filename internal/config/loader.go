@@ -4,9 +4,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
 )
 
 // Loader handles configuration loading
@@ -45,28 +51,101 @@ func (l *Loader) Load() (*Config, error) {
 			log.Warn().Msg("No config file found, using defaults")
 			return &Config{}, nil
 		}
-		return nil, fmt.Errorf("error reading config: %w", err)
+		return nil, fmt.Errorf("%w: error reading config: %w", errs.ErrInvalidConfig, err)
 	}
 
 	log.Info().Str("config", v.ConfigFileUsed()).Msg("Using config file")
 
-	// Unmarshal config
-	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	// Unmarshal config. YAML and JSON files are decoded directly rather
+	// than through viper, which lowercases map keys; launchd keys and
+	// environment variable names are case-sensitive. Other formats viper
+	// reads, such as TOML, still go through viper and keep that limitation.
+	var cfg *Config
+	var err error
+	if isYAMLFile(v.ConfigFileUsed()) {
+		cfg, err = decodeConfigFile(v.ConfigFileUsed())
+	} else {
+		cfg = &Config{}
+		err = v.Unmarshal(cfg, viper.DecodeHook(decodeHook()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: error unmarshaling config: %w", errs.ErrInvalidConfig, err)
 	}
 
 	// Validate config
-	if err := l.validateConfig(&cfg); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Version < SchemaVersion {
+		log.Warn().
+			Int("version", cfg.Version).
+			Msg("Daemon config uses an older schema version, run 'daemon-control config migrate' to upgrade it")
+	}
+
+	l.config = cfg
+	return cfg, nil
+}
+
+// isYAMLFile reports whether path is a YAML or JSON file. JSON is a subset
+// of YAML, so both are decoded by decodeConfigFile.
+func isYAMLFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// decodeConfigFile reads a YAML (or JSON) config file into a Config
+func decodeConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path comes from the user's configuration
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	raw, err := nodeValue(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       decodeHook(),
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decoder.Decode(raw); err != nil {
+		return nil, err
 	}
 
-	l.config = &cfg
 	return &cfg, nil
 }
 
+// Validate checks a configuration the way Load does, for configs built
+// without reading a file
+func Validate(cfg *Config) error {
+	if err := validateConfig(cfg); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidConfig, err)
+	}
+	return nil
+}
+
 // validateConfig validates the configuration
-func (l *Loader) validateConfig(cfg *Config) error {
+func validateConfig(cfg *Config) error {
+	if cfg.Version > SchemaVersion {
+		return fmt.Errorf("config version %d is newer than supported version %d", cfg.Version, SchemaVersion)
+	}
+
 	// Check for duplicate names
 	names := make(map[string]bool)
 	labels := make(map[string]bool)
@@ -116,6 +195,68 @@ func (l *Loader) validateConfig(cfg *Config) error {
 			}
 		}
 
+		// Validate resource limits
+		if err := validateResourceLimits(&daemon); err != nil {
+			return fmt.Errorf("daemon[%s]: %w", daemon.Name, err)
+		}
+
+		// Validate session types
+		for _, sessionType := range daemon.LimitLoadToSessionType {
+			if !validSessionTypes[sessionType] {
+				return fmt.Errorf("daemon[%s]: invalid limit_load_to_session_type: %s", daemon.Name, sessionType)
+			}
+		}
+
+		// Validate umask
+		if daemon.Umask != nil && (*daemon.Umask < 0 || *daemon.Umask > 0777) {
+			return fmt.Errorf("daemon[%s]: umask must be between 0 and 0777", daemon.Name)
+		}
+
+		// inetd-style jobs receive their connections through sockets
+		if daemon.InetdCompatibility != nil && len(daemon.Sockets) == 0 {
+			return fmt.Errorf("daemon[%s]: inetd_compatibility requires sockets", daemon.Name)
+		}
+
+		// Validate launch events
+		for subsystem, events := range daemon.LaunchEvents {
+			for event, descriptor := range events {
+				if err := validatePlistValue(descriptor); err != nil {
+					return fmt.Errorf("daemon[%s].launch_events[%s][%s]: %w", daemon.Name, subsystem, event, err)
+				}
+			}
+		}
+
+		// Validate raw plist passthrough keys
+		for key, value := range daemon.ExtraPlistKeys {
+			if field, ok := ModeledPlistKeys[key]; ok {
+				return fmt.Errorf("daemon[%s]: extra_plist_keys.%s collides with modeled field %s", daemon.Name, key, field)
+			}
+			if err := validatePlistValue(value); err != nil {
+				return fmt.Errorf("daemon[%s].extra_plist_keys[%s]: %w", daemon.Name, key, err)
+			}
+		}
+
+		// Validate tags, which are joined with commas in metrics labels
+		for _, tag := range daemon.Tags {
+			if tag == "" || strings.Contains(tag, ",") {
+				return fmt.Errorf("daemon[%s]: tags must be non-empty and must not contain commas: %q", daemon.Name, tag)
+			}
+		}
+
+		// Validate health check
+		if daemon.HealthCheck != nil {
+			if err := validateHealthCheck(daemon.HealthCheck); err != nil {
+				return fmt.Errorf("daemon[%s].health_check: %w", daemon.Name, err)
+			}
+		}
+
+		// Validate notifications
+		if daemon.Notify != nil {
+			if err := ValidateNotify(daemon.Notify); err != nil {
+				return fmt.Errorf("daemon[%s].notify: %w", daemon.Name, err)
+			}
+		}
+
 		// Validate calendar intervals
 		for j, interval := range daemon.StartCalendarInterval {
 			if err := validateCalendarInterval(interval); err != nil {
@@ -127,6 +268,104 @@ func (l *Loader) validateConfig(cfg *Config) error {
 	return nil
 }
 
+// nodeValue converts a YAML node to plain Go values. Unlike decoding into
+// interface{}, !!binary scalars become []byte so data values keep their type.
+func nodeValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return nodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return nodeValue(node.Alias)
+	case yaml.SequenceNode:
+		items := make([]interface{}, 0, len(node.Content))
+		for _, child := range node.Content {
+			item, err := nodeValue(child)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			value, err := nodeValue(valueNode)
+			if err != nil {
+				return nil, err
+			}
+
+			// Merge keys (<<: *anchor) copy the referenced mapping
+			if keyNode.Tag == "!!merge" {
+				if merged, ok := value.(map[string]interface{}); ok {
+					for k, v := range merged {
+						if _, exists := m[k]; !exists {
+							m[k] = v
+						}
+					}
+				}
+				continue
+			}
+
+			m[keyNode.Value] = value
+		}
+		return m, nil
+	case yaml.ScalarNode:
+		if node.Tag == "!!binary" {
+			var text string
+			if err := node.Decode(&text); err != nil {
+				return nil, err
+			}
+			return []byte(text), nil
+		}
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unsupported YAML node at line %d", node.Line)
+	}
+}
+
+// validSessionTypes lists the values accepted by LimitLoadToSessionType
+var validSessionTypes = map[string]bool{
+	"Aqua":        true,
+	"Background":  true,
+	"LoginWindow": true,
+	"StandardIO":  true,
+	"System":      true,
+}
+
+// validatePlistValue checks that a free-form value can be written to a plist
+func validatePlistValue(value interface{}) error {
+	switch v := value.(type) {
+	case string, bool, int, int64, uint64, float64, time.Time, []byte:
+		return nil
+	case map[string]interface{}:
+		for key, item := range v {
+			if err := validatePlistValue(item); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		return nil
+	case []interface{}:
+		for i, item := range v {
+			if err := validatePlistValue(item); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return nil
+	case nil:
+		return fmt.Errorf("null values are not supported")
+	default:
+		return fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
 // validateCalendarInterval validates a calendar interval
 func validateCalendarInterval(interval CalendarInterval) error {
 	if interval.Minute != nil && (*interval.Minute < 0 || *interval.Minute > 59) {
@@ -164,7 +403,7 @@ func (l *Loader) GetDaemon(name string) (*Daemon, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("daemon not found: %s", name)
+	return nil, fmt.Errorf("%w: %s", errs.ErrNotFound, name)
 }
 
 // GetAllDaemons returns all configured daemons
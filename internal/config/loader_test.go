@@ -4,9 +4,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
 )
 
 func TestNewLoader(t *testing.T) {
@@ -43,6 +46,28 @@ func TestLoader_Load(t *testing.T) {
 				assert.True(t, cfg.Daemons[0].RunAtLoad)
 			},
 		},
+		{
+			name:       "toml config",
+			configPath: "daemons.toml",
+			configData: `version = 1
+
+[[daemons]]
+name = "toml-daemon"
+label = "com.example.toml"
+program = "/usr/bin/toml"
+run_at_load = true
+
+[daemons.resource_limits]
+data = "512MiB"`,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				assert.Equal(t, "toml-daemon", cfg.Daemons[0].Name)
+				assert.Equal(t, "com.example.toml", cfg.Daemons[0].Label)
+				assert.True(t, cfg.Daemons[0].RunAtLoad)
+				require.NotNil(t, cfg.Daemons[0].ResourceLimits)
+				assert.Equal(t, ByteSize(512<<20), *cfg.Daemons[0].ResourceLimits.Data)
+			},
+		},
 		{
 			name:       "valid config with multiple daemons",
 			configPath: "daemons.yaml",
@@ -76,7 +101,10 @@ func TestLoader_Load(t *testing.T) {
 			wantError: false,
 			validateCfg: func(t *testing.T, cfg *Config) {
 				require.Len(t, cfg.Daemons, 1)
-				// Skip environment variable check as viper might not unmarshal it correctly
+				assert.Equal(t, map[string]string{
+					"PATH":     "/usr/local/bin:/usr/bin",
+					"NODE_ENV": "production",
+				}, cfg.Daemons[0].EnvironmentVariables)
 			},
 		},
 		{
@@ -115,6 +143,224 @@ func TestLoader_Load(t *testing.T) {
 				assert.True(t, *cfg.Daemons[0].KeepAlive.Crashed)
 			},
 		},
+		{
+			name:       "config with keep alive boolean",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: always-daemon
+    label: com.example.always
+    program: /usr/bin/always
+    keep_alive: true`,
+			wantError: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				require.NotNil(t, cfg.Daemons[0].KeepAlive)
+				require.NotNil(t, cfg.Daemons[0].KeepAlive.Always)
+				assert.True(t, *cfg.Daemons[0].KeepAlive.Always)
+			},
+		},
+		{
+			name:       "config with extended launchd keys",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: full-daemon
+    label: com.example.full
+    program: /usr/bin/full
+    disabled: true
+    limit_load_to_session_type: [Aqua]
+    limit_load_to_hosts: [build-host]
+    mach_services:
+      com.example.full.xpc: true
+      com.example.full.reset:
+        reset_at_close: true
+    launch_events:
+      com.apple.iokit.matching:
+        usb-device:
+          IOProviderClass: IOUSBDevice
+          idVendor: 1452
+    umask: 022
+    inetd_compatibility:
+      wait: true
+    sockets:
+      listener:
+        sock_service_name: "8080"
+    associated_bundle_identifiers: [com.example.app]`,
+			wantError: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				daemon := cfg.Daemons[0]
+				assert.True(t, daemon.Disabled)
+				assert.Equal(t, []string{"Aqua"}, daemon.LimitLoadToSessionType)
+				assert.Equal(t, []string{"build-host"}, daemon.LimitLoadToHosts)
+				assert.True(t, daemon.MachServices["com.example.full.xpc"].IsEmpty())
+				assert.True(t, *daemon.MachServices["com.example.full.reset"].ResetAtClose)
+				descriptor := daemon.LaunchEvents["com.apple.iokit.matching"]["usb-device"]
+				assert.Equal(t, "IOUSBDevice", descriptor["IOProviderClass"])
+				assert.Equal(t, 1452, descriptor["idVendor"])
+				assert.Equal(t, 0o022, *daemon.Umask)
+				assert.True(t, *daemon.InetdCompatibility.Wait)
+				assert.Equal(t, []string{"com.example.app"}, daemon.AssociatedBundleIdentifiers)
+			},
+		},
+		{
+			name:       "config with soft and hard limits",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: limited
+    label: com.example.limited
+    program: /usr/bin/limited
+    resource_limits:
+      number_of_files: 1024
+    soft_resource_limits:
+      resident_set_size: 256MiB
+    hard_resource_limits:
+      resident_set_size: 512MiB
+      stack: 8388608`,
+			wantError: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				soft, hard := cfg.Daemons[0].EffectiveResourceLimits()
+				assert.Equal(t, ByteSize(256<<20), *soft.ResidentSetSize)
+				assert.Equal(t, ByteSize(512<<20), *hard.ResidentSetSize)
+				assert.Equal(t, ByteSize(8<<20), *hard.Stack)
+				assert.Equal(t, 1024, *soft.NumberOfFiles)
+				assert.Equal(t, 1024, *hard.NumberOfFiles)
+			},
+		},
+		{
+			name:       "soft limit exceeds hard limit",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: limited
+    label: com.example.limited
+    program: /usr/bin/limited
+    soft_resource_limits:
+      resident_set_size: 1GiB
+    hard_resource_limits:
+      resident_set_size: 512MiB`,
+			wantError: true,
+			errorMsg:  "soft resource limit resident_set_size (1GiB) exceeds hard limit (512MiB)",
+		},
+		{
+			name:       "invalid size",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: limited
+    label: com.example.limited
+    program: /usr/bin/limited
+    resource_limits:
+      stack: 8 parsecs`,
+			wantError: true,
+			errorMsg:  "invalid size",
+		},
+		{
+			name:       "config with extra plist keys",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: extra
+    label: com.example.extra
+    program: /usr/bin/extra
+    extra_plist_keys:
+      MaterializeDatalessFiles: true
+      ProcessPriority: 5
+      Weight: 0.75
+      Since: 2024-01-02T03:04:05Z
+      Token: !!binary aGVsbG8=
+      Nested:
+        CamelCaseKey: value
+        List: [a, 1, false]`,
+			wantError: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				extra := cfg.Daemons[0].ExtraPlistKeys
+				assert.Equal(t, true, extra["MaterializeDatalessFiles"])
+				assert.Equal(t, 5, extra["ProcessPriority"])
+				assert.Equal(t, 0.75, extra["Weight"])
+				assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), extra["Since"])
+				assert.Equal(t, []byte("hello"), extra["Token"])
+				assert.Equal(t, map[string]interface{}{
+					"CamelCaseKey": "value",
+					"List":         []interface{}{"a", 1, false},
+				}, extra["Nested"])
+			},
+		},
+		{
+			name:       "config with yaml anchors",
+			configPath: "daemons.yaml",
+			configData: `defaults: &defaults
+  program: /usr/bin/shared
+  run_at_load: true
+daemons:
+  - <<: *defaults
+    name: anchored
+    label: com.example.anchored`,
+			wantError: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				assert.Equal(t, "/usr/bin/shared", cfg.Daemons[0].Program)
+				assert.True(t, cfg.Daemons[0].RunAtLoad)
+			},
+		},
+		{
+			name:       "extra plist key collides with modeled field",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: extra
+    label: com.example.extra
+    program: /usr/bin/extra
+    extra_plist_keys:
+      RunAtLoad: true`,
+			wantError: true,
+			errorMsg:  "extra_plist_keys.RunAtLoad collides with modeled field run_at_load",
+		},
+		{
+			name:       "invalid session type",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    limit_load_to_session_type: [Desktop]`,
+			wantError: true,
+			errorMsg:  "invalid limit_load_to_session_type",
+		},
+		{
+			name:       "umask out of range",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    umask: 01000`,
+			wantError: true,
+			errorMsg:  "umask must be between 0 and 0777",
+		},
+		{
+			name:       "inetd compatibility without sockets",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    inetd_compatibility:
+      wait: false`,
+			wantError: true,
+			errorMsg:  "inetd_compatibility requires sockets",
+		},
+		{
+			name:       "null launch event value",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    launch_events:
+      com.apple.notifyd.matching:
+        event:
+          Notification:`,
+			wantError: true,
+			errorMsg:  "null values are not supported",
+		},
 		{
 			name:       "invalid yaml",
 			configPath: "daemons.yaml",
@@ -211,6 +457,94 @@ func TestLoader_Load(t *testing.T) {
 			wantError: true,
 			errorMsg:  "minute must be between 0 and 59",
 		},
+		{
+			name:       "tags and health check",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    tags: [api, prod]
+    health_check:
+      http: http://127.0.0.1:8080/healthz
+      timeout: 2`,
+			wantError: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				assert.Equal(t, []string{"api", "prod"}, cfg.Daemons[0].Tags)
+				require.NotNil(t, cfg.Daemons[0].HealthCheck)
+				assert.Equal(t, "http://127.0.0.1:8080/healthz", cfg.Daemons[0].HealthCheck.HTTP)
+				assert.Equal(t, 2, cfg.Daemons[0].HealthCheck.Timeout)
+			},
+		},
+		{
+			name:       "tag with comma",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    tags: ["api,prod"]`,
+			wantError: true,
+			errorMsg:  "must not contain commas",
+		},
+		{
+			name:       "health check without a check",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    health_check:
+      timeout: 2`,
+			wantError: true,
+			errorMsg:  "exactly one of command, http or tcp is required",
+		},
+		{
+			name:       "notify block",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    notify:
+      webhook: https://hooks.example.com/daemons
+      desktop: true
+      events: [crash, flapping]
+      flap_restarts: 5`,
+			wantError: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.Daemons, 1)
+				n := cfg.Daemons[0].Notify
+				require.NotNil(t, n)
+				assert.Equal(t, "https://hooks.example.com/daemons", n.Webhook)
+				require.NotNil(t, n.Desktop)
+				assert.True(t, *n.Desktop)
+				assert.Equal(t, []string{"crash", "flapping"}, n.Events)
+				assert.Equal(t, 5, n.FlapRestarts)
+			},
+		},
+		{
+			name:       "notify unknown event",
+			configPath: "daemons.yaml",
+			configData: `daemons:
+  - name: test
+    label: com.example.test
+    program: /usr/bin/test
+    notify:
+      command: [/usr/local/bin/page-me]
+      events: [exit]`,
+			wantError: true,
+			errorMsg:  `daemon[test].notify: unknown event "exit"`,
+		},
+		{
+			name:       "newer schema version",
+			configPath: "daemons.yaml",
+			configData: `version: 99
+daemons: []`,
+			wantError: true,
+			errorMsg:  "config version 99 is newer than supported version",
+		},
 	}
 
 	for _, tt := range tests {
@@ -240,7 +574,7 @@ func TestLoader_Load(t *testing.T) {
 			cfg, err := loader.Load()
 
 			if tt.wantError {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, errs.ErrInvalidConfig)
 				if tt.errorMsg != "" {
 					assert.Contains(t, err.Error(), tt.errorMsg)
 				}
@@ -302,6 +636,7 @@ func TestLoader_GetDaemon(t *testing.T) {
 			if tt.wantError {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), "daemon not found")
+				assert.ErrorIs(t, err, errs.ErrNotFound)
 			} else {
 				assert.NoError(t, err)
 				assert.NotNil(t, daemon)
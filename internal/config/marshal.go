@@ -0,0 +1,253 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// keepAliveConditions is KeepAlive without custom marshalers
+type keepAliveConditions KeepAlive
+
+// machServiceOptions is MachService without custom marshalers
+type machServiceOptions MachService
+
+// MarshalYAML writes a plain boolean when Always is set
+func (k KeepAlive) MarshalYAML() (interface{}, error) {
+	if k.Always != nil {
+		return *k.Always, nil
+	}
+	return keepAliveConditions(k), nil
+}
+
+// UnmarshalYAML accepts either a boolean or a map of conditions
+func (k *KeepAlive) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var always bool
+		if err := value.Decode(&always); err != nil {
+			return err
+		}
+		*k = KeepAlive{Always: &always}
+		return nil
+	}
+
+	var conditions keepAliveConditions
+	if err := value.Decode(&conditions); err != nil {
+		return err
+	}
+	*k = KeepAlive(conditions)
+	return nil
+}
+
+// MarshalJSON writes a plain boolean when Always is set
+func (k KeepAlive) MarshalJSON() ([]byte, error) {
+	if k.Always != nil {
+		return json.Marshal(*k.Always)
+	}
+	return json.Marshal(keepAliveConditions(k))
+}
+
+// UnmarshalJSON accepts either a boolean or an object of conditions
+func (k *KeepAlive) UnmarshalJSON(data []byte) error {
+	var always bool
+	if err := json.Unmarshal(data, &always); err == nil {
+		*k = KeepAlive{Always: &always}
+		return nil
+	}
+
+	var conditions keepAliveConditions
+	if err := json.Unmarshal(data, &conditions); err != nil {
+		return err
+	}
+	*k = KeepAlive(conditions)
+	return nil
+}
+
+// IsEmpty reports whether the service has no options set
+func (m MachService) IsEmpty() bool {
+	return m.ResetAtClose == nil && m.HideUntilCheckIn == nil
+}
+
+// MarshalYAML writes a plain true when no options are set
+func (m MachService) MarshalYAML() (interface{}, error) {
+	if m.IsEmpty() {
+		return true, nil
+	}
+	return machServiceOptions(m), nil
+}
+
+// UnmarshalYAML accepts either a boolean or a map of options
+func (m *MachService) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var enabled bool
+		if err := value.Decode(&enabled); err != nil {
+			return err
+		}
+		*m = MachService{}
+		return nil
+	}
+
+	var options machServiceOptions
+	if err := value.Decode(&options); err != nil {
+		return err
+	}
+	*m = MachService(options)
+	return nil
+}
+
+// MarshalJSON writes a plain true when no options are set
+func (m MachService) MarshalJSON() ([]byte, error) {
+	if m.IsEmpty() {
+		return json.Marshal(true)
+	}
+	return json.Marshal(machServiceOptions(m))
+}
+
+// UnmarshalJSON accepts either a boolean or an object of options
+func (m *MachService) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*m = MachService{}
+		return nil
+	}
+
+	var options machServiceOptions
+	if err := json.Unmarshal(data, &options); err != nil {
+		return err
+	}
+	*m = MachService(options)
+	return nil
+}
+
+// decodeHook returns the mapstructure hooks used when decoding daemon
+// configuration, allowing boolean shorthands for KeepAlive and MachService
+// and human-readable sizes
+func decodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		boolShorthandHook,
+		byteSizeHook,
+	)
+}
+
+// byteSizeHook parses size strings such as "512MiB"
+func byteSizeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(ByteSize(0)) {
+		return data, nil
+	}
+	return ParseByteSize(data.(string))
+}
+
+// boolShorthandHook converts plain booleans into their struct forms
+func boolShorthandHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.Bool {
+		return data, nil
+	}
+
+	switch to {
+	case reflect.TypeOf(KeepAlive{}):
+		always := data.(bool)
+		return KeepAlive{Always: &always}, nil
+	case reflect.TypeOf(MachService{}):
+		return MachService{}, nil
+	}
+
+	return data, nil
+}
+
+// Marshal writes a configuration as YAML that Load reads back unchanged.
+// Free-form plist values keep their types: data is written as !!binary
+// and whole reals keep a decimal point so they are not read as integers.
+func Marshal(cfg *Config) ([]byte, error) {
+	out := Config{Version: cfg.Version, Daemons: make([]Daemon, len(cfg.Daemons))}
+	for i, daemon := range cfg.Daemons {
+		if daemon.ExtraPlistKeys != nil {
+			daemon.ExtraPlistKeys = yamlPlistValue(daemon.ExtraPlistKeys).(map[string]interface{})
+		}
+		if daemon.LaunchEvents != nil {
+			events := make(map[string]map[string]map[string]interface{}, len(daemon.LaunchEvents))
+			for subsystem, subsystemEvents := range daemon.LaunchEvents {
+				events[subsystem] = make(map[string]map[string]interface{}, len(subsystemEvents))
+				for event, descriptor := range subsystemEvents {
+					events[subsystem][event] = yamlPlistValue(descriptor).(map[string]interface{})
+				}
+			}
+			daemon.LaunchEvents = events
+		}
+		out.Daemons[i] = daemon
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(out); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlBinary is data written as a !!binary scalar
+type yamlBinary []byte
+
+// MarshalYAML writes the data base64-encoded with the !!binary tag
+func (b yamlBinary) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: base64.StdEncoding.EncodeToString(b)}, nil
+}
+
+// yamlFloat is a real that always reads back as a float
+type yamlFloat float64
+
+// MarshalYAML writes the value with a decimal point or exponent
+func (f yamlFloat) MarshalYAML() (interface{}, error) {
+	var text string
+	switch v := float64(f); {
+	case math.IsInf(v, 1):
+		text = ".inf"
+	case math.IsInf(v, -1):
+		text = "-.inf"
+	case math.IsNaN(v):
+		text = ".nan"
+	default:
+		text = strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: text}, nil
+}
+
+// yamlPlistValue copies a free-form value, wrapping data and reals so
+// they keep their types through YAML
+func yamlPlistValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return yamlBinary(v)
+	case float64:
+		return yamlFloat(v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = yamlPlistValue(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = yamlPlistValue(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	always := true
+	cfg := &Config{
+		Daemons: []Daemon{
+			{
+				Name:                 "passthrough",
+				Label:                "com.example.passthrough",
+				Program:              "/usr/bin/true",
+				EnvironmentVariables: map[string]string{"PATH": "/usr/bin"},
+				KeepAlive:            &KeepAlive{Always: &always},
+				ResourceLimits:       &ResourceLimits{Stack: sizePtr(8 << 20)},
+				LaunchEvents: map[string]map[string]map[string]interface{}{
+					"com.apple.iokit.matching": {"device": {"idVendor": 1452, "Scale": 1.0}},
+				},
+				ExtraPlistKeys: map[string]interface{}{
+					"Whole":    3.0,
+					"Fraction": 0.25,
+					"Huge":     1e21,
+					"Infinite": math.Inf(1),
+					"Blob":     []byte{0, 1, 0xff},
+					"Since":    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					"Count":    7,
+					"List":     []interface{}{1.0, "two", []byte("3")},
+				},
+			},
+		},
+	}
+
+	data, err := Marshal(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Whole: 3.0")
+	assert.Contains(t, string(data), "Blob: !!binary AAH/")
+	assert.Contains(t, string(data), "stack: 8MiB")
+	assert.Contains(t, string(data), "keep_alive: true")
+
+	// The input is not modified
+	assert.IsType(t, []byte{}, cfg.Daemons[0].ExtraPlistKeys["Blob"])
+
+	path := filepath.Join(t.TempDir(), "daemons.yaml")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	loaded, err := NewLoader(path).Load()
+	require.NoError(t, err)
+	require.Len(t, loaded.Daemons, 1)
+	assert.Equal(t, cfg.Daemons[0], loaded.Daemons[0])
+}
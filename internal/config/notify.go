@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Monitoring events a notify block can send
+const (
+	// EventCrash is a daemon exiting with a failure status
+	EventCrash = "crash"
+	// EventFlapping is a daemon restarting flap_restarts times within
+	// flap_window_minutes
+	EventFlapping = "flapping"
+	// EventHealthCheck is a running daemon failing its health check
+	EventHealthCheck = "health_check"
+)
+
+// NotifyEvents lists every event in the order they are documented
+var NotifyEvents = []string{EventCrash, EventFlapping, EventHealthCheck}
+
+// Flapping defaults for a notify block that does not set them
+const (
+	DefaultFlapRestarts = 3
+	DefaultFlapWindow   = 10 * time.Minute
+)
+
+// EffectiveNotify returns the daemon's notify settings: global applies to
+// every daemon and the fields the daemon's own notify block sets override
+// it. It returns nil when neither is set.
+func (d *Daemon) EffectiveNotify(global *Notify) *Notify {
+	if global == nil && d.Notify == nil {
+		return nil
+	}
+
+	var n Notify
+	if global != nil {
+		n = *global
+	}
+	if own := d.Notify; own != nil {
+		if own.Webhook != "" {
+			n.Webhook = own.Webhook
+		}
+		if len(own.Command) > 0 {
+			n.Command = own.Command
+		}
+		if own.Desktop != nil {
+			n.Desktop = own.Desktop
+		}
+		if own.Events != nil {
+			n.Events = own.Events
+		}
+		if own.FlapRestarts > 0 {
+			n.FlapRestarts = own.FlapRestarts
+		}
+		if own.FlapWindowMinutes > 0 {
+			n.FlapWindowMinutes = own.FlapWindowMinutes
+		}
+	}
+	return &n
+}
+
+// HasSinks reports whether events are sent anywhere
+func (n *Notify) HasSinks() bool {
+	return n != nil && (n.Webhook != "" || len(n.Command) > 0 || (n.Desktop != nil && *n.Desktop))
+}
+
+// Wants reports whether the event is sent. Without an events list every
+// event is.
+func (n *Notify) Wants(event string) bool {
+	return n.Events == nil || slices.Contains(n.Events, event)
+}
+
+// FlapThreshold returns how many restarts within how long count as
+// flapping
+func (n *Notify) FlapThreshold() (int, time.Duration) {
+	restarts, window := DefaultFlapRestarts, DefaultFlapWindow
+	if n.FlapRestarts > 0 {
+		restarts = n.FlapRestarts
+	}
+	if n.FlapWindowMinutes > 0 {
+		window = time.Duration(n.FlapWindowMinutes) * time.Minute
+	}
+	return restarts, window
+}
+
+// ValidateNotify checks a notify block from the daemon or core config
+func ValidateNotify(n *Notify) error {
+	if n.Webhook != "" {
+		u, err := url.Parse(n.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook must be an http or https URL: %q", n.Webhook)
+		}
+	}
+	if len(n.Command) > 0 && n.Command[0] == "" {
+		return fmt.Errorf("command must start with a program")
+	}
+	for _, event := range n.Events {
+		if !slices.Contains(NotifyEvents, event) {
+			return fmt.Errorf("unknown event %q, must be one of %v", event, NotifyEvents)
+		}
+	}
+	if n.FlapRestarts < 0 || n.FlapWindowMinutes < 0 {
+		return fmt.Errorf("flap_restarts and flap_window_minutes must not be negative")
+	}
+	if n.FlapRestarts == 1 {
+		return fmt.Errorf("flap_restarts must be at least 2, a single restart is not flapping")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateNotify(t *testing.T) {
+	tests := []struct {
+		name    string
+		notify  Notify
+		wantErr string
+	}{
+		{name: "empty", notify: Notify{}},
+		{name: "all sinks", notify: Notify{Webhook: "https://hooks.example.com/x", Command: []string{"/usr/local/bin/page"}, Desktop: boolPtr(true)}},
+		{name: "events", notify: Notify{Events: []string{EventCrash, EventHealthCheck}}},
+		{name: "flapping", notify: Notify{FlapRestarts: 5, FlapWindowMinutes: 30}},
+		{name: "webhook not a URL", notify: Notify{Webhook: "hooks.example.com"}, wantErr: "webhook must be an http or https URL"},
+		{name: "empty program", notify: Notify{Command: []string{""}}, wantErr: "command must start with a program"},
+		{name: "unknown event", notify: Notify{Events: []string{"exit"}}, wantErr: `unknown event "exit"`},
+		{name: "negative window", notify: Notify{FlapWindowMinutes: -1}, wantErr: "must not be negative"},
+		{name: "one restart", notify: Notify{FlapRestarts: 1}, wantErr: "flap_restarts must be at least 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNotify(&tt.notify)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDaemon_EffectiveNotify(t *testing.T) {
+	global := &Notify{Webhook: "https://hooks.example.com/all", Desktop: boolPtr(true), FlapRestarts: 4}
+
+	assert.Nil(t, (&Daemon{}).EffectiveNotify(nil))
+
+	n := (&Daemon{}).EffectiveNotify(global)
+	require.NotNil(t, n)
+	assert.Equal(t, *global, *n)
+	assert.NotSame(t, global, n)
+
+	d := &Daemon{Notify: &Notify{
+		Command:           []string{"/usr/local/bin/page"},
+		Desktop:           boolPtr(false),
+		Events:            []string{EventCrash},
+		FlapWindowMinutes: 5,
+	}}
+	n = d.EffectiveNotify(global)
+	assert.Equal(t, "https://hooks.example.com/all", n.Webhook)
+	assert.Equal(t, []string{"/usr/local/bin/page"}, n.Command)
+	assert.False(t, *n.Desktop)
+	assert.Equal(t, []string{EventCrash}, n.Events)
+	assert.Equal(t, 4, n.FlapRestarts)
+	assert.Equal(t, 5, n.FlapWindowMinutes)
+	assert.True(t, *global.Desktop, "global block is not modified")
+}
+
+func TestNotify_HasSinks(t *testing.T) {
+	assert.False(t, (*Notify)(nil).HasSinks())
+	assert.False(t, (&Notify{Events: []string{EventCrash}}).HasSinks())
+	assert.False(t, (&Notify{Desktop: boolPtr(false)}).HasSinks())
+	assert.True(t, (&Notify{Desktop: boolPtr(true)}).HasSinks())
+	assert.True(t, (&Notify{Webhook: "https://hooks.example.com/x"}).HasSinks())
+	assert.True(t, (&Notify{Command: []string{"/bin/echo"}}).HasSinks())
+}
+
+func TestNotify_Wants(t *testing.T) {
+	assert.True(t, (&Notify{}).Wants(EventFlapping))
+	n := &Notify{Events: []string{EventCrash}}
+	assert.True(t, n.Wants(EventCrash))
+	assert.False(t, n.Wants(EventFlapping))
+	assert.False(t, (&Notify{Events: []string{}}).Wants(EventCrash))
+}
+
+func TestNotify_FlapThreshold(t *testing.T) {
+	restarts, window := (&Notify{}).FlapThreshold()
+	assert.Equal(t, DefaultFlapRestarts, restarts)
+	assert.Equal(t, DefaultFlapWindow, window)
+
+	restarts, window = (&Notify{FlapRestarts: 5, FlapWindowMinutes: 2}).FlapThreshold()
+	assert.Equal(t, 5, restarts)
+	assert.Equal(t, 2*time.Minute, window)
+}
@@ -1,7 +1,12 @@
 package config
 
+// SchemaVersion is the daemon config schema version written by this build.
+// Files without a version key are version 0.
+const SchemaVersion = 1
+
 // Config represents the main configuration structure
 type Config struct {
+	Version int      `mapstructure:"version" yaml:"version,omitempty" json:"version,omitempty"`
 	Daemons []Daemon `mapstructure:"daemons" yaml:"daemons" json:"daemons"`
 }
 
@@ -13,7 +18,7 @@ type Daemon struct {
 	Description string `mapstructure:"description,omitempty" yaml:"description,omitempty" json:"description,omitempty"`
 
 	// Program Information
-	Program          string   `mapstructure:"program" yaml:"program" json:"program"`
+	Program          string   `mapstructure:"program" yaml:"program,omitempty" json:"program,omitempty"`
 	ProgramArguments []string `mapstructure:"program_arguments,omitempty" yaml:"program_arguments,omitempty" json:"program_arguments,omitempty"`
 	WorkingDirectory string   `mapstructure:"working_directory,omitempty" yaml:"working_directory,omitempty" json:"working_directory,omitempty"`
 
@@ -32,8 +37,11 @@ type Daemon struct {
 	KeepAlive        *KeepAlive `mapstructure:"keep_alive,omitempty" yaml:"keep_alive,omitempty" json:"keep_alive,omitempty"`
 	ThrottleInterval int        `mapstructure:"throttle_interval,omitempty" yaml:"throttle_interval,omitempty" json:"throttle_interval,omitempty"` // seconds
 
-	// Resource Limits
-	ResourceLimits *ResourceLimits `mapstructure:"resource_limits,omitempty" yaml:"resource_limits,omitempty" json:"resource_limits,omitempty"`
+	// Resource Limits. resource_limits applies to both the soft and hard
+	// limits; soft_resource_limits and hard_resource_limits override it.
+	ResourceLimits     *ResourceLimits `mapstructure:"resource_limits,omitempty" yaml:"resource_limits,omitempty" json:"resource_limits,omitempty"`
+	SoftResourceLimits *ResourceLimits `mapstructure:"soft_resource_limits,omitempty" yaml:"soft_resource_limits,omitempty" json:"soft_resource_limits,omitempty"`
+	HardResourceLimits *ResourceLimits `mapstructure:"hard_resource_limits,omitempty" yaml:"hard_resource_limits,omitempty" json:"hard_resource_limits,omitempty"`
 
 	// Process Settings
 	ProcessType   string `mapstructure:"process_type,omitempty" yaml:"process_type,omitempty" json:"process_type,omitempty"` // Background, Standard, Adaptive, Interactive
@@ -58,10 +66,99 @@ type Daemon struct {
 	EnableTransactions  bool `mapstructure:"enable_transactions,omitempty" yaml:"enable_transactions,omitempty" json:"enable_transactions,omitempty"`
 	EnablePressuredExit bool `mapstructure:"enable_pressured_exit,omitempty" yaml:"enable_pressured_exit,omitempty" json:"enable_pressured_exit,omitempty"`
 	ExitTimeOut         int  `mapstructure:"exit_timeout,omitempty" yaml:"exit_timeout,omitempty" json:"exit_timeout,omitempty"` // seconds
+
+	// Launch Constraints
+	Disabled               bool     `mapstructure:"disabled,omitempty" yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	LimitLoadToSessionType []string `mapstructure:"limit_load_to_session_type,omitempty" yaml:"limit_load_to_session_type,omitempty" json:"limit_load_to_session_type,omitempty"` // Aqua, Background, LoginWindow, StandardIO, System
+	LimitLoadToHosts       []string `mapstructure:"limit_load_to_hosts,omitempty" yaml:"limit_load_to_hosts,omitempty" json:"limit_load_to_hosts,omitempty"`
+
+	// IPC and Events
+	MachServices map[string]MachService                       `mapstructure:"mach_services,omitempty" yaml:"mach_services,omitempty" json:"mach_services,omitempty"`
+	LaunchEvents map[string]map[string]map[string]interface{} `mapstructure:"launch_events,omitempty" yaml:"launch_events,omitempty" json:"launch_events,omitempty"` // subsystem -> event name -> descriptor
+	StartOnMount bool                                         `mapstructure:"start_on_mount,omitempty" yaml:"start_on_mount,omitempty" json:"start_on_mount,omitempty"`
+
+	// inetd Compatibility
+	InetdCompatibility *InetdCompatibility `mapstructure:"inetd_compatibility,omitempty" yaml:"inetd_compatibility,omitempty" json:"inetd_compatibility,omitempty"`
+
+	// Process Behavior
+	Umask                   *int `mapstructure:"umask,omitempty" yaml:"umask,omitempty" json:"umask,omitempty"` // e.g. 0o022
+	AbandonProcessGroup     bool `mapstructure:"abandon_process_group,omitempty" yaml:"abandon_process_group,omitempty" json:"abandon_process_group,omitempty"`
+	LowPriorityIO           bool `mapstructure:"low_priority_io,omitempty" yaml:"low_priority_io,omitempty" json:"low_priority_io,omitempty"`
+	LowPriorityBackgroundIO bool `mapstructure:"low_priority_background_io,omitempty" yaml:"low_priority_background_io,omitempty" json:"low_priority_background_io,omitempty"`
+	SessionCreate           bool `mapstructure:"session_create,omitempty" yaml:"session_create,omitempty" json:"session_create,omitempty"`
+
+	// Debugging
+	Debug           bool `mapstructure:"debug,omitempty" yaml:"debug,omitempty" json:"debug,omitempty"`
+	WaitForDebugger bool `mapstructure:"wait_for_debugger,omitempty" yaml:"wait_for_debugger,omitempty" json:"wait_for_debugger,omitempty"`
+
+	// Bundle Association
+	AssociatedBundleIdentifiers []string `mapstructure:"associated_bundle_identifiers,omitempty" yaml:"associated_bundle_identifiers,omitempty" json:"associated_bundle_identifiers,omitempty"`
+
+	// Raw plist keys the schema does not model, written as-is
+	ExtraPlistKeys map[string]interface{} `mapstructure:"extra_plist_keys,omitempty" yaml:"extra_plist_keys,omitempty" json:"extra_plist_keys,omitempty"`
+
+	// Lint rule IDs to suppress for this daemon
+	LintIgnore []string `mapstructure:"lint_ignore,omitempty" yaml:"lint_ignore,omitempty" json:"lint_ignore,omitempty"`
+
+	// Monitoring. Tags label the daemon's metrics and notifications; none
+	// of these are written to the plist.
+	Tags        []string     `mapstructure:"tags,omitempty" yaml:"tags,omitempty" json:"tags,omitempty"`
+	HealthCheck *HealthCheck `mapstructure:"health_check,omitempty" yaml:"health_check,omitempty" json:"health_check,omitempty"`
+	Notify      *Notify      `mapstructure:"notify,omitempty" yaml:"notify,omitempty" json:"notify,omitempty"`
 }
 
-// KeepAlive represents keep-alive settings
+// ModeledPlistKeys maps each top-level launchd.plist key the schema models
+// to the config key that sets it
+var ModeledPlistKeys = map[string]string{
+	"Label":                       "label",
+	"Program":                     "program",
+	"ProgramArguments":            "program_arguments",
+	"WorkingDirectory":            "working_directory",
+	"EnvironmentVariables":        "environment_variables",
+	"StandardOutPath":             "standard_out_path",
+	"StandardErrorPath":           "standard_error_path",
+	"RunAtLoad":                   "run_at_load",
+	"StartInterval":               "start_interval",
+	"KeepAlive":                   "keep_alive",
+	"ThrottleInterval":            "throttle_interval",
+	"SoftResourceLimits":          "soft_resource_limits",
+	"HardResourceLimits":          "hard_resource_limits",
+	"ProcessType":                 "process_type",
+	"Nice":                        "nice",
+	"InitGroups":                  "init_groups",
+	"UserName":                    "user_name",
+	"GroupName":                   "group_name",
+	"RootDirectory":               "root_directory",
+	"Sockets":                     "sockets",
+	"StartCalendarInterval":       "start_calendar_interval",
+	"WatchPaths":                  "watch_paths",
+	"QueueDirectories":            "queue_paths",
+	"EnableGlobbing":              "enable_globbing",
+	"EnableTransactions":          "enable_transactions",
+	"EnablePressuredExit":         "enable_pressured_exit",
+	"ExitTimeOut":                 "exit_timeout",
+	"Disabled":                    "disabled",
+	"LimitLoadToSessionType":      "limit_load_to_session_type",
+	"LimitLoadToHosts":            "limit_load_to_hosts",
+	"MachServices":                "mach_services",
+	"LaunchEvents":                "launch_events",
+	"StartOnMount":                "start_on_mount",
+	"inetdCompatibility":          "inetd_compatibility",
+	"Umask":                       "umask",
+	"AbandonProcessGroup":         "abandon_process_group",
+	"LowPriorityIO":               "low_priority_io",
+	"LowPriorityBackgroundIO":     "low_priority_background_io",
+	"SessionCreate":               "session_create",
+	"Debug":                       "debug",
+	"WaitForDebugger":             "wait_for_debugger",
+	"AssociatedBundleIdentifiers": "associated_bundle_identifiers",
+}
+
+// KeepAlive represents keep-alive settings. It is either a plain boolean
+// (Always) or a set of conditions.
 type KeepAlive struct {
+	Always *bool `mapstructure:"-" yaml:"-" json:"-"`
+
 	SuccessfulExit     *bool           `mapstructure:"successful_exit,omitempty" yaml:"successful_exit,omitempty" json:"successful_exit,omitempty"`
 	NetworkState       *bool           `mapstructure:"network_state,omitempty" yaml:"network_state,omitempty" json:"network_state,omitempty"`
 	PathState          map[string]bool `mapstructure:"path_state,omitempty" yaml:"path_state,omitempty" json:"path_state,omitempty"`
@@ -70,17 +167,18 @@ type KeepAlive struct {
 	AfterInitialDemand *bool           `mapstructure:"after_initial_demand,omitempty" yaml:"after_initial_demand,omitempty" json:"after_initial_demand,omitempty"`
 }
 
-// ResourceLimits represents resource limitations
+// ResourceLimits represents resource limitations. Size limits accept
+// human-readable values such as "512MiB".
 type ResourceLimits struct {
-	CPU               *int `mapstructure:"cpu,omitempty" yaml:"cpu,omitempty" json:"cpu,omitempty"`
-	FileSize          *int `mapstructure:"file_size,omitempty" yaml:"file_size,omitempty" json:"file_size,omitempty"`
-	NumberOfFiles     *int `mapstructure:"number_of_files,omitempty" yaml:"number_of_files,omitempty" json:"number_of_files,omitempty"`
-	Core              *int `mapstructure:"core,omitempty" yaml:"core,omitempty" json:"core,omitempty"`
-	Data              *int `mapstructure:"data,omitempty" yaml:"data,omitempty" json:"data,omitempty"`
-	MemoryLock        *int `mapstructure:"memory_lock,omitempty" yaml:"memory_lock,omitempty" json:"memory_lock,omitempty"`
-	NumberOfProcesses *int `mapstructure:"number_of_processes,omitempty" yaml:"number_of_processes,omitempty" json:"number_of_processes,omitempty"`
-	ResidentSetSize   *int `mapstructure:"resident_set_size,omitempty" yaml:"resident_set_size,omitempty" json:"resident_set_size,omitempty"`
-	Stack             *int `mapstructure:"stack,omitempty" yaml:"stack,omitempty" json:"stack,omitempty"`
+	CPU               *int      `mapstructure:"cpu,omitempty" yaml:"cpu,omitempty" json:"cpu,omitempty"` // seconds
+	FileSize          *ByteSize `mapstructure:"file_size,omitempty" yaml:"file_size,omitempty" json:"file_size,omitempty"`
+	NumberOfFiles     *int      `mapstructure:"number_of_files,omitempty" yaml:"number_of_files,omitempty" json:"number_of_files,omitempty"`
+	Core              *ByteSize `mapstructure:"core,omitempty" yaml:"core,omitempty" json:"core,omitempty"`
+	Data              *ByteSize `mapstructure:"data,omitempty" yaml:"data,omitempty" json:"data,omitempty"`
+	MemoryLock        *ByteSize `mapstructure:"memory_lock,omitempty" yaml:"memory_lock,omitempty" json:"memory_lock,omitempty"`
+	NumberOfProcesses *int      `mapstructure:"number_of_processes,omitempty" yaml:"number_of_processes,omitempty" json:"number_of_processes,omitempty"`
+	ResidentSetSize   *ByteSize `mapstructure:"resident_set_size,omitempty" yaml:"resident_set_size,omitempty" json:"resident_set_size,omitempty"`
+	Stack             *ByteSize `mapstructure:"stack,omitempty" yaml:"stack,omitempty" json:"stack,omitempty"`
 }
 
 // Socket represents socket activation settings
@@ -105,3 +203,36 @@ type CalendarInterval struct {
 	Weekday *int `mapstructure:"weekday,omitempty" yaml:"weekday,omitempty" json:"weekday,omitempty"` // 0-7 (0 and 7 are Sunday)
 	Month   *int `mapstructure:"month,omitempty" yaml:"month,omitempty" json:"month,omitempty"`
 }
+
+// MachService represents a Mach service registration. An empty MachService
+// is written as a plain true value.
+type MachService struct {
+	ResetAtClose     *bool `mapstructure:"reset_at_close,omitempty" yaml:"reset_at_close,omitempty" json:"reset_at_close,omitempty"`
+	HideUntilCheckIn *bool `mapstructure:"hide_until_check_in,omitempty" yaml:"hide_until_check_in,omitempty" json:"hide_until_check_in,omitempty"`
+}
+
+// InetdCompatibility represents inetd-style launching settings
+type InetdCompatibility struct {
+	Wait *bool `mapstructure:"wait,omitempty" yaml:"wait,omitempty" json:"wait,omitempty"`
+}
+
+// HealthCheck checks that a running daemon is working. Exactly one of
+// Command, HTTP or TCP is set.
+type HealthCheck struct {
+	Command []string `mapstructure:"command,omitempty" yaml:"command,omitempty" json:"command,omitempty"` // program and arguments, healthy when it exits 0
+	HTTP    string   `mapstructure:"http,omitempty" yaml:"http,omitempty" json:"http,omitempty"`          // URL that answers GET with a 2xx status
+	TCP     string   `mapstructure:"tcp,omitempty" yaml:"tcp,omitempty" json:"tcp,omitempty"`             // host:port that accepts connections
+	Timeout int      `mapstructure:"timeout,omitempty" yaml:"timeout,omitempty" json:"timeout,omitempty"` // seconds, default 5
+}
+
+// Notify says where to send a daemon's monitoring events. A daemon's
+// notify block overrides the fields it sets in the core config's. The desc
+// tags describe the fields as core config keys.
+type Notify struct {
+	Webhook           string   `mapstructure:"webhook,omitempty" yaml:"webhook,omitempty" json:"webhook,omitempty" desc:"URL each event is POSTed to as JSON"`
+	Command           []string `mapstructure:"command,omitempty" yaml:"command,omitempty" json:"command,omitempty" desc:"Program and arguments run for each event"`
+	Desktop           *bool    `mapstructure:"desktop,omitempty" yaml:"desktop,omitempty" json:"desktop,omitempty" desc:"Show events as macOS notifications"`
+	Events            []string `mapstructure:"events,omitempty" yaml:"events,omitempty" json:"events,omitempty" desc:"Events to send: crash, flapping, health_check (default all)"`
+	FlapRestarts      int      `mapstructure:"flap_restarts,omitempty" yaml:"flap_restarts,omitempty" json:"flap_restarts,omitempty" desc:"Restarts within flap_window_minutes that count as flapping (default 3)"`
+	FlapWindowMinutes int      `mapstructure:"flap_window_minutes,omitempty" yaml:"flap_window_minutes,omitempty" json:"flap_window_minutes,omitempty" desc:"Minutes flapping restarts are counted over (default 10)"`
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,14 +48,14 @@ func TestDaemonYAMLMarshalUnmarshal(t *testing.T) {
 				ThrottleInterval: 10,
 				ResourceLimits: &ResourceLimits{
 					CPU:               intPtr(80),
-					FileSize:          intPtr(1048576),
+					FileSize:          sizePtr(1048576),
 					NumberOfFiles:     intPtr(256),
-					Core:              intPtr(0),
-					Data:              intPtr(268435456),
-					MemoryLock:        intPtr(0),
+					Core:              sizePtr(0),
+					Data:              sizePtr(268435456),
+					MemoryLock:        sizePtr(0),
 					NumberOfProcesses: intPtr(64),
-					ResidentSetSize:   intPtr(536870912),
-					Stack:             intPtr(8388608),
+					ResidentSetSize:   sizePtr(536870912),
+					Stack:             sizePtr(8388608),
 				},
 				ProcessType:   "Background",
 				Nice:          intPtr(10),
@@ -84,6 +85,30 @@ func TestDaemonYAMLMarshalUnmarshal(t *testing.T) {
 				EnableTransactions:  true,
 				EnablePressuredExit: false,
 				ExitTimeOut:         30,
+				Disabled:            true,
+				LimitLoadToSessionType: []string{
+					"Aqua",
+					"Background",
+				},
+				LimitLoadToHosts: []string{"build-host"},
+				MachServices: map[string]MachService{
+					"com.example.full.xpc": {ResetAtClose: boolPtr(true)},
+				},
+				LaunchEvents: map[string]map[string]map[string]interface{}{
+					"com.apple.notifyd.matching": {
+						"network-change": {"Notification": "com.apple.system.config.network_change"},
+					},
+				},
+				StartOnMount:                true,
+				InetdCompatibility:          &InetdCompatibility{Wait: boolPtr(false)},
+				Umask:                       intPtr(0o022),
+				AbandonProcessGroup:         true,
+				LowPriorityIO:               true,
+				LowPriorityBackgroundIO:     true,
+				SessionCreate:               true,
+				Debug:                       true,
+				WaitForDebugger:             true,
+				AssociatedBundleIdentifiers: []string{"com.example.app"},
 			},
 		},
 	}
@@ -169,17 +194,90 @@ func TestKeepAliveYAMLMarshalUnmarshal(t *testing.T) {
 	assert.Equal(t, keepAlive, unmarshaled)
 }
 
+func TestKeepAliveBooleanMarshalUnmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepAlive KeepAlive
+		wantYAML  string
+		wantJSON  string
+	}{
+		{
+			name:      "always true",
+			keepAlive: KeepAlive{Always: boolPtr(true)},
+			wantYAML:  "true\n",
+			wantJSON:  "true",
+		},
+		{
+			name:      "always false",
+			keepAlive: KeepAlive{Always: boolPtr(false)},
+			wantYAML:  "false\n",
+			wantJSON:  "false",
+		},
+		{
+			name:      "conditions",
+			keepAlive: KeepAlive{Crashed: boolPtr(true)},
+			wantYAML:  "crashed: true\n",
+			wantJSON:  `{"crashed":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := yaml.Marshal(tt.keepAlive)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantYAML, string(data))
+
+			var fromYAML KeepAlive
+			assert.NoError(t, yaml.Unmarshal(data, &fromYAML))
+			assert.Equal(t, tt.keepAlive, fromYAML)
+
+			data, err = json.Marshal(tt.keepAlive)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantJSON, string(data))
+
+			var fromJSON KeepAlive
+			assert.NoError(t, json.Unmarshal(data, &fromJSON))
+			assert.Equal(t, tt.keepAlive, fromJSON)
+		})
+	}
+}
+
+func TestMachServiceYAMLMarshalUnmarshal(t *testing.T) {
+	services := map[string]MachService{
+		"com.example.plain":   {},
+		"com.example.options": {ResetAtClose: boolPtr(true), HideUntilCheckIn: boolPtr(false)},
+	}
+
+	data, err := yaml.Marshal(services)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "com.example.plain: true")
+
+	var unmarshaled map[string]MachService
+	err = yaml.Unmarshal(data, &unmarshaled)
+	assert.NoError(t, err)
+	assert.Equal(t, services, unmarshaled)
+
+	data, err = json.Marshal(services)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"com.example.plain":true`)
+
+	unmarshaled = nil
+	err = json.Unmarshal(data, &unmarshaled)
+	assert.NoError(t, err)
+	assert.Equal(t, services, unmarshaled)
+}
+
 func TestResourceLimitsYAMLMarshalUnmarshal(t *testing.T) {
 	limits := ResourceLimits{
 		CPU:               intPtr(75),
-		FileSize:          intPtr(524288),
+		FileSize:          sizePtr(524288),
 		NumberOfFiles:     intPtr(1024),
-		Core:              intPtr(0),
-		Data:              intPtr(134217728),
-		MemoryLock:        intPtr(65536),
+		Core:              sizePtr(0),
+		Data:              sizePtr(134217728),
+		MemoryLock:        sizePtr(65536),
 		NumberOfProcesses: intPtr(32),
-		ResidentSetSize:   intPtr(268435456),
-		Stack:             intPtr(4194304),
+		ResidentSetSize:   sizePtr(268435456),
+		Stack:             sizePtr(4194304),
 	}
 
 	// Marshal to YAML
@@ -285,3 +383,9 @@ func TestCalendarIntervalYAMLMarshalUnmarshal(t *testing.T) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+// Helper function to create size pointers
+func sizePtr(n int64) *ByteSize {
+	size := ByteSize(n)
+	return &size
+}
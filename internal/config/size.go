@@ -0,0 +1,147 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a size in bytes that can be written as a plain integer or
+// a human-readable string such as "512MiB". Binary units (KiB, MiB, GiB,
+// TiB and the short forms K, M, G, T) are powers of 1024; decimal units
+// (KB, MB, GB, TB) are powers of 1000.
+type ByteSize int64
+
+// sizeUnits maps unit suffixes to their multipliers
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KIB": 1 << 10,
+	"KB":  1000,
+	"M":   1 << 20,
+	"MIB": 1 << 20,
+	"MB":  1000 * 1000,
+	"G":   1 << 30,
+	"GIB": 1 << 30,
+	"GB":  1000 * 1000 * 1000,
+	"T":   1 << 40,
+	"TIB": 1 << 40,
+	"TB":  1000 * 1000 * 1000 * 1000,
+}
+
+// binaryUnits lists units used when formatting, largest first
+var binaryUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+}
+
+// ParseByteSize parses a size such as "4096", "64KiB", "1.5GiB" or "10MB"
+func ParseByteSize(s string) (ByteSize, error) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	// Split numeric prefix from unit suffix
+	i := 0
+	for i < len(trimmed) && (trimmed[i] >= '0' && trimmed[i] <= '9' || trimmed[i] == '.') {
+		i++
+	}
+	number, unit := trimmed[:i], strings.ToUpper(strings.TrimSpace(trimmed[i:]))
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	if !strings.Contains(number, ".") {
+		value, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size %q: %w", s, err)
+		}
+		if value > math.MaxInt64/multiplier {
+			return 0, fmt.Errorf("size %q overflows", s)
+		}
+		return ByteSize(value * multiplier), nil
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	bytes := value * float64(multiplier)
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q overflows", s)
+	}
+	if bytes != math.Trunc(bytes) {
+		return 0, fmt.Errorf("size %q is not a whole number of bytes", s)
+	}
+	return ByteSize(bytes), nil
+}
+
+// String formats the size with the largest binary unit that divides it exactly
+func (b ByteSize) String() string {
+	for _, unit := range binaryUnits {
+		if b != 0 && int64(b)%unit.size == 0 {
+			return fmt.Sprintf("%d%s", int64(b)/unit.size, unit.suffix)
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
+}
+
+// MarshalYAML writes the size in its human-readable form when exact
+func (b ByteSize) MarshalYAML() (interface{}, error) {
+	if s := b.String(); s != strconv.FormatInt(int64(b), 10) {
+		return s, nil
+	}
+	return int64(b), nil
+}
+
+// UnmarshalYAML accepts either an integer or a size string
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	var raw string
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	size, err := ParseByteSize(raw)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// MarshalJSON writes the size as a number of bytes
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(b))
+}
+
+// UnmarshalJSON accepts either a number or a size string
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("size must be a number or string: %w", err)
+	}
+	size, err := ParseByteSize(raw)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      ByteSize
+		wantError bool
+	}{
+		{name: "plain bytes", input: "4096", want: 4096},
+		{name: "bytes suffix", input: "512B", want: 512},
+		{name: "kibibytes", input: "64KiB", want: 64 << 10},
+		{name: "short binary unit", input: "8M", want: 8 << 20},
+		{name: "mebibytes", input: "512MiB", want: 512 << 20},
+		{name: "gibibytes lowercase", input: "2gib", want: 2 << 30},
+		{name: "tebibytes", input: "1TiB", want: 1 << 40},
+		{name: "decimal megabytes", input: "10MB", want: 10 * 1000 * 1000},
+		{name: "fractional", input: "1.5GiB", want: 3 << 29},
+		{name: "space before unit", input: "256 MiB", want: 256 << 20},
+		{name: "zero", input: "0", want: 0},
+		{name: "empty", input: "", wantError: true},
+		{name: "unknown unit", input: "5PB", wantError: true},
+		{name: "no number", input: "MiB", wantError: true},
+		{name: "negative", input: "-1", wantError: true},
+		{name: "fractional bytes", input: "1.5B", wantError: true},
+		{name: "overflow", input: "9999999TiB", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestByteSize_String(t *testing.T) {
+	assert.Equal(t, "0", ByteSize(0).String())
+	assert.Equal(t, "1000", ByteSize(1000).String())
+	assert.Equal(t, "1KiB", ByteSize(1024).String())
+	assert.Equal(t, "1536KiB", ByteSize(1536<<10).String())
+	assert.Equal(t, "512MiB", ByteSize(512<<20).String())
+	assert.Equal(t, "2GiB", ByteSize(2<<30).String())
+}
+
+func TestByteSizeYAMLMarshalUnmarshal(t *testing.T) {
+	for _, size := range []ByteSize{0, 1000, 512 << 20, 3 << 29} {
+		data, err := yaml.Marshal(size)
+		assert.NoError(t, err)
+
+		var unmarshaled ByteSize
+		assert.NoError(t, yaml.Unmarshal(data, &unmarshaled))
+		assert.Equal(t, size, unmarshaled)
+	}
+
+	data, err := yaml.Marshal(ByteSize(512 << 20))
+	assert.NoError(t, err)
+	assert.Equal(t, "512MiB\n", string(data))
+}
+
+func TestByteSizeJSONMarshalUnmarshal(t *testing.T) {
+	data, err := json.Marshal(ByteSize(512 << 20))
+	assert.NoError(t, err)
+	assert.Equal(t, "536870912", string(data))
+
+	var fromNumber ByteSize
+	assert.NoError(t, json.Unmarshal(data, &fromNumber))
+	assert.Equal(t, ByteSize(512<<20), fromNumber)
+
+	var fromString ByteSize
+	assert.NoError(t, json.Unmarshal([]byte(`"512MiB"`), &fromString))
+	assert.Equal(t, ByteSize(512<<20), fromString)
+
+	var invalid ByteSize
+	assert.Error(t, json.Unmarshal([]byte(`"lots"`), &invalid))
+}
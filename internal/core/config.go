@@ -1,48 +1,79 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/utils"
 )
 
-// CoreConfig represents the core daemon-control configuration
+// CoreConfig represents the core daemon-control configuration. The desc
+// and enum tags describe each key for config list and config set.
 type CoreConfig struct {
+	// Schema version, maintained by config migrate
+	Version int `mapstructure:"version" yaml:"version" json:"version"`
+
 	// Daemon configuration file path
-	DaemonConfigPath string `mapstructure:"daemon_config_path" yaml:"daemon_config_path" json:"daemon_config_path"`
+	DaemonConfigPath string `mapstructure:"daemon_config_path" yaml:"daemon_config_path" json:"daemon_config_path" desc:"Path to the daemons YAML file"`
 
 	// Default paths
-	DaemonsDir string `mapstructure:"daemons_dir" yaml:"daemons_dir" json:"daemons_dir"`
-	OutputDir  string `mapstructure:"output_dir" yaml:"output_dir" json:"output_dir"`
-	LogsDir    string `mapstructure:"logs_dir" yaml:"logs_dir" json:"logs_dir"`
+	DaemonsDir string `mapstructure:"daemons_dir" yaml:"daemons_dir" json:"daemons_dir" desc:"Directory holding the plist files to install"`
+	OutputDir  string `mapstructure:"output_dir" yaml:"output_dir" json:"output_dir" desc:"Directory generate writes plists to"`
+	LogsDir    string `mapstructure:"logs_dir" yaml:"logs_dir" json:"logs_dir" desc:"Directory for daemon log files"`
 
 	// Behavior settings
-	AutoGeneratePlists bool `mapstructure:"auto_generate_plists" yaml:"auto_generate_plists" json:"auto_generate_plists"`
-	BackupOnGenerate   bool `mapstructure:"backup_on_generate" yaml:"backup_on_generate" json:"backup_on_generate"`
-	ValidatePlists     bool `mapstructure:"validate_plists" yaml:"validate_plists" json:"validate_plists"`
+	AutoGeneratePlists bool `mapstructure:"auto_generate_plists" yaml:"auto_generate_plists" json:"auto_generate_plists" desc:"Copy generated plists into the daemons directory"`
+	BackupOnGenerate   bool `mapstructure:"backup_on_generate" yaml:"backup_on_generate" json:"backup_on_generate" desc:"Record generated plists in the daemon history"`
+	ValidatePlists     bool `mapstructure:"validate_plists" yaml:"validate_plists" json:"validate_plists" desc:"Validate plists before writing them"`
+
+	// History retention
+	HistoryMaxRevisions int `mapstructure:"history_max_revisions" yaml:"history_max_revisions" json:"history_max_revisions" desc:"Plist revisions kept per daemon, 0 for no limit"`
+	HistoryMaxAgeDays   int `mapstructure:"history_max_age_days" yaml:"history_max_age_days" json:"history_max_age_days" desc:"Days plist revisions are kept, 0 for no limit"`
+
+	// Monitoring
+	MetricsIntervalSeconds int `mapstructure:"metrics_interval_seconds" yaml:"metrics_interval_seconds" json:"metrics_interval_seconds" desc:"Seconds between daemon metrics collections"`
+	// Notifications for every daemon. Each daemon's notify block overrides
+	// the fields it sets.
+	Notify *config.Notify `mapstructure:"notify" yaml:"notify,omitempty" json:"notify,omitempty" desc:"Where monitor sends events for every daemon"`
 
 	// Logging settings
-	LogLevel  string `mapstructure:"log_level" yaml:"log_level" json:"log_level"`
-	LogFormat string `mapstructure:"log_format" yaml:"log_format" json:"log_format"` // json or console
+	LogLevel  string `mapstructure:"log_level" yaml:"log_level" json:"log_level" desc:"Logging level" enum:"debug,info,warn,error"`
+	LogFormat string `mapstructure:"log_format" yaml:"log_format" json:"log_format" desc:"Log output format" enum:"console,json"`
 
 	// LaunchAgent settings
-	LaunchAgentsDir string `mapstructure:"launch_agents_dir" yaml:"launch_agents_dir" json:"launch_agents_dir"`
+	LaunchAgentsDir string `mapstructure:"launch_agents_dir" yaml:"launch_agents_dir" json:"launch_agents_dir" desc:"Directory daemons are installed into"`
 
 	// Advanced settings
-	UseSystemLaunchd bool              `mapstructure:"use_system_launchd" yaml:"use_system_launchd" json:"use_system_launchd"`
-	CustomEnvVars    map[string]string `mapstructure:"custom_env_vars" yaml:"custom_env_vars" json:"custom_env_vars"`
+	UseSystemLaunchd bool              `mapstructure:"use_system_launchd" yaml:"use_system_launchd" json:"use_system_launchd" desc:"Manage system-wide daemons instead of per-user agents"`
+	CustomEnvVars    map[string]string `mapstructure:"custom_env_vars" yaml:"custom_env_vars" json:"custom_env_vars" desc:"Environment variables added to every daemon"`
+	InheritEnvVars   []string          `mapstructure:"inherit_env_vars" yaml:"inherit_env_vars" json:"inherit_env_vars" desc:"Variables copied from the invoking shell into every daemon at generate time"`
 }
 
 // Manager handles core configuration operations
 type Manager struct {
 	configPath string
 	config     *CoreConfig
-	viper      *viper.Viper
+	// viper resolves effective values from flags, the environment, the
+	// config file and defaults
+	viper *viper.Viper
+	// file holds only the values from the config file and Set calls, so
+	// Save never persists flag or environment overrides. It is decoded
+	// directly rather than through viper, which lowercases map keys;
+	// environment variable names are case-sensitive.
+	file  map[string]interface{}
+	flags map[string]*pflag.Flag
 }
 
 // ConfigDir returns the daemon-control config directory path
@@ -54,8 +85,12 @@ func ConfigDir() string {
 	return filepath.Join(home, ".daemon-control")
 }
 
-// ConfigPath returns the core config file path
+// ConfigPath returns the core config file path. DAEMON_CONTROL_CORE_CONFIG
+// overrides the default location.
 func ConfigPath() string {
+	if path := os.Getenv(CoreConfigEnv); path != "" {
+		return ExpandPath(path)
+	}
 	return filepath.Join(ConfigDir(), "core.config.yaml")
 }
 
@@ -63,33 +98,53 @@ func ConfigPath() string {
 func DefaultConfig() *CoreConfig {
 	home, _ := os.UserHomeDir()
 	return &CoreConfig{
-		DaemonConfigPath:   "./daemons.yaml",
-		DaemonsDir:         "./daemons",
-		OutputDir:          "./out",
-		LogsDir:            "./logs",
-		AutoGeneratePlists: false,
-		BackupOnGenerate:   true,
-		ValidatePlists:     true,
-		LogLevel:           "info",
-		LogFormat:          "console",
-		LaunchAgentsDir:    filepath.Join(home, "Library", "LaunchAgents"),
-		UseSystemLaunchd:   false,
-		CustomEnvVars:      make(map[string]string),
+		Version:                CurrentVersion,
+		DaemonConfigPath:       "./daemons.yaml",
+		DaemonsDir:             "./daemons",
+		OutputDir:              "./out",
+		LogsDir:                "./logs",
+		AutoGeneratePlists:     false,
+		BackupOnGenerate:       true,
+		ValidatePlists:         true,
+		HistoryMaxRevisions:    20,
+		HistoryMaxAgeDays:      90,
+		MetricsIntervalSeconds: 15,
+		LogLevel:               "info",
+		LogFormat:              "console",
+		LaunchAgentsDir:        filepath.Join(home, "Library", "LaunchAgents"),
+		UseSystemLaunchd:       false,
+		CustomEnvVars:          make(map[string]string),
+		InheritEnvVars:         []string{},
 	}
 }
 
 // NewManager creates a new core config manager
 func NewManager() *Manager {
+	return NewManagerAt(ConfigPath())
+}
+
+// NewManagerAt creates a core config manager for the config file at path
+func NewManagerAt(path string) *Manager {
 	return &Manager{
-		configPath: ConfigPath(),
+		configPath: path,
 		viper:      viper.New(),
 	}
 }
 
+// BindFlag makes a command line flag override a config key when the flag
+// is set. It takes effect on the next Init or Load.
+func (m *Manager) BindFlag(key string, flag *pflag.Flag) error {
+	if m.flags == nil {
+		m.flags = make(map[string]*pflag.Flag)
+	}
+	m.flags[key] = flag
+	return nil
+}
+
 // Init initializes the core configuration
 func (m *Manager) Init() error {
 	// Create config directory if it doesn't exist
-	configDir := ConfigDir()
+	configDir := filepath.Dir(m.configPath)
 	if err := os.MkdirAll(configDir, 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
@@ -108,35 +163,52 @@ func (m *Manager) Init() error {
 
 // Load loads the core configuration
 func (m *Manager) Load() error {
+	m.viper = viper.New()
+	for key, flag := range m.flags {
+		if err := m.viper.BindPFlag(key, flag); err != nil {
+			return fmt.Errorf("failed to bind flag: %w", err)
+		}
+	}
 	m.viper.SetConfigFile(m.configPath)
 	m.viper.SetConfigType("yaml")
 
-	// Set defaults
-	defaults := DefaultConfig()
-	m.viper.SetDefault("daemon_config_path", defaults.DaemonConfigPath)
-	m.viper.SetDefault("daemons_dir", defaults.DaemonsDir)
-	m.viper.SetDefault("output_dir", defaults.OutputDir)
-	m.viper.SetDefault("logs_dir", defaults.LogsDir)
-	m.viper.SetDefault("auto_generate_plists", defaults.AutoGeneratePlists)
-	m.viper.SetDefault("backup_on_generate", defaults.BackupOnGenerate)
-	m.viper.SetDefault("validate_plists", defaults.ValidatePlists)
-	m.viper.SetDefault("log_level", defaults.LogLevel)
-	m.viper.SetDefault("log_format", defaults.LogFormat)
-	m.viper.SetDefault("launch_agents_dir", defaults.LaunchAgentsDir)
-	m.viper.SetDefault("use_system_launchd", defaults.UseSystemLaunchd)
+	// Set defaults. The version has none, so files without one read as
+	// version 0.
+	for key, value := range configValues(DefaultConfig()) {
+		if key != versionKey {
+			m.viper.SetDefault(key, value)
+		}
+	}
+
+	// Environment variables override the config file
+	m.viper.SetEnvPrefix(EnvPrefix)
+	m.viper.AutomaticEnv()
+	if err := m.viper.BindEnv(append([]string{"daemon_config_path"}, envNames("daemon_config_path")...)...); err != nil {
+		return fmt.Errorf("failed to bind environment: %w", err)
+	}
 
 	// Read config
 	if err := m.viper.ReadInConfig(); err != nil {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
 
+	file, err := readFileSettings(m.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	m.file = file
+
 	// Unmarshal config
-	var config CoreConfig
-	if err := m.viper.Unmarshal(&config); err != nil {
+	config, err := m.unmarshal()
+	if err != nil {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	m.config = &config
+	if config.Version > CurrentVersion {
+		return fmt.Errorf("%w: core config version %d is newer than supported version %d", errs.ErrInvalidConfig, config.Version, CurrentVersion)
+	}
+
+	m.config = config
 
 	// Apply log settings
 	m.applyLogSettings()
@@ -150,26 +222,19 @@ func (m *Manager) Save() error {
 		return fmt.Errorf("no config loaded")
 	}
 
-	// Set all values in viper
-	m.viper.Set("daemon_config_path", m.config.DaemonConfigPath)
-	m.viper.Set("daemons_dir", m.config.DaemonsDir)
-	m.viper.Set("output_dir", m.config.OutputDir)
-	m.viper.Set("logs_dir", m.config.LogsDir)
-	m.viper.Set("auto_generate_plists", m.config.AutoGeneratePlists)
-	m.viper.Set("backup_on_generate", m.config.BackupOnGenerate)
-	m.viper.Set("validate_plists", m.config.ValidatePlists)
-	m.viper.Set("log_level", m.config.LogLevel)
-	m.viper.Set("log_format", m.config.LogFormat)
-	m.viper.Set("launch_agents_dir", m.config.LaunchAgentsDir)
-	m.viper.Set("use_system_launchd", m.config.UseSystemLaunchd)
-	m.viper.Set("custom_env_vars", m.config.CustomEnvVars)
-
-	// Write config
-	if err := m.viper.WriteConfig(); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	// Overridden keys keep the value they have in the file
+	settings := configValues(m.config)
+	defaults := configValues(DefaultConfig())
+	for key := range settings {
+		if m.Source(key).Overridden() {
+			settings[key] = defaults[key]
+			if value, ok := m.file[key]; ok {
+				settings[key] = value
+			}
+		}
 	}
 
-	return nil
+	return m.writeFile(settings)
 }
 
 // Get returns a config value by key
@@ -178,10 +243,26 @@ func (m *Manager) Get(key string) (interface{}, error) {
 		return nil, fmt.Errorf("config not loaded")
 	}
 
+	// Keys in a block read as nil until the block sets them
+	if info, entry, err := LookupKey(key); err == nil && entry == "" && strings.Contains(info.Key, ".") && m.config != nil {
+		return configValue(m.config, info.Key), nil
+	}
+
 	if !m.viper.IsSet(key) {
 		return nil, fmt.Errorf("key not found: %s", key)
 	}
 
+	// Map entries come from the decoded config, which keeps their case
+	if info, entry, err := LookupKey(key); err == nil && info.Type == KeyMap && m.config != nil {
+		entries, _ := configValues(m.config)[info.Key].(map[string]string)
+		if entry == "" {
+			return entries, nil
+		}
+		if value, ok := entries[entry]; ok {
+			return value, nil
+		}
+	}
+
 	return m.viper.Get(key), nil
 }
 
@@ -191,14 +272,28 @@ func (m *Manager) Set(key string, value interface{}) error {
 		return fmt.Errorf("config not loaded")
 	}
 
-	m.viper.Set(key, value)
+	if _, _, err := LookupKey(key); err != nil {
+		return err
+	}
+
+	m.setFileValue(key, value)
+
+	// A flag or environment override still wins for this invocation
+	if !m.Source(key).Overridden() {
+		m.viper.Set(key, value)
+	}
 
 	// Reload config to update struct
-	var config CoreConfig
-	if err := m.viper.Unmarshal(&config); err != nil {
+	updated, err := m.unmarshal()
+	if err != nil {
 		return fmt.Errorf("failed to update config: %w", err)
 	}
-	m.config = &config
+	if updated.Notify != nil {
+		if err := config.ValidateNotify(updated.Notify); err != nil {
+			return fmt.Errorf("%w: notify: %v", errs.ErrInvalidConfig, err)
+		}
+	}
+	m.config = updated
 
 	// Apply log settings if changed
 	if key == "log_level" || key == "log_format" {
@@ -208,6 +303,139 @@ func (m *Manager) Set(key string, value interface{}) error {
 	return m.Save()
 }
 
+// SetValue validates and sets a value given on the command line. Map
+// entries are addressed as key.name.
+func (m *Manager) SetValue(key, value string) error {
+	info, entry, err := LookupKey(key)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := info.ParseValue(value, entry)
+	if err != nil {
+		return err
+	}
+
+	if entry == "" {
+		return m.Set(info.Key, parsed)
+	}
+
+	entries := m.fileMap(info.Key)
+	entries[entry] = value
+	return m.Set(info.Key, entries)
+}
+
+// Unset removes a key from the config file so it falls back to its
+// default. Map entries are addressed as key.name, and a block such as
+// notify is removed whole by its name.
+func (m *Manager) Unset(key string) error {
+	if IsBlock(key) {
+		return m.unsetBlock(key)
+	}
+
+	info, entry, err := LookupKey(key)
+	if err != nil {
+		return err
+	}
+
+	if entry != "" {
+		entries := m.fileMap(info.Key)
+		if _, ok := entries[entry]; !ok {
+			return fmt.Errorf("key not found: %s", key)
+		}
+		delete(entries, entry)
+		return m.Set(info.Key, entries)
+	}
+
+	if _, ok := m.fileValue(info.Key); !ok {
+		return m.Load()
+	}
+	deleteFileValue(m.file, info.Key)
+	if err := m.writeFile(m.file); err != nil {
+		return err
+	}
+
+	return m.Load()
+}
+
+// unsetBlock removes a block of keys, such as notify, from the config file
+func (m *Manager) unsetBlock(block string) error {
+	if _, ok := m.fileValue(block); ok {
+		deleteFileValue(m.file, block)
+		if err := m.writeFile(m.file); err != nil {
+			return err
+		}
+	}
+	return m.Load()
+}
+
+// fileValue returns the value a key has in the config file. Keys in a
+// block are looked up in the block's mapping.
+func (m *Manager) fileValue(key string) (interface{}, bool) {
+	var value interface{} = m.file
+	for _, name := range strings.Split(key, ".") {
+		block, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = block[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// setFileValue sets a key in the config file settings, creating the
+// mapping of the block it is in if needed
+func (m *Manager) setFileValue(key string, value interface{}) {
+	if m.file == nil {
+		m.file = make(map[string]interface{})
+	}
+	names := strings.Split(key, ".")
+	block := m.file
+	for _, name := range names[:len(names)-1] {
+		inner, ok := block[name].(map[string]interface{})
+		if !ok {
+			inner = make(map[string]interface{})
+			block[name] = inner
+		}
+		block = inner
+	}
+	block[names[len(names)-1]] = value
+}
+
+// deleteFileValue removes a key from config file settings, and each block
+// it was in that is left empty
+func deleteFileValue(settings map[string]interface{}, key string) {
+	name, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		delete(settings, name)
+		return
+	}
+	block, ok := settings[name].(map[string]interface{})
+	if !ok {
+		return
+	}
+	deleteFileValue(block, rest)
+	if len(block) == 0 {
+		delete(settings, name)
+	}
+}
+
+// fileMap returns a copy of a map key's entries from the config file
+func (m *Manager) fileMap(key string) map[string]interface{} {
+	entries := make(map[string]interface{})
+	if m.file == nil {
+		return entries
+	}
+	if current, ok := m.file[key].(map[string]interface{}); ok {
+		for k, v := range current {
+			entries[k] = v
+		}
+	}
+	return entries
+}
+
 // GetConfig returns the loaded configuration
 func (m *Manager) GetConfig() *CoreConfig {
 	return m.config
@@ -219,42 +447,89 @@ func (m *Manager) GetDaemonConfigPath() string {
 		return DefaultConfig().DaemonConfigPath
 	}
 
-	// Expand home directory if needed
-	path := m.config.DaemonConfigPath
+	return ExpandPath(m.config.DaemonConfigPath)
+}
+
+// DaemonEnvironment returns the environment variables added to every
+// daemon: the inherit_env_vars set in the current environment, overlaid
+// with custom_env_vars. Variables defined by a daemon take precedence over
+// both.
+func (c *CoreConfig) DaemonEnvironment() map[string]string {
+	env := make(map[string]string)
+	for _, name := range c.InheritEnvVars {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	for name, value := range c.CustomEnvVars {
+		env[name] = value
+	}
+	return env
+}
+
+// ExpandPath expands a leading ~/ to the user's home directory
+func ExpandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		home, _ := os.UserHomeDir()
 		path = filepath.Join(home, path[2:])
 	}
-
 	return path
 }
 
 // createDefaultConfig creates the default config file
 func (m *Manager) createDefaultConfig() error {
-	config := DefaultConfig()
+	return m.writeFile(configValues(DefaultConfig()))
+}
 
-	// Create viper instance with defaults
-	v := viper.New()
-	v.SetConfigFile(m.configPath)
-	v.SetConfigType("yaml")
+// unmarshal decodes the effective configuration. viper lowercases map
+// keys, so map values that come from the config file are taken from the
+// file as written.
+func (m *Manager) unmarshal() (*CoreConfig, error) {
+	var config CoreConfig
+	if err := m.viper.Unmarshal(&config); err != nil {
+		return nil, err
+	}
 
-	v.Set("daemon_config_path", config.DaemonConfigPath)
-	v.Set("daemons_dir", config.DaemonsDir)
-	v.Set("output_dir", config.OutputDir)
-	v.Set("logs_dir", config.LogsDir)
-	v.Set("auto_generate_plists", config.AutoGeneratePlists)
-	v.Set("backup_on_generate", config.BackupOnGenerate)
-	v.Set("validate_plists", config.ValidatePlists)
-	v.Set("log_level", config.LogLevel)
-	v.Set("log_format", config.LogFormat)
-	v.Set("launch_agents_dir", config.LaunchAgentsDir)
-	v.Set("use_system_launchd", config.UseSystemLaunchd)
-	v.Set("custom_env_vars", config.CustomEnvVars)
+	if m.Source("custom_env_vars").Kind == SourceFile {
+		config.CustomEnvVars = make(map[string]string)
+		for name, value := range m.fileMap("custom_env_vars") {
+			config.CustomEnvVars[name] = fmt.Sprint(value)
+		}
+	}
 
-	// Add header comment
-	v.Set("_comment", "daemon-control core configuration file")
+	return &config, nil
+}
+
+// readFileSettings reads the values set in a config file
+func readFileSettings(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is the core config file
+	if err != nil {
+		return nil, err
+	}
 
-	return v.WriteConfig()
+	settings := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// writeFile replaces the config file with settings
+func (m *Manager) writeFile(settings map[string]interface{}) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(settings); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(m.configPath, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
 }
 
 // applyLogSettings applies the log level and format settings
@@ -285,20 +560,68 @@ func (m *Manager) applyLogSettings() {
 	}
 }
 
+// configValues returns a configuration's top-level values by key, read
+// from the CoreConfig mapstructure tags. Blocks that are not set, such as
+// notify, are left out.
+func configValues(config *CoreConfig) map[string]interface{} {
+	v := reflect.ValueOf(config).Elem()
+	values := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		key := fieldKey(v.Type().Field(i))
+		field := v.Field(i)
+		if key == "" || (field.Kind() == reflect.Pointer && field.IsNil()) {
+			continue
+		}
+		values[key] = field.Interface()
+	}
+	return values
+}
+
+// configValue returns the value of a key, which may be inside a block. It
+// returns nil for a key in a block that is not set.
+func configValue(config *CoreConfig, key string) interface{} {
+	v := reflect.ValueOf(config).Elem()
+	for _, name := range strings.Split(key, ".") {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil
+		}
+		field, ok := fieldByKey(v, name)
+		if !ok {
+			return nil
+		}
+		v = field
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
+// fieldByKey returns the field of a struct value with the given config key
+func fieldByKey(v reflect.Value, key string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if fieldKey(v.Type().Field(i)) == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
 // ValidKeys returns all valid configuration keys
 func ValidKeys() []string {
-	return []string{
-		"daemon_config_path",
-		"daemons_dir",
-		"output_dir",
-		"logs_dir",
-		"auto_generate_plists",
-		"backup_on_generate",
-		"validate_plists",
-		"log_level",
-		"log_format",
-		"launch_agents_dir",
-		"use_system_launchd",
-		"custom_env_vars",
+	keys := Keys()
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, key.Key)
 	}
+	return names
 }
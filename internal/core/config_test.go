@@ -255,6 +255,34 @@ func TestManager_GetAndSet(t *testing.T) {
 	}
 }
 
+func TestManager_Notify(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "core.config.yaml")
+	content := `version: 1
+log_level: info
+notify:
+  webhook: https://hooks.example.com/daemons
+  command: [/usr/local/bin/page, --team, ops]
+  flap_restarts: 4
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0600))
+
+	manager := NewManagerAt(configPath)
+	require.NoError(t, manager.Load())
+	n := manager.GetConfig().Notify
+	require.NotNil(t, n)
+	assert.Equal(t, "https://hooks.example.com/daemons", n.Webhook)
+	assert.Equal(t, []string{"/usr/local/bin/page", "--team", "ops"}, n.Command)
+	assert.Equal(t, 4, n.FlapRestarts)
+	assert.NotContains(t, ValidKeys(), "notify")
+
+	// Setting another key keeps the notify block
+	require.NoError(t, manager.SetValue("log_level", "debug"))
+	reloaded := NewManagerAt(configPath)
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, n, reloaded.GetConfig().Notify)
+	assert.Equal(t, "debug", reloaded.GetConfig().LogLevel)
+}
+
 func TestManager_GetDaemonConfigPath(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -305,16 +333,41 @@ func TestValidKeys(t *testing.T) {
 		"auto_generate_plists",
 		"backup_on_generate",
 		"validate_plists",
+		"history_max_revisions",
+		"history_max_age_days",
+		"metrics_interval_seconds",
+		"notify.webhook",
+		"notify.command",
+		"notify.desktop",
+		"notify.events",
+		"notify.flap_restarts",
+		"notify.flap_window_minutes",
 		"log_level",
 		"log_format",
 		"launch_agents_dir",
 		"use_system_launchd",
 		"custom_env_vars",
+		"inherit_env_vars",
 	}
 
 	assert.ElementsMatch(t, expectedKeys, keys)
 }
 
+func TestCoreConfig_DaemonEnvironment(t *testing.T) {
+	t.Setenv("DC_TEST_SHELL_ONLY", "shell")
+	t.Setenv("DC_TEST_BOTH", "shell")
+
+	cfg := DefaultConfig()
+	cfg.InheritEnvVars = []string{"DC_TEST_SHELL_ONLY", "DC_TEST_BOTH", "DC_TEST_UNSET"}
+	cfg.CustomEnvVars = map[string]string{"DC_TEST_BOTH": "custom", "API_URL": "https://example.com"}
+
+	assert.Equal(t, map[string]string{
+		"DC_TEST_SHELL_ONLY": "shell",
+		"DC_TEST_BOTH":       "custom",
+		"API_URL":            "https://example.com",
+	}, cfg.DaemonEnvironment())
+}
+
 func TestManager_applyLogSettings(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
+)
+
+// KeyType is the value type of a configuration key
+type KeyType string
+
+const (
+	// KeyString is a free-form or enumerated string
+	KeyString KeyType = "string"
+	// KeyBool is a boolean
+	KeyBool KeyType = "bool"
+	// KeyInt is a non-negative integer
+	KeyInt KeyType = "int"
+	// KeyMap is a string map whose entries are addressed as key.name
+	KeyMap KeyType = "map"
+	// KeyList is a list of strings, set as a comma-separated value
+	KeyList KeyType = "list"
+)
+
+// KeyInfo describes a configuration key
+type KeyInfo struct {
+	Key         string   `yaml:"key" json:"key"`
+	Type        KeyType  `yaml:"type" json:"type"`
+	Allowed     []string `yaml:"allowed,omitempty" json:"allowed,omitempty"`
+	Description string   `yaml:"description" json:"description"`
+}
+
+// Keys returns metadata for every configuration key, derived from the
+// CoreConfig struct tags. Fields without a desc tag, such as the schema
+// version, are not user settable and are left out. The fields of a nested
+// block, such as notify, are keys of their own, written block.field.
+func Keys() []KeyInfo {
+	return structKeys(reflect.TypeOf(CoreConfig{}), "")
+}
+
+// structKeys returns the keys of a struct's fields, prefixed with the
+// block they are in
+func structKeys(t reflect.Type, prefix string) []KeyInfo {
+	keys := make([]KeyInfo, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := fieldKey(field)
+		if key == "" || field.Tag.Get("desc") == "" {
+			continue
+		}
+		key = prefix + key
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			keys = append(keys, structKeys(fieldType, key+".")...)
+			continue
+		}
+
+		info := KeyInfo{Key: key, Description: field.Tag.Get("desc")}
+		switch fieldType.Kind() {
+		case reflect.Bool:
+			info.Type = KeyBool
+		case reflect.Int:
+			info.Type = KeyInt
+		case reflect.Map:
+			info.Type = KeyMap
+		case reflect.Slice:
+			info.Type = KeyList
+		default:
+			info.Type = KeyString
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			info.Allowed = strings.Split(enum, ",")
+		}
+		keys = append(keys, info)
+	}
+	return keys
+}
+
+// fieldKey returns the config key of a struct field
+func fieldKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	return key
+}
+
+// LookupKey resolves a key, which may address a map entry as key.name. It
+// returns the key's metadata and the map entry name, if any. Map entries
+// are one level deep, so everything after the map's key is the entry name.
+func LookupKey(key string) (KeyInfo, string, error) {
+	keys := Keys()
+	for _, info := range keys {
+		if info.Key == key {
+			return info, "", nil
+		}
+	}
+
+	for _, info := range keys {
+		entry, nested := strings.CutPrefix(key, info.Key+".")
+		switch {
+		case !nested:
+			continue
+		case info.Type != KeyMap:
+			return KeyInfo{}, "", fmt.Errorf("%w: %s is not a map and has no key %q", errs.ErrInvalidConfig, info.Key, entry)
+		case entry == "":
+			return KeyInfo{}, "", fmt.Errorf("%w: missing map key in %q", errs.ErrInvalidConfig, key)
+		}
+		return info, entry, nil
+	}
+
+	if IsBlock(key) {
+		return KeyInfo{}, "", fmt.Errorf("%w: %s is a block: set its keys, such as %s", errs.ErrInvalidConfig, key, blockKeys(keys, key)[0])
+	}
+	return KeyInfo{}, "", fmt.Errorf("%w: unknown configuration key: %s", errs.ErrInvalidConfig, key)
+}
+
+// IsBlock reports whether key names a block of keys, such as notify
+func IsBlock(key string) bool {
+	return len(blockKeys(Keys(), key)) > 0
+}
+
+// blockKeys returns the keys inside a block
+func blockKeys(keys []KeyInfo, block string) []string {
+	var inside []string
+	for _, info := range keys {
+		if strings.HasPrefix(info.Key, block+".") {
+			inside = append(inside, info.Key)
+		}
+	}
+	return inside
+}
+
+// ParseValue converts a command line value for a key, rejecting values the
+// key does not accept. Map entries are plain strings.
+func (k KeyInfo) ParseValue(value string, entry string) (interface{}, error) {
+	if entry != "" {
+		return value, nil
+	}
+
+	switch k.Type {
+	case KeyBool:
+		switch strings.ToLower(value) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%w: invalid boolean value %q for %s: use true, false, yes, no, on or off", errs.ErrInvalidConfig, value, k.Key)
+	case KeyInt:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: invalid value %q for %s: must be a whole number of 0 or more", errs.ErrInvalidConfig, value, k.Key)
+		}
+		return n, nil
+	case KeyMap:
+		return nil, fmt.Errorf("%w: %s is a map: set entries with %s.NAME", errs.ErrInvalidConfig, k.Key, k.Key)
+	case KeyList:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+
+	if len(k.Allowed) > 0 {
+		for _, allowed := range k.Allowed {
+			if value == allowed {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: invalid value %q for %s: must be one of %s", errs.ErrInvalidConfig, value, k.Key, strings.Join(k.Allowed, ", "))
+	}
+	return value, nil
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
+)
+
+func TestKeys(t *testing.T) {
+	keys := Keys()
+	// Every value except the schema version is a settable key, and the
+	// notify block is set through its keys
+	require.Len(t, keys, len(configValues(DefaultConfig()))-1+6)
+
+	byKey := make(map[string]KeyInfo)
+	for _, key := range keys {
+		assert.NotEmpty(t, key.Description, key.Key)
+		byKey[key.Key] = key
+	}
+
+	assert.Equal(t, KeyBool, byKey["auto_generate_plists"].Type)
+	assert.Equal(t, KeyMap, byKey["custom_env_vars"].Type)
+	assert.Equal(t, KeyString, byKey["daemons_dir"].Type)
+	assert.Equal(t, []string{"debug", "info", "warn", "error"}, byKey["log_level"].Allowed)
+	assert.NotContains(t, byKey, "version")
+	assert.NotContains(t, byKey, "notify")
+	assert.Equal(t, KeyString, byKey["notify.webhook"].Type)
+	assert.Equal(t, KeyList, byKey["notify.command"].Type)
+	assert.Equal(t, KeyBool, byKey["notify.desktop"].Type)
+	assert.Equal(t, KeyList, byKey["notify.events"].Type)
+	assert.Equal(t, KeyInt, byKey["notify.flap_restarts"].Type)
+}
+
+func TestLookupKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		wantKey   string
+		wantEntry string
+		wantError string
+	}{
+		{name: "plain key", key: "log_level", wantKey: "log_level"},
+		{name: "map entry", key: "custom_env_vars.API_TOKEN", wantKey: "custom_env_vars", wantEntry: "API_TOKEN"},
+		{name: "unknown key", key: "typo_key", wantError: "unknown configuration key"},
+		{name: "entry of non-map", key: "log_level.x", wantError: "is not a map"},
+		{name: "empty entry", key: "custom_env_vars.", wantError: "missing map key"},
+		{name: "key in block", key: "notify.webhook", wantKey: "notify.webhook"},
+		{name: "block", key: "notify", wantError: "is a block"},
+		{name: "unknown key in block", key: "notify.typo", wantError: "unknown configuration key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, entry, err := LookupKey(tt.key)
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				assert.ErrorIs(t, err, errs.ErrInvalidConfig)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKey, info.Key)
+			assert.Equal(t, tt.wantEntry, entry)
+		})
+	}
+}
+
+func TestKeyInfo_ParseValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		want      interface{}
+		wantError string
+	}{
+		{name: "bool", key: "validate_plists", value: "off", want: false},
+		{name: "invalid bool", key: "validate_plists", value: "maybe", wantError: "invalid boolean value"},
+		{name: "int", key: "history_max_revisions", value: "5", want: 5},
+		{name: "negative int", key: "history_max_revisions", value: "-1", wantError: "must be a whole number"},
+		{name: "enum", key: "log_level", value: "warn", want: "warn"},
+		{name: "invalid enum", key: "log_level", value: "verbose", wantError: "must be one of debug, info, warn, error"},
+		{name: "free string", key: "daemons_dir", value: "/srv/daemons", want: "/srv/daemons"},
+		{name: "whole map", key: "custom_env_vars", value: "x", wantError: "is a map"},
+		{name: "map entry", key: "custom_env_vars.PATH", value: "/usr/bin", want: "/usr/bin"},
+		{name: "list", key: "inherit_env_vars", value: "PATH, SSH_AUTH_SOCK,", want: []string{"PATH", "SSH_AUTH_SOCK"}},
+		{name: "empty list", key: "inherit_env_vars", value: "", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, entry, err := LookupKey(tt.key)
+			require.NoError(t, err)
+
+			got, err := info.ParseValue(tt.value, entry)
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				assert.ErrorIs(t, err, errs.ErrInvalidConfig)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_SetValueAndUnset(t *testing.T) {
+	manager := newSourcesManager(t, "log_level: warn\noutput_dir: /file/out\n")
+
+	require.NoError(t, manager.SetValue("auto_generate_plists", "yes"))
+	require.NoError(t, manager.SetValue("custom_env_vars.first", "1"))
+	require.NoError(t, manager.SetValue("custom_env_vars.second", "2"))
+	require.NoError(t, manager.SetValue("custom_env_vars.THIRD", "3"))
+	assert.True(t, manager.GetConfig().AutoGeneratePlists)
+	assert.Equal(t, map[string]string{"first": "1", "second": "2", "THIRD": "3"}, manager.GetConfig().CustomEnvVars)
+
+	// Map entries keep their case, since they name environment variables
+	value, err := manager.Get("custom_env_vars.THIRD")
+	require.NoError(t, err)
+	assert.Equal(t, "3", value)
+	require.NoError(t, manager.Load())
+	assert.Equal(t, "3", manager.GetConfig().CustomEnvVars["THIRD"])
+
+	// Invalid values and unknown keys are rejected before anything is written
+	assert.Error(t, manager.SetValue("log_level", "verbose"))
+	assert.Error(t, manager.SetValue("typo_key", "x"))
+
+	require.NoError(t, manager.Unset("custom_env_vars.first"))
+	require.NoError(t, manager.Unset("custom_env_vars.THIRD"))
+	require.NoError(t, manager.Unset("output_dir"))
+	assert.Error(t, manager.Unset("custom_env_vars.missing"))
+
+	assert.Equal(t, map[string]string{"second": "2"}, manager.GetConfig().CustomEnvVars)
+	assert.Equal(t, DefaultConfig().OutputDir, manager.GetConfig().OutputDir)
+	assert.Equal(t, SourceDefault, manager.Source("output_dir").Kind)
+
+	data, err := os.ReadFile(manager.configPath)
+	require.NoError(t, err)
+
+	var saved map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &saved))
+	assert.Equal(t, "warn", saved["log_level"])
+	assert.NotContains(t, saved, "typo_key")
+	assert.NotContains(t, saved, "output_dir")
+}
+
+func TestManager_SetValueInBlock(t *testing.T) {
+	manager := newSourcesManager(t, "log_level: warn\n")
+
+	value, err := manager.Get("notify.webhook")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	require.NoError(t, manager.SetValue("notify.webhook", "https://hooks.example.com/daemons"))
+	require.NoError(t, manager.SetValue("notify.flap_restarts", "4"))
+	require.NoError(t, manager.SetValue("notify.desktop", "yes"))
+	n := manager.GetConfig().Notify
+	require.NotNil(t, n)
+	assert.Equal(t, "https://hooks.example.com/daemons", n.Webhook)
+	assert.Equal(t, 4, n.FlapRestarts)
+	assert.Equal(t, SourceFile, manager.Source("notify.webhook").Kind)
+
+	value, err = manager.Get("notify.flap_restarts")
+	require.NoError(t, err)
+	assert.Equal(t, 4, value)
+
+	// The block is validated as a whole before anything is written
+	assert.ErrorContains(t, manager.SetValue("notify.flap_restarts", "1"), "notify: ")
+	assert.ErrorContains(t, manager.SetValue("notify.events", "crash,reboot"), "notify: ")
+	assert.Equal(t, 4, manager.GetConfig().Notify.FlapRestarts)
+
+	require.NoError(t, manager.Unset("notify.webhook"))
+	require.NoError(t, manager.Unset("notify.flap_restarts"))
+	assert.Empty(t, manager.GetConfig().Notify.Webhook)
+
+	require.NoError(t, manager.Unset("notify"))
+	assert.Nil(t, manager.GetConfig().Notify)
+
+	data, err := os.ReadFile(manager.configPath)
+	require.NoError(t, err)
+	var saved map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &saved))
+	assert.NotContains(t, saved, "notify")
+	assert.Equal(t, "warn", saved["log_level"])
+
+	// Removing the last key in a block removes the block
+	require.NoError(t, manager.SetValue("notify.webhook", "https://hooks.example.com/daemons"))
+	require.NoError(t, manager.Unset("notify.webhook"))
+	data, err = os.ReadFile(manager.configPath)
+	require.NoError(t, err)
+	saved = nil
+	require.NoError(t, yaml.Unmarshal(data, &saved))
+	assert.NotContains(t, saved, "notify")
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/utils"
+)
+
+// CurrentVersion is the core config schema version written by this build
+const CurrentVersion = 1
+
+// versionKey is the top-level key holding a config file's schema version.
+// Files without it are version 0.
+const versionKey = "version"
+
+// Migration upgrades a config file to Version from the version before it.
+// Migrations edit the file text rather than re-encoding it, so comments and
+// layout the user wrote survive. The framework updates the version key after
+// Apply succeeds.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(data []byte) ([]byte, error)
+}
+
+// coreMigrations upgrade the core config file, in version order
+var coreMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "add version key and remove _comment",
+		Apply: func(data []byte) ([]byte, error) {
+			return removeTopLevelKey(data, "_comment"), nil
+		},
+	},
+}
+
+// daemonMigrations upgrade the daemon config file, in version order
+var daemonMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "add version key",
+		Apply:       func(data []byte) ([]byte, error) { return data, nil },
+	},
+}
+
+// MigrationResult describes a config file migration
+type MigrationResult struct {
+	Path        string   `yaml:"path" json:"path"`
+	FromVersion int      `yaml:"from_version" json:"from_version"`
+	ToVersion   int      `yaml:"to_version" json:"to_version"`
+	Applied     []string `yaml:"applied" json:"applied"`
+	Backup      string   `yaml:"backup,omitempty" json:"backup,omitempty"`
+	Diff        string   `yaml:"diff,omitempty" json:"diff,omitempty"`
+	DryRun      bool     `yaml:"dry_run" json:"dry_run"`
+}
+
+// Changed reports whether the migration modified (or would modify) the file
+func (r *MigrationResult) Changed() bool {
+	return len(r.Applied) > 0
+}
+
+// MigrateCoreConfig upgrades the core config file at path to
+// CurrentVersion. Unless dryRun is set, the original file is kept as a
+// backup next to it.
+func MigrateCoreConfig(path string, dryRun bool) (*MigrationResult, error) {
+	return migrateFile(path, coreMigrations, CurrentVersion, dryRun)
+}
+
+// MigrateDaemonConfig upgrades the daemon config file at path to
+// config.SchemaVersion. Unless dryRun is set, the original file is kept as
+// a backup next to it.
+func MigrateDaemonConfig(path string, dryRun bool) (*MigrationResult, error) {
+	return migrateFile(path, daemonMigrations, config.SchemaVersion, dryRun)
+}
+
+// FileVersion returns the schema version of the config file at path
+func FileVersion(path string) (int, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path comes from the user's configuration
+	if err != nil {
+		return 0, err
+	}
+	return documentVersion(data)
+}
+
+// migrateFile applies the migrations newer than the file's version, up to
+// target
+func migrateFile(path string, migrations []Migration, target int, dryRun bool) (*MigrationResult, error) {
+	original, err := os.ReadFile(path) // #nosec G304 - path comes from the user's configuration
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := documentVersion(original)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errs.ErrInvalidConfig, path, err)
+	}
+	if version > target {
+		return nil, fmt.Errorf("%w: %s has version %d, newer than supported version %d", errs.ErrInvalidConfig, path, version, target)
+	}
+
+	result := &MigrationResult{Path: path, FromVersion: version, ToVersion: version, Applied: []string{}, DryRun: dryRun}
+	migrated := original
+	for _, migration := range migrations {
+		if migration.Version <= version || migration.Version > target {
+			continue
+		}
+		if migrated, err = migration.Apply(migrated); err != nil {
+			return nil, fmt.Errorf("migration to version %d failed: %w", migration.Version, err)
+		}
+		migrated = setVersion(migrated, migration.Version)
+		result.ToVersion = migration.Version
+		result.Applied = append(result.Applied, fmt.Sprintf("v%d: %s", migration.Version, migration.Description))
+	}
+	if !result.Changed() {
+		return result, nil
+	}
+
+	// The edits are textual, so check that the result still parses
+	if got, err := documentVersion(migrated); err != nil || got != result.ToVersion {
+		return nil, fmt.Errorf("%w: %s could not be migrated automatically, add \"version: %d\" by hand", errs.ErrInvalidConfig, path, result.ToVersion)
+	}
+
+	result.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(original)),
+		B:        difflib.SplitLines(string(migrated)),
+		FromFile: fmt.Sprintf("%s (v%d)", path, result.FromVersion),
+		ToFile:   fmt.Sprintf("%s (v%d)", path, result.ToVersion),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	result.Backup = fmt.Sprintf("%s.v%d.bak", path, result.FromVersion)
+	if err := utils.WriteFileAtomic(result.Backup, original, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, migrated, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write migrated config: %w", err)
+	}
+
+	return result, nil
+}
+
+// documentVersion returns the version key of a YAML document. Documents
+// without one are version 0.
+func documentVersion(data []byte) (int, error) {
+	var document struct {
+		Version *int `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return 0, err
+	}
+	if document.Version == nil {
+		return 0, nil
+	}
+	if *document.Version < 0 {
+		return 0, fmt.Errorf("invalid version %d", *document.Version)
+	}
+	return *document.Version, nil
+}
+
+// topLevelKeyPattern matches the line that starts a top-level key
+func topLevelKeyPattern(key string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `[ \t]*:.*$`)
+}
+
+// setVersion sets the version key, adding it before the first top-level key
+// if missing
+func setVersion(data []byte, version int) []byte {
+	line := []byte(fmt.Sprintf("%s: %d", versionKey, version))
+	if pattern := topLevelKeyPattern(versionKey); pattern.Match(data) {
+		return pattern.ReplaceAllLiteral(data, line)
+	}
+
+	// Insert after any leading comments, blank lines and document marker
+	offset := 0
+	for offset < len(data) {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			end = len(data) - offset
+		} else {
+			end++
+		}
+		text := strings.TrimSpace(string(data[offset : offset+end]))
+		if text != "" && text != "---" && !strings.HasPrefix(text, "#") {
+			break
+		}
+		offset += end
+	}
+
+	migrated := make([]byte, 0, len(data)+len(line)+1)
+	migrated = append(migrated, data[:offset]...)
+	if offset > 0 && data[offset-1] != '\n' {
+		migrated = append(migrated, '\n')
+	}
+	migrated = append(migrated, line...)
+	migrated = append(migrated, '\n')
+	return append(migrated, data[offset:]...)
+}
+
+// removeTopLevelKey deletes a top-level key along with its indented value
+// lines
+func removeTopLevelKey(data []byte, key string) []byte {
+	loc := topLevelKeyPattern(key).FindIndex(data)
+	if loc == nil {
+		return data
+	}
+
+	end := loc[1]
+	for end < len(data) {
+		next := bytes.IndexByte(data[end+1:], '\n')
+		line := data[end+1:]
+		if next >= 0 {
+			line = data[end+1 : end+1+next]
+		}
+		if len(line) == 0 || (line[0] != ' ' && line[0] != '\t' && line[0] != '-') {
+			break
+		}
+		end += len(line) + 1
+	}
+	if end < len(data) {
+		end++ // trailing newline
+	}
+	return append(data[:loc[0]:loc[0]], data[end:]...)
+}
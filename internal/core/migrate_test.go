@@ -0,0 +1,130 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
+)
+
+func TestMigrateCoreConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        string
+		wantApplied int
+		wantError   bool
+	}{
+		{
+			name:        "unversioned with comment key",
+			content:     "# core settings\n_comment: daemon-control core configuration file\nlog_level: debug\n",
+			want:        "# core settings\nversion: 1\nlog_level: debug\n",
+			wantApplied: 1,
+		},
+		{
+			name:        "multi-line comment key",
+			content:     "log_level: debug\n_comment: >\n  folded\n  text\noutput_dir: ./out\n",
+			want:        "version: 1\nlog_level: debug\noutput_dir: ./out\n",
+			wantApplied: 1,
+		},
+		{
+			name:        "empty file",
+			content:     "",
+			want:        "version: 1\n",
+			wantApplied: 1,
+		},
+		{
+			name:    "current version",
+			content: "version: 1\nlog_level: debug\n",
+			want:    "version: 1\nlog_level: debug\n",
+		},
+		{
+			name:      "newer version",
+			content:   "version: 2\n",
+			want:      "version: 2\n",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "core.config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			// A dry run reports the changes without writing anything
+			preview, err := MigrateCoreConfig(path, true)
+			if tt.wantError {
+				assert.ErrorIs(t, err, errs.ErrInvalidConfig)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, preview.Applied, tt.wantApplied)
+			assert.Empty(t, preview.Backup)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(data))
+
+			result, err := MigrateCoreConfig(path, false)
+			require.NoError(t, err)
+			assert.Equal(t, preview.Diff, result.Diff)
+
+			data, err = os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+
+			if tt.wantApplied == 0 {
+				assert.False(t, result.Changed())
+				assert.Empty(t, result.Backup)
+				return
+			}
+			assert.Equal(t, CurrentVersion, result.ToVersion)
+			backup, err := os.ReadFile(result.Backup)
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(backup))
+		})
+	}
+}
+
+func TestMigrateDaemonConfig(t *testing.T) {
+	content := "# My daemons\n\ndaemons:\n  # keep this comment\n  - name: app\n    label: com.example.app\n    program: /bin/app\n"
+	path := filepath.Join(t.TempDir(), "daemons.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	result, err := MigrateDaemonConfig(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.FromVersion)
+	assert.Equal(t, 1, result.ToVersion)
+	assert.Equal(t, path+".v0.bak", result.Backup)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# My daemons\n\nversion: 1\n"+content[len("# My daemons\n\n"):], string(data))
+
+	version, err := FileVersion(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+}
+
+func TestManager_LoadVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	// Files without a version key are version 0
+	manager := newSourcesManager(t, "log_level: warn\n")
+	assert.Equal(t, 0, manager.GetConfig().Version)
+
+	// New files are created at the current version
+	manager = NewManagerAt(filepath.Join(dir, "new.yaml"))
+	require.NoError(t, manager.Init())
+	assert.Equal(t, CurrentVersion, manager.GetConfig().Version)
+	data, err := os.ReadFile(filepath.Join(dir, "new.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "_comment")
+
+	path := filepath.Join(dir, "newer.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: 99\n"), 0600))
+	assert.ErrorIs(t, NewManagerAt(path).Load(), errs.ErrInvalidConfig)
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// EnvPrefix prefixes the environment variables that override config keys
+	EnvPrefix = "DAEMON_CONTROL"
+	// CoreConfigEnv selects the core config file
+	CoreConfigEnv = EnvPrefix + "_CORE_CONFIG"
+)
+
+// SourceKind is the layer a configuration value was resolved from. Layers
+// are listed from highest to lowest precedence.
+type SourceKind string
+
+const (
+	// SourceFlag is a command line flag
+	SourceFlag SourceKind = "flag"
+	// SourceEnv is a DAEMON_CONTROL_* environment variable
+	SourceEnv SourceKind = "env"
+	// SourceFile is the core config file
+	SourceFile SourceKind = "file"
+	// SourceDefault is the built-in default
+	SourceDefault SourceKind = "default"
+)
+
+// Source describes where a configuration value came from
+type Source struct {
+	Kind SourceKind `yaml:"kind" json:"kind"`
+	// Name is the flag, environment variable or file the value came from
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+}
+
+// Overridden reports whether the value overrides the config file for this
+// invocation only
+func (s Source) Overridden() bool {
+	return s.Kind == SourceFlag || s.Kind == SourceEnv
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return string(s.Kind)
+	}
+	return fmt.Sprintf("%s (%s)", s.Kind, s.Name)
+}
+
+// Setting is a resolved configuration value and its source
+type Setting struct {
+	Key    string      `yaml:"key" json:"key"`
+	Value  interface{} `yaml:"value" json:"value"`
+	Source Source      `yaml:"source" json:"source"`
+}
+
+// Source returns where the value of a key came from
+func (m *Manager) Source(key string) Source {
+	if flag, ok := m.flags[key]; ok && flag.Changed {
+		return Source{Kind: SourceFlag, Name: "--" + flag.Name}
+	}
+	for _, name := range envNames(key) {
+		if os.Getenv(name) != "" {
+			return Source{Kind: SourceEnv, Name: name}
+		}
+	}
+	if _, ok := m.fileValue(key); ok {
+		return Source{Kind: SourceFile, Name: m.configPath}
+	}
+	return Source{Kind: SourceDefault}
+}
+
+// Settings returns every configuration key with its value and source
+func (m *Manager) Settings() []Setting {
+	settings := make([]Setting, 0, len(ValidKeys()))
+	for _, key := range ValidKeys() {
+		var value interface{}
+		if m.config != nil {
+			value = configValue(m.config, key)
+		}
+		settings = append(settings, Setting{Key: key, Value: value, Source: m.Source(key)})
+	}
+	return settings
+}
+
+// envNames returns the environment variables that override a key, in
+// order of precedence
+func envNames(key string) []string {
+	// Maps and the keys of blocks are only read from the config file
+	if key == "custom_env_vars" || strings.Contains(key, ".") {
+		return nil
+	}
+
+	name := EnvPrefix + "_" + strings.ToUpper(key)
+	if key == "daemon_config_path" {
+		return []string{EnvPrefix + "_CONFIG", name}
+	}
+	return []string{name}
+}
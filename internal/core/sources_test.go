@@ -0,0 +1,137 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// newSourcesManager writes a core config file and returns a manager for it
+// with --daemons-dir bound to daemons_dir
+func newSourcesManager(t *testing.T, content string, args ...string) *Manager {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "core.config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0600))
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("daemons-dir", "", "")
+	require.NoError(t, flags.Parse(args))
+
+	manager := NewManagerAt(configPath)
+	require.NoError(t, manager.BindFlag("daemons_dir", flags.Lookup("daemons-dir")))
+	require.NoError(t, manager.Init())
+	return manager
+}
+
+func TestManager_Precedence(t *testing.T) {
+	content := "daemons_dir: /file/daemons\nlog_level: warn\n"
+
+	tests := []struct {
+		name       string
+		env        string
+		args       []string
+		wantValue  string
+		wantSource Source
+	}{
+		{
+			name:       "file",
+			wantValue:  "/file/daemons",
+			wantSource: Source{Kind: SourceFile},
+		},
+		{
+			name:       "env overrides file",
+			env:        "/env/daemons",
+			wantValue:  "/env/daemons",
+			wantSource: Source{Kind: SourceEnv, Name: "DAEMON_CONTROL_DAEMONS_DIR"},
+		},
+		{
+			name:       "flag overrides env",
+			env:        "/env/daemons",
+			args:       []string{"--daemons-dir", "/flag/daemons"},
+			wantValue:  "/flag/daemons",
+			wantSource: Source{Kind: SourceFlag, Name: "--daemons-dir"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DAEMON_CONTROL_DAEMONS_DIR", tt.env)
+
+			manager := newSourcesManager(t, content, tt.args...)
+			assert.Equal(t, tt.wantValue, manager.GetConfig().DaemonsDir)
+
+			source := manager.Source("daemons_dir")
+			assert.Equal(t, tt.wantSource.Kind, source.Kind)
+			if tt.wantSource.Name != "" {
+				assert.Equal(t, tt.wantSource.Name, source.Name)
+			}
+
+			// Keys without overrides are unaffected
+			assert.Equal(t, "warn", manager.GetConfig().LogLevel)
+			assert.Equal(t, SourceDefault, manager.Source("output_dir").Kind)
+		})
+	}
+}
+
+func TestManager_ConfigEnvAlias(t *testing.T) {
+	t.Setenv("DAEMON_CONTROL_CONFIG", "/env/daemons.yaml")
+
+	manager := newSourcesManager(t, "daemon_config_path: /file/daemons.yaml\n")
+	assert.Equal(t, "/env/daemons.yaml", manager.GetDaemonConfigPath())
+	assert.Equal(t, Source{Kind: SourceEnv, Name: "DAEMON_CONTROL_CONFIG"}, manager.Source("daemon_config_path"))
+}
+
+func TestManager_BoolFromEnv(t *testing.T) {
+	t.Setenv("DAEMON_CONTROL_AUTO_GENERATE_PLISTS", "true")
+
+	manager := newSourcesManager(t, "auto_generate_plists: false\n")
+	assert.True(t, manager.GetConfig().AutoGeneratePlists)
+}
+
+func TestManager_SaveDoesNotPersistOverrides(t *testing.T) {
+	t.Setenv("DAEMON_CONTROL_LOG_LEVEL", "debug")
+
+	manager := newSourcesManager(t, "daemons_dir: /file/daemons\nlog_level: warn\n", "--daemons-dir", "/flag/daemons")
+	require.NoError(t, manager.Set("output_dir", "/file/out"))
+
+	// Setting an overridden key stores it but keeps the override in effect
+	require.NoError(t, manager.Set("log_level", "error"))
+	assert.Equal(t, "debug", manager.GetConfig().LogLevel)
+
+	data, err := os.ReadFile(manager.configPath)
+	require.NoError(t, err)
+
+	var saved map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &saved))
+	assert.Equal(t, "/file/daemons", saved["daemons_dir"])
+	assert.Equal(t, "error", saved["log_level"])
+	assert.Equal(t, "/file/out", saved["output_dir"])
+}
+
+func TestManager_Settings(t *testing.T) {
+	manager := newSourcesManager(t, "log_level: warn\n")
+
+	settings := manager.Settings()
+	require.Len(t, settings, len(ValidKeys()))
+
+	for _, setting := range settings {
+		if setting.Key == "log_level" {
+			assert.Equal(t, "warn", setting.Value)
+			assert.Equal(t, SourceFile, setting.Source.Kind)
+		}
+	}
+}
+
+func TestConfigPath_Env(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.yaml")
+	t.Setenv(CoreConfigEnv, path)
+
+	assert.Equal(t, path, ConfigPath())
+	assert.Equal(t, path, NewManager().configPath)
+}
@@ -0,0 +1,121 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned by daemon operations. Callers wrap them with
+// context and match them with errors.Is.
+var (
+	// ErrNotFound means the daemon is not defined
+	ErrNotFound = errors.New("daemon not found")
+	// ErrNotInstalled means the daemon is not installed in LaunchAgents
+	ErrNotInstalled = errors.New("daemon not installed")
+	// ErrAlreadyInstalled means the daemon is already installed
+	ErrAlreadyInstalled = errors.New("daemon already installed")
+	// ErrAlreadyRunning means the daemon is already running
+	ErrAlreadyRunning = errors.New("daemon already running")
+	// ErrNotRunning means the daemon is not running
+	ErrNotRunning = errors.New("daemon not running")
+	// ErrBackend means launchctl or another system tool failed
+	ErrBackend = errors.New("launchd backend failed")
+	// ErrHealthCheck means the daemon did not come up after starting
+	ErrHealthCheck = errors.New("health check failed")
+	// ErrInvalidConfig means a configuration file could not be used
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrValidation means validation ran and reported problems
+	ErrValidation = errors.New("validation failed")
+)
+
+// Exit codes. They are part of the command line interface and must not
+// change once released.
+const (
+	ExitOK               = 0
+	ExitFailure          = 1
+	ExitUsage            = 2
+	ExitNotFound         = 3
+	ExitNotInstalled     = 4
+	ExitAlreadyInstalled = 5
+	ExitAlreadyRunning   = 6
+	ExitNotRunning       = 7
+	ExitBackend          = 8
+	ExitHealthCheck      = 9
+	ExitInvalidConfig    = 10
+	ExitValidation       = 11
+)
+
+// exitCodes maps each sentinel to its exit code, most specific first
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{ErrNotFound, ExitNotFound},
+	{ErrNotInstalled, ExitNotInstalled},
+	{ErrAlreadyInstalled, ExitAlreadyInstalled},
+	{ErrAlreadyRunning, ExitAlreadyRunning},
+	{ErrNotRunning, ExitNotRunning},
+	{ErrHealthCheck, ExitHealthCheck},
+	{ErrBackend, ExitBackend},
+	{ErrInvalidConfig, ExitInvalidConfig},
+	{ErrValidation, ExitValidation},
+}
+
+// exitError carries an explicit exit code for commands whose codes follow
+// another tool's convention, such as diff
+type exitError struct {
+	err  error
+	code int
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// WithExitCode attaches an explicit exit code to err
+func WithExitCode(err error, code int) error {
+	return &exitError{err: err, code: code}
+}
+
+// Silent returns an error that only sets the exit code. It is used when a
+// command has already reported its outcome.
+func Silent(code int) error {
+	return &exitError{code: code}
+}
+
+// IsSilent reports whether err only carries an exit code
+func IsSilent(err error) bool {
+	var exit *exitError
+	return errors.As(err, &exit) && exit.err == nil
+}
+
+// Backend wraps a failed system command as an ErrBackend
+func Backend(operation string, err error) error {
+	return fmt.Errorf("%w: %s: %w", ErrBackend, operation, err)
+}
+
+// ExitCode returns the exit code for err
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+
+	for _, mapping := range exitCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.code
+		}
+	}
+	return ExitFailure
+}
@@ -0,0 +1,48 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"unknown error", errors.New("boom"), ExitFailure},
+		{"sentinel", ErrNotInstalled, ExitNotInstalled},
+		{"wrapped sentinel", fmt.Errorf("start my-daemon: %w", ErrAlreadyRunning), ExitAlreadyRunning},
+		{"backend", Backend("launchctl load", errors.New("exit status 5")), ExitBackend},
+		{"health check wins over backend", fmt.Errorf("%w: %w", ErrHealthCheck, ErrBackend), ExitHealthCheck},
+		{"explicit exit code", WithExitCode(ErrNotFound, 2), 2},
+		{"silent", Silent(1), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExitCode(tt.err))
+		})
+	}
+}
+
+func TestBackend(t *testing.T) {
+	cause := errors.New("exit status 5")
+	err := Backend("launchctl load", cause)
+
+	assert.ErrorIs(t, err, ErrBackend)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "launchd backend failed: launchctl load: exit status 5", err.Error())
+}
+
+func TestSilent(t *testing.T) {
+	assert.True(t, IsSilent(Silent(1)))
+	assert.True(t, IsSilent(fmt.Errorf("wrapped: %w", Silent(1))))
+	assert.False(t, IsSilent(WithExitCode(ErrNotFound, 2)))
+	assert.False(t, IsSilent(ErrNotFound))
+}
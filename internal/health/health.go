@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+// Check runs a daemon's health check, returning nil if it passes. The
+// check's timeout bounds it in addition to ctx.
+func Check(ctx context.Context, check *config.HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, check.TimeoutDuration())
+	defer cancel()
+
+	switch {
+	case len(check.Command) > 0:
+		return checkCommand(ctx, check.Command)
+	case check.HTTP != "":
+		return checkHTTP(ctx, check.HTTP)
+	case check.TCP != "":
+		return checkTCP(ctx, check.TCP)
+	default:
+		return fmt.Errorf("health check has no command, http or tcp check")
+	}
+}
+
+func checkCommand(ctx context.Context, command []string) error {
+	output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput() // #nosec G204 - command comes from the user's configuration
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s timed out", command[0])
+		}
+		if len(output) > 0 {
+			return fmt.Errorf("%s failed: %w: %s", command[0], err, truncate(output))
+		}
+		return fmt.Errorf("%s failed: %w", command[0], err)
+	}
+	return nil
+}
+
+func checkHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+func checkTCP(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// truncate shortens command output for an error message
+func truncate(output []byte) string {
+	const limit = 200
+	if len(output) > limit {
+		return string(output[:limit]) + "..."
+	}
+	return string(output)
+}
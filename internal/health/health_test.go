@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+func TestCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	open := listener.Addr().String()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	require.NoError(t, closed.Close())
+	defer listener.Close()
+
+	tests := []struct {
+		name    string
+		check   config.HealthCheck
+		wantErr string
+	}{
+		{name: "command passes", check: config.HealthCheck{Command: []string{"true"}}},
+		{name: "command fails", check: config.HealthCheck{Command: []string{"sh", "-c", "echo broken; exit 3"}}, wantErr: "sh failed: exit status 3: broken"},
+		{name: "command times out", check: config.HealthCheck{Command: []string{"sleep", "5"}, Timeout: 1}, wantErr: "sleep timed out"},
+		{name: "http passes", check: config.HealthCheck{HTTP: healthy.URL}},
+		{name: "http fails", check: config.HealthCheck{HTTP: unhealthy.URL}, wantErr: "503 Service Unavailable"},
+		{name: "tcp passes", check: config.HealthCheck{TCP: open}},
+		{name: "tcp fails", check: config.HealthCheck{TCP: closedAddr}, wantErr: "connection refused"},
+		{name: "no check", check: config.HealthCheck{}, wantErr: "no command, http or tcp check"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(context.Background(), &tt.check)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
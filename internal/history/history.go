@@ -0,0 +1,250 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mjmorales/daemon-control/internal/utils"
+)
+
+// Revision sources
+const (
+	SourceGenerate = "generate"
+	SourceInstall  = "install"
+	SourceRollback = "rollback"
+	// SourceBackup is a plist found in the daemons directory before
+	// generate replaced it
+	SourceBackup = "backup"
+)
+
+// objectsDir holds plist contents, named by their SHA-256
+const objectsDir = "objects"
+
+// Revision is a recorded version of a daemon's plist
+type Revision struct {
+	Number     int       `json:"rev" yaml:"rev"`
+	Hash       string    `json:"hash" yaml:"hash"`
+	ConfigHash string    `json:"config_hash,omitempty" yaml:"config_hash,omitempty"`
+	Time       time.Time `json:"time" yaml:"time"`
+	Source     string    `json:"source" yaml:"source"`
+}
+
+// Retention limits how much history is kept per daemon. Zero values mean
+// no limit. The newest revision is always kept.
+type Retention struct {
+	MaxRevisions int
+	MaxAge       time.Duration
+}
+
+// Store is a content-addressed history of plists. Each daemon has an index
+// of revisions; identical plists share one object.
+type Store struct {
+	dir       string
+	retention Retention
+	now       func() time.Time
+}
+
+// NewStore creates a history store rooted at dir
+func NewStore(dir string, retention Retention) *Store {
+	return &Store{dir: dir, retention: retention, now: time.Now}
+}
+
+// Dir returns the directory the store is rooted at
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Record adds data as a new revision of the daemon's plist, unless it
+// matches the latest revision already. An empty configHash is filled in
+// from an earlier revision with the same contents. It returns the latest
+// revision and whether one was added.
+func (s *Store) Record(daemon string, data []byte, configHash, source string) (*Revision, bool, error) {
+	revisions, err := s.Revisions(daemon)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hash := Hash(data)
+	if n := len(revisions); n > 0 && revisions[n-1].Hash == hash {
+		return &revisions[n-1], false, nil
+	}
+	if configHash == "" {
+		for i := len(revisions) - 1; i >= 0; i-- {
+			if revisions[i].Hash == hash {
+				configHash = revisions[i].ConfigHash
+				break
+			}
+		}
+	}
+
+	if err := s.writeObject(hash, data); err != nil {
+		return nil, false, err
+	}
+
+	now := s.now().UTC()
+	revision := Revision{
+		Number:     1,
+		Hash:       hash,
+		ConfigHash: configHash,
+		Time:       now,
+		Source:     source,
+	}
+	if n := len(revisions); n > 0 {
+		revision.Number = revisions[n-1].Number + 1
+	}
+	revisions = s.retain(append(revisions, revision), now)
+
+	if err := s.writeIndex(daemon, revisions); err != nil {
+		return nil, false, err
+	}
+	if err := s.collect(); err != nil {
+		return nil, false, err
+	}
+	return &revision, true, nil
+}
+
+// Revisions returns a daemon's revisions, oldest first
+func (s *Store) Revisions(daemon string) ([]Revision, error) {
+	data, err := os.ReadFile(s.indexPath(daemon))
+	if os.IsNotExist(err) {
+		return []Revision{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, fmt.Errorf("corrupt history index for %s: %w", daemon, err)
+	}
+	return revisions, nil
+}
+
+// Revision returns revision number rev of a daemon's plist
+func (s *Store) Revision(daemon string, rev int) (*Revision, error) {
+	revisions, err := s.Revisions(daemon)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Number == rev {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no revision %d", daemon, rev)
+}
+
+// Read returns the plist contents of a revision
+func (s *Store) Read(revision *Revision) ([]byte, error) {
+	data, err := os.ReadFile(s.objectPath(revision.Hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision %d: %w", revision.Number, err)
+	}
+	if Hash(data) != revision.Hash {
+		return nil, fmt.Errorf("revision %d is corrupt: content does not match its hash", revision.Number)
+	}
+	return data, nil
+}
+
+// Hash returns the content address of a plist
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// retain drops revisions beyond the retention limits as of now, always
+// keeping the newest
+func (s *Store) retain(revisions []Revision, now time.Time) []Revision {
+	if limit := s.retention.MaxRevisions; limit > 0 && len(revisions) > limit {
+		revisions = revisions[len(revisions)-limit:]
+	}
+	if s.retention.MaxAge > 0 {
+		cutoff := now.Add(-s.retention.MaxAge)
+		for len(revisions) > 1 && revisions[0].Time.Before(cutoff) {
+			revisions = revisions[1:]
+		}
+	}
+	return revisions
+}
+
+// collect removes objects no index refers to any more
+func (s *Store) collect() error {
+	daemons, err := s.Daemons()
+	if err != nil {
+		return err
+	}
+
+	referenced := map[string]bool{}
+	for _, daemon := range daemons {
+		revisions, err := s.Revisions(daemon)
+		if err != nil {
+			return err
+		}
+		for _, revision := range revisions {
+			referenced[revision.Hash] = true
+		}
+	}
+
+	objects, err := os.ReadDir(filepath.Join(s.dir, objectsDir))
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if referenced[object.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, objectsDir, object.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Daemons returns the names of the daemons with recorded history
+func (s *Store) Daemons() ([]string, error) {
+	indexes, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		names = append(names, strings.TrimSuffix(filepath.Base(index), ".json"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *Store) writeObject(hash string, data []byte) error {
+	path := s.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return utils.WriteFileAtomic(path, data, 0600)
+}
+
+func (s *Store) writeIndex(daemon string, revisions []Revision) error {
+	data, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(s.indexPath(daemon), append(data, '\n'), 0600)
+}
+
+func (s *Store) indexPath(daemon string) string {
+	return filepath.Join(s.dir, daemon+".json")
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.dir, objectsDir, hash)
+}
@@ -0,0 +1,156 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore returns a store whose clock advances an hour per call
+func newTestStore(t *testing.T, retention Retention) *Store {
+	t.Helper()
+	store := NewStore(t.TempDir(), retention)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time {
+		clock = clock.Add(time.Hour)
+		return clock
+	}
+	return store
+}
+
+func objectCount(t *testing.T, store *Store) int {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(store.Dir(), objectsDir))
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestStore_Record(t *testing.T) {
+	store := newTestStore(t, Retention{})
+
+	first, added, err := store.Record("web", []byte("v1"), "config1", SourceGenerate)
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, 1, first.Number)
+	assert.Equal(t, Hash([]byte("v1")), first.Hash)
+
+	// Recording the latest contents again is a no-op
+	same, added, err := store.Record("web", []byte("v1"), "", SourceInstall)
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, 1, same.Number)
+
+	_, _, err = store.Record("web", []byte("v2"), "config2", SourceGenerate)
+	require.NoError(t, err)
+
+	// Earlier contents get a new revision that shares the object and
+	// inherits its config hash
+	back, added, err := store.Record("web", []byte("v1"), "", SourceRollback)
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, 3, back.Number)
+	assert.Equal(t, "config1", back.ConfigHash)
+	assert.Equal(t, 2, objectCount(t, store))
+
+	revisions, err := store.Revisions("web")
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, []string{SourceGenerate, SourceGenerate, SourceRollback},
+		[]string{revisions[0].Source, revisions[1].Source, revisions[2].Source})
+	assert.True(t, revisions[1].Time.After(revisions[0].Time))
+
+	data, err := store.Read(&revisions[1])
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+
+	daemons, err := store.Daemons()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, daemons)
+}
+
+func TestStore_Retention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention Retention
+		wantRevs  []int
+	}{
+		{name: "unlimited", retention: Retention{}, wantRevs: []int{1, 2, 3, 4, 5}},
+		{name: "max revisions", retention: Retention{MaxRevisions: 2}, wantRevs: []int{4, 5}},
+		// Revision n is recorded n hours in; the last is recorded at hour 5
+		{name: "max age", retention: Retention{MaxAge: 150 * time.Minute}, wantRevs: []int{3, 4, 5}},
+		{name: "max age keeps newest", retention: Retention{MaxAge: time.Minute}, wantRevs: []int{5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t, tt.retention)
+			for _, content := range []string{"a", "b", "c", "d", "e"} {
+				_, _, err := store.Record("web", []byte(content), "", SourceGenerate)
+				require.NoError(t, err)
+			}
+
+			revisions, err := store.Revisions("web")
+			require.NoError(t, err)
+			got := make([]int, 0, len(revisions))
+			for _, revision := range revisions {
+				got = append(got, revision.Number)
+			}
+			assert.Equal(t, tt.wantRevs, got)
+
+			// Objects of dropped revisions are removed
+			assert.Equal(t, len(tt.wantRevs), objectCount(t, store))
+		})
+	}
+}
+
+func TestStore_SharedObjects(t *testing.T) {
+	store := newTestStore(t, Retention{MaxRevisions: 1})
+
+	_, _, err := store.Record("web", []byte("shared"), "", SourceGenerate)
+	require.NoError(t, err)
+	_, _, err = store.Record("api", []byte("shared"), "", SourceGenerate)
+	require.NoError(t, err)
+	_, _, err = store.Record("web", []byte("web only"), "", SourceGenerate)
+	require.NoError(t, err)
+
+	// api still refers to the shared object
+	revisions, err := store.Revisions("api")
+	require.NoError(t, err)
+	data, err := store.Read(&revisions[0])
+	require.NoError(t, err)
+	assert.Equal(t, "shared", string(data))
+	assert.Equal(t, 2, objectCount(t, store))
+}
+
+func TestStore_Revision(t *testing.T) {
+	store := newTestStore(t, Retention{})
+
+	revisions, err := store.Revisions("missing")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	_, _, err = store.Record("web", []byte("v1"), "", SourceGenerate)
+	require.NoError(t, err)
+
+	revision, err := store.Revision("web", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, revision.Number)
+
+	_, err = store.Revision("web", 7)
+	assert.EqualError(t, err, "web has no revision 7")
+}
+
+func TestStore_ReadCorrupt(t *testing.T) {
+	store := newTestStore(t, Retention{})
+
+	revision, _, err := store.Record("web", []byte("v1"), "", SourceGenerate)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir(), objectsDir, revision.Hash), []byte("tampered"), 0600))
+
+	_, err = store.Read(revision)
+	assert.ErrorContains(t, err, "corrupt")
+}
@@ -0,0 +1,75 @@
+package launchd
+
+import "syscall"
+
+// Sighting is a job's state at one poll, kept to compare with the next
+type Sighting struct {
+	Loaded bool
+	// PID is the running process, 0 when the job is not running
+	PID int
+	// Runs is launchd's count of the job's starts, -1 when unknown
+	Runs           int
+	LastExitStatus *int
+}
+
+// Observe records the state of job, reading its run count from backend
+// when it is loaded
+func Observe(backend Backend, job Job, loaded bool) Sighting {
+	s := Sighting{Loaded: loaded, Runs: -1, LastExitStatus: job.LastExitStatus}
+	if job.Running() {
+		s.PID = *job.PID
+	}
+	if loaded {
+		if runs, err := backend.Runs(job.Label); err == nil {
+			s.Runs = runs
+		}
+	}
+	return s
+}
+
+// ExitsSince returns how many times the job exited between the earlier
+// sighting prev and s. With run counts every start is accounted for, so
+// exits are seen even when no poll caught the process running. Without
+// them, an exit is seen when the process seen at prev is gone or the last
+// exit status changed. An unloaded job has not exited on its own.
+func (s Sighting) ExitsSince(prev Sighting) int {
+	if !s.Loaded {
+		return 0
+	}
+	if !prev.Loaded {
+		prev = Sighting{Loaded: true}
+	}
+
+	if s.Runs >= 0 && prev.Runs >= 0 && s.Runs >= prev.Runs {
+		exits := s.Runs - prev.Runs
+		if prev.PID != 0 {
+			exits++
+		}
+		if s.PID != 0 {
+			exits--
+		}
+		return max(exits, 0)
+	}
+
+	if prev.PID != 0 && s.PID != prev.PID {
+		return 1
+	}
+	if !sameStatus(prev.LastExitStatus, s.LastExitStatus) {
+		return 1
+	}
+	return 0
+}
+
+// Crashed reports whether an exit status is a failure. launchctl reports
+// a process killed by a signal as the negated signal number; SIGTERM is
+// how launchd stops a job, so it is not a crash.
+func Crashed(status *int) bool {
+	return status != nil && *status != 0 && *status != -int(syscall.SIGTERM)
+}
+
+func sameStatus(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
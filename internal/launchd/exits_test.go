@@ -0,0 +1,44 @@
+package launchd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSighting_ExitsSince(t *testing.T) {
+	status := func(s int) *int { return &s }
+	tests := []struct {
+		name string
+		prev Sighting
+		now  Sighting
+		want int
+	}{
+		{name: "still running", prev: Sighting{Loaded: true, PID: 10, Runs: 1}, now: Sighting{Loaded: true, PID: 10, Runs: 1}, want: 0},
+		{name: "exited", prev: Sighting{Loaded: true, PID: 10, Runs: 1}, now: Sighting{Loaded: true, Runs: 1}, want: 1},
+		{name: "restarted", prev: Sighting{Loaded: true, PID: 10, Runs: 1}, now: Sighting{Loaded: true, PID: 11, Runs: 2}, want: 1},
+		{name: "exited twice unseen", prev: Sighting{Loaded: true, Runs: 3}, now: Sighting{Loaded: true, Runs: 5}, want: 2},
+		{name: "started and running", prev: Sighting{Loaded: true, Runs: 3}, now: Sighting{Loaded: true, PID: 12, Runs: 4}, want: 0},
+		{name: "loaded and exited", prev: Sighting{Runs: -1}, now: Sighting{Loaded: true, Runs: 1}, want: 1},
+		{name: "unloaded", prev: Sighting{Loaded: true, PID: 10, Runs: 1}, now: Sighting{Runs: -1}, want: 0},
+		{name: "runs reset", prev: Sighting{Loaded: true, PID: 10, Runs: 5}, now: Sighting{Loaded: true, PID: 10, Runs: 1}, want: 0},
+		{name: "no runs, process gone", prev: Sighting{Loaded: true, PID: 10, Runs: -1}, now: Sighting{Loaded: true, Runs: -1}, want: 1},
+		{name: "no runs, status changed", prev: Sighting{Loaded: true, Runs: -1, LastExitStatus: status(0)}, now: Sighting{Loaded: true, Runs: -1, LastExitStatus: status(1)}, want: 1},
+		{name: "no runs, same status", prev: Sighting{Loaded: true, Runs: -1, LastExitStatus: status(1)}, now: Sighting{Loaded: true, Runs: -1, LastExitStatus: status(1)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.now.ExitsSince(tt.prev))
+		})
+	}
+}
+
+func TestCrashed(t *testing.T) {
+	status := func(s int) *int { return &s }
+	assert.False(t, Crashed(nil))
+	assert.False(t, Crashed(status(0)))
+	assert.False(t, Crashed(status(-15)), "SIGTERM is how launchd stops jobs")
+	assert.True(t, Crashed(status(1)))
+	assert.True(t, Crashed(status(-9)))
+}
@@ -0,0 +1,167 @@
+package launchd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Job is a launchd job as reported by launchctl list
+type Job struct {
+	Label string
+	// PID is set while the job is running
+	PID *int
+	// LastExitStatus is the status of the job's last exit, if known
+	LastExitStatus *int
+}
+
+// Running reports whether the job has a running process
+func (j Job) Running() bool {
+	return j.PID != nil
+}
+
+// Backend controls launchd jobs
+type Backend interface {
+	// Name identifies the backend in recorded state
+	Name() string
+	// List returns every job loaded in the current session
+	List() ([]Job, error)
+	// Load loads the job defined by a plist file
+	Load(plistPath string) error
+	// Unload unloads the job defined by a plist file
+	Unload(plistPath string) error
+	// Start starts a loaded job
+	Start(label string) error
+	// Stop stops a running job
+	Stop(label string) error
+	// Runs returns how many times launchd has started a loaded job
+	Runs(label string) (int, error)
+}
+
+// Launchctl is a Backend that runs the launchctl command
+type Launchctl struct {
+	// Domain is the launchd domain jobs are inspected in, see Domain
+	Domain string
+	// Output receives launchctl's own output. It defaults to stderr so
+	// stdout only carries command results.
+	Output io.Writer
+}
+
+// NewLaunchctl creates a launchctl backend for jobs in domain, writing its
+// output to stderr
+func NewLaunchctl(domain string) *Launchctl {
+	return &Launchctl{Domain: domain, Output: os.Stderr}
+}
+
+// Name identifies the backend in recorded state
+func (l *Launchctl) Name() string {
+	return "launchctl"
+}
+
+// List returns every job loaded in the current session
+func (l *Launchctl) List() ([]Job, error) {
+	ctx := context.Background()
+	output, err := exec.CommandContext(ctx, "launchctl", "list").Output()
+	if err != nil {
+		return nil, err
+	}
+	return ParseList(output), nil
+}
+
+// Load loads the job defined by a plist file
+func (l *Launchctl) Load(plistPath string) error {
+	return l.run("load", plistPath)
+}
+
+// Unload unloads the job defined by a plist file
+func (l *Launchctl) Unload(plistPath string) error {
+	return l.run("unload", plistPath)
+}
+
+// Start starts a loaded job
+func (l *Launchctl) Start(label string) error {
+	return l.run("start", label)
+}
+
+// Stop stops a running job
+func (l *Launchctl) Stop(label string) error {
+	return l.run("stop", label)
+}
+
+// Runs returns how many times launchd has started a loaded job
+func (l *Launchctl) Runs(label string) (int, error) {
+	ctx := context.Background()
+	output, err := exec.CommandContext(ctx, "launchctl", "print", l.Domain+"/"+label).Output()
+	if err != nil {
+		return 0, err
+	}
+	return ParseRuns(output)
+}
+
+func (l *Launchctl) run(args ...string) error {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, "launchctl", args...)
+	cmd.Stdout = l.Output
+	cmd.Stderr = l.Output
+	return cmd.Run()
+}
+
+// Domain returns the launchd domain jobs are loaded into: the system
+// domain, or the GUI session of the current user
+func Domain(system bool) string {
+	if system {
+		return "system"
+	}
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+// ParseList parses the output of launchctl list. Each line holds the PID
+// ("-" when not running), the last exit status and the label.
+func ParseList(output []byte) []Job {
+	var jobs []Job
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] == "PID" {
+			continue
+		}
+
+		job := Job{Label: fields[2]}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			job.PID = &pid
+		}
+		if status, err := strconv.Atoi(fields[1]); err == nil {
+			job.LastExitStatus = &status
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// ParseRuns reads the run count from the output of launchctl print
+func ParseRuns(output []byte) (int, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " = ")
+		if ok && key == "runs" {
+			return strconv.Atoi(value)
+		}
+	}
+	return 0, errors.New("launchctl print reported no run count")
+}
+
+// Find returns the job with the given label
+func Find(jobs []Job, label string) (Job, bool) {
+	for _, job := range jobs {
+		if job.Label == label {
+			return job, true
+		}
+	}
+	return Job{}, false
+}
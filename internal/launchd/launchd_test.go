@@ -0,0 +1,78 @@
+package launchd
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseList(t *testing.T) {
+	output := []byte(`PID	Status	Label
+123	0	com.example.running
+-	78	com.example.crashed
+-	0	com.example.loaded
+garbage
+`)
+
+	jobs := ParseList(output)
+	require.Len(t, jobs, 3)
+
+	running, ok := Find(jobs, "com.example.running")
+	require.True(t, ok)
+	assert.True(t, running.Running())
+	assert.Equal(t, 123, *running.PID)
+	assert.Equal(t, 0, *running.LastExitStatus)
+
+	crashed, ok := Find(jobs, "com.example.crashed")
+	require.True(t, ok)
+	assert.False(t, crashed.Running())
+	assert.Nil(t, crashed.PID)
+	assert.Equal(t, 78, *crashed.LastExitStatus)
+
+	// Labels are matched exactly, not as substrings
+	_, ok = Find(jobs, "com.example")
+	assert.False(t, ok)
+}
+
+func TestParseList_Empty(t *testing.T) {
+	assert.Empty(t, ParseList(nil))
+}
+
+func TestLaunchctl_List(t *testing.T) {
+	// Skip if launchctl is not available
+	if _, err := exec.LookPath("launchctl"); err != nil {
+		t.Skip("launchctl command not available")
+	}
+
+	_, err := NewLaunchctl(Domain(false)).List()
+	assert.NoError(t, err)
+}
+
+func TestDomain(t *testing.T) {
+	assert.Equal(t, "system", Domain(true))
+	assert.Equal(t, "gui/"+strconv.Itoa(os.Getuid()), Domain(false))
+}
+
+func TestParseRuns(t *testing.T) {
+	output := []byte(`gui/501/com.example.web = {
+	active count = 1
+	path = /Users/me/Library/LaunchAgents/com.example.web.plist
+	state = running
+
+	program = /usr/local/bin/web
+	runs = 7
+	pid = 123
+	last exit code = 1
+}
+`)
+	runs, err := ParseRuns(output)
+	require.NoError(t, err)
+	assert.Equal(t, 7, runs)
+
+	_, err = ParseRuns([]byte("Could not find service \"com.example.web\" in domain for port\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+// Package launchdtest provides a fake launchd backend for tests
+package launchdtest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mjmorales/daemon-control/internal/launchd"
+)
+
+// Backend is a launchd.Backend serving a job list the test sets and
+// changes between calls
+type Backend struct {
+	Jobs []launchd.Job
+	// ListErr is returned by List
+	ListErr error
+	// RunCounts maps labels to the count Runs returns. Runs fails for
+	// labels without one, as it does for jobs launchctl print cannot read.
+	RunCounts map[string]int
+	// FailLoads is how many calls to Load fail before they succeed
+	FailLoads int
+	// Loads counts the calls to Load
+	Loads int
+}
+
+// Name identifies the backend in recorded state
+func (b *Backend) Name() string { return "fake" }
+
+// List returns Jobs, or ListErr if it is set
+func (b *Backend) List() ([]launchd.Job, error) {
+	if b.ListErr != nil {
+		return nil, b.ListErr
+	}
+	return b.Jobs, nil
+}
+
+// Load fails for the first FailLoads calls
+func (b *Backend) Load(plistPath string) error {
+	b.Loads++
+	if b.Loads <= b.FailLoads {
+		return errors.New("load failed")
+	}
+	return nil
+}
+
+// Unload does nothing
+func (b *Backend) Unload(plistPath string) error { return nil }
+
+// Start does nothing
+func (b *Backend) Start(label string) error { return nil }
+
+// Stop does nothing
+func (b *Backend) Stop(label string) error { return nil }
+
+// Runs returns the label's entry in RunCounts
+func (b *Backend) Runs(label string) (int, error) {
+	runs, ok := b.RunCounts[label]
+	if !ok {
+		return 0, fmt.Errorf("no run count for %s", label)
+	}
+	return runs, nil
+}
@@ -0,0 +1,111 @@
+package lint
+
+import (
+	"sort"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+// Severity represents how serious a finding is
+type Severity string
+
+const (
+	// SeverityError marks definitions that will not work as intended
+	SeverityError Severity = "error"
+	// SeverityWarning marks definitions that are likely mistakes
+	SeverityWarning Severity = "warning"
+	// SeverityInfo marks definitions worth a second look
+	SeverityInfo Severity = "info"
+)
+
+// Finding represents a single lint result for a daemon
+type Finding struct {
+	Daemon   string   `yaml:"daemon" json:"daemon"`
+	RuleID   string   `yaml:"rule" json:"rule"`
+	Severity Severity `yaml:"severity" json:"severity"`
+	Message  string   `yaml:"message" json:"message"`
+}
+
+// Rule represents a single lint check
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	Check       func(daemon *config.Daemon) []string
+}
+
+// Rules returns all registered lint rules
+func Rules() []Rule {
+	return rules
+}
+
+// LookupRule returns the rule with the given ID
+func LookupRule(id string) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Lint runs every rule against all daemons
+func Lint(daemons []config.Daemon) []Finding {
+	var findings []Finding
+	for i := range daemons {
+		findings = append(findings, LintDaemon(&daemons[i])...)
+	}
+	return findings
+}
+
+// LintDaemon runs every rule against a single daemon, skipping rules
+// listed in the daemon's lint_ignore
+func LintDaemon(daemon *config.Daemon) []Finding {
+	ignored := make(map[string]bool, len(daemon.LintIgnore))
+	for _, id := range daemon.LintIgnore {
+		ignored[id] = true
+	}
+
+	var findings []Finding
+	for _, rule := range rules {
+		if ignored[rule.ID] {
+			continue
+		}
+		for _, msg := range rule.Check(daemon) {
+			findings = append(findings, Finding{
+				Daemon:   daemon.Name,
+				RuleID:   rule.ID,
+				Severity: rule.Severity,
+				Message:  msg,
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank(findings[i].Severity) < severityRank(findings[j].Severity)
+	})
+
+	return findings
+}
+
+// HasSeverity reports whether any finding is at least as severe as threshold
+func HasSeverity(findings []Finding, threshold Severity) bool {
+	for _, f := range findings {
+		if severityRank(f.Severity) <= severityRank(threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// severityRank orders severities from most to least serious
+func severityRank(s Severity) int {
+	switch s {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
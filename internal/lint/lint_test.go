@@ -0,0 +1,229 @@
+package lint
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+func TestLintDaemon(t *testing.T) {
+	tempDir := t.TempDir()
+
+	executable := filepath.Join(tempDir, "run.sh")
+	require.NoError(t, os.WriteFile(executable, []byte("#!/bin/sh\n"), 0700)) // #nosec G306 - test file
+
+	plainFile := filepath.Join(tempDir, "data.txt")
+	require.NoError(t, os.WriteFile(plainFile, []byte("data"), 0600))
+
+	current, err := user.Current()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		daemon    config.Daemon
+		wantRules []string
+	}{
+		{
+			name: "clean daemon",
+			daemon: config.Daemon{
+				Name:            "clean",
+				Label:           "com.example.clean",
+				Program:         executable,
+				StandardOutPath: filepath.Join(tempDir, "out.log"),
+				UserName:        current.Username,
+				RunAtLoad:       true,
+			},
+			wantRules: nil,
+		},
+		{
+			name: "missing program",
+			daemon: config.Daemon{
+				Name:      "missing",
+				Label:     "com.example.missing",
+				Program:   filepath.Join(tempDir, "does-not-exist"),
+				RunAtLoad: true,
+			},
+			wantRules: []string{"program-executable"},
+		},
+		{
+			name: "program not executable",
+			daemon: config.Daemon{
+				Name:             "noexec",
+				Label:            "com.example.noexec",
+				ProgramArguments: []string{plainFile, "--flag"},
+				RunAtLoad:        true,
+			},
+			wantRules: []string{"program-executable"},
+		},
+		{
+			name: "bare program resolved through PATH",
+			daemon: config.Daemon{
+				Name:                 "bare",
+				Label:                "com.example.bare",
+				ProgramArguments:     []string{"run.sh"},
+				EnvironmentVariables: map[string]string{"PATH": tempDir},
+				RunAtLoad:            true,
+			},
+			wantRules: nil,
+		},
+		{
+			name: "log directory missing",
+			daemon: config.Daemon{
+				Name:              "logs",
+				Label:             "com.example.logs",
+				Program:           executable,
+				StandardErrorPath: filepath.Join(tempDir, "missing", "err.log"),
+				RunAtLoad:         true,
+			},
+			wantRules: []string{"log-path-writable"},
+		},
+		{
+			name: "unknown user and group",
+			daemon: config.Daemon{
+				Name:      "ids",
+				Label:     "com.example.ids",
+				Program:   executable,
+				UserName:  "no-such-user-daemon-control",
+				GroupName: "no-such-group-daemon-control",
+				RunAtLoad: true,
+			},
+			wantRules: []string{"user-exists", "group-exists"},
+		},
+		{
+			name: "start interval with keep alive",
+			daemon: config.Daemon{
+				Name:          "interval",
+				Label:         "com.example.interval",
+				Program:       executable,
+				StartInterval: 60,
+				KeepAlive: &config.KeepAlive{
+					SuccessfulExit: boolPtr(true),
+				},
+			},
+			wantRules: []string{"interval-with-keepalive"},
+		},
+		{
+			name: "no trigger",
+			daemon: config.Daemon{
+				Name:    "idle",
+				Label:   "com.example.idle",
+				Program: executable,
+			},
+			wantRules: []string{"no-trigger"},
+		},
+		{
+			name: "label not reverse dns",
+			daemon: config.Daemon{
+				Name:      "label",
+				Label:     "my-daemon",
+				Program:   executable,
+				RunAtLoad: true,
+			},
+			wantRules: []string{"label-reverse-dns"},
+		},
+		{
+			name: "conflicting socket options",
+			daemon: config.Daemon{
+				Name:    "socket",
+				Label:   "com.example.socket",
+				Program: executable,
+				Sockets: map[string]config.Socket{
+					"listener": {
+						SockType:        "stream",
+						SockProtocol:    "UDP",
+						SockPathName:    "/tmp/socket.sock",
+						SockServiceName: "8080",
+					},
+				},
+			},
+			wantRules: []string{"socket-conflict", "socket-conflict"},
+		},
+		{
+			name: "nice out of range",
+			daemon: config.Daemon{
+				Name:      "nice",
+				Label:     "com.example.nice",
+				Program:   executable,
+				Nice:      intPtr(25),
+				RunAtLoad: true,
+			},
+			wantRules: []string{"nice-range"},
+		},
+		{
+			name: "suppressed rules",
+			daemon: config.Daemon{
+				Name:       "suppressed",
+				Label:      "suppressed",
+				Program:    executable,
+				LintIgnore: []string{"label-reverse-dns", "no-trigger"},
+			},
+			wantRules: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := LintDaemon(&tt.daemon)
+
+			var gotRules []string
+			for _, f := range findings {
+				assert.Equal(t, tt.daemon.Name, f.Daemon)
+				assert.NotEmpty(t, f.Message)
+				gotRules = append(gotRules, f.RuleID)
+			}
+			assert.ElementsMatch(t, tt.wantRules, gotRules)
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	daemons := []config.Daemon{
+		{Name: "a", Label: "bad label", Program: "/nonexistent/program", RunAtLoad: true},
+		{Name: "b", Label: "com.example.b", Program: "/nonexistent/program", Nice: intPtr(-30), RunAtLoad: true},
+	}
+
+	findings := Lint(daemons)
+	require.NotEmpty(t, findings)
+
+	// Errors are reported before warnings for each daemon
+	assert.Equal(t, "a", findings[0].Daemon)
+	assert.Equal(t, SeverityError, findings[0].Severity)
+	assert.Equal(t, "label-reverse-dns", findings[1].RuleID)
+
+	assert.True(t, HasSeverity(findings, SeverityError))
+}
+
+func TestHasSeverity(t *testing.T) {
+	warnings := []Finding{{RuleID: "no-trigger", Severity: SeverityWarning}}
+
+	assert.False(t, HasSeverity(warnings, SeverityError))
+	assert.True(t, HasSeverity(warnings, SeverityWarning))
+	assert.True(t, HasSeverity(warnings, SeverityInfo))
+	assert.False(t, HasSeverity(nil, SeverityInfo))
+}
+
+func TestLookupRule(t *testing.T) {
+	for _, rule := range Rules() {
+		got, ok := LookupRule(rule.ID)
+		assert.True(t, ok)
+		assert.Equal(t, rule.ID, got.ID)
+		assert.NotEmpty(t, got.Description)
+	}
+
+	_, ok := LookupRule("does-not-exist")
+	assert.False(t, ok)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
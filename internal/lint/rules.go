@@ -0,0 +1,276 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+// launchdDefaultPath is the PATH launchd uses to resolve non-absolute programs
+const launchdDefaultPath = "/usr/bin:/bin:/usr/sbin:/sbin"
+
+// reverseDNSPattern matches labels such as com.example.my-daemon
+var reverseDNSPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*(\.[A-Za-z0-9][A-Za-z0-9_-]*)+$`)
+
+var rules = []Rule{
+	{
+		ID:          "program-executable",
+		Severity:    SeverityError,
+		Description: "Program or ProgramArguments[0] must exist and be executable",
+		Check:       checkProgramExecutable,
+	},
+	{
+		ID:          "log-path-writable",
+		Severity:    SeverityError,
+		Description: "Parent directories of StandardOutPath and StandardErrorPath must exist and be writable",
+		Check:       checkLogPathWritable,
+	},
+	{
+		ID:          "user-exists",
+		Severity:    SeverityError,
+		Description: "UserName must name an existing user",
+		Check:       checkUserExists,
+	},
+	{
+		ID:          "group-exists",
+		Severity:    SeverityError,
+		Description: "GroupName must name an existing group",
+		Check:       checkGroupExists,
+	},
+	{
+		ID:          "interval-with-keepalive",
+		Severity:    SeverityWarning,
+		Description: "StartInterval has no effect on a job that launchd keeps alive after every exit",
+		Check:       checkIntervalWithKeepAlive,
+	},
+	{
+		ID:          "no-trigger",
+		Severity:    SeverityWarning,
+		Description: "A job without RunAtLoad needs at least one launch trigger",
+		Check:       checkNoTrigger,
+	},
+	{
+		ID:          "label-reverse-dns",
+		Severity:    SeverityWarning,
+		Description: "Labels should use reverse-DNS notation",
+		Check:       checkLabelReverseDNS,
+	},
+	{
+		ID:          "socket-conflict",
+		Severity:    SeverityError,
+		Description: "Socket options must not contradict each other",
+		Check:       checkSocketConflicts,
+	},
+	{
+		ID:          "nice-range",
+		Severity:    SeverityError,
+		Description: "Nice must be between -20 and 20",
+		Check:       checkNiceRange,
+	},
+}
+
+// checkProgramExecutable verifies the program launchd will exec
+func checkProgramExecutable(daemon *config.Daemon) []string {
+	program := daemon.Program
+	if len(daemon.ProgramArguments) > 0 {
+		program = daemon.ProgramArguments[0]
+	}
+	if program == "" {
+		return nil
+	}
+
+	// launchd resolves bare program names using the job's PATH
+	if !strings.Contains(program, "/") {
+		searchPath := daemon.EnvironmentVariables["PATH"]
+		if searchPath == "" {
+			searchPath = launchdDefaultPath
+		}
+		for _, dir := range filepath.SplitList(searchPath) {
+			if isExecutableFile(filepath.Join(dir, program)) {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("program %q not found in PATH %s", program, searchPath)}
+	}
+
+	if !filepath.IsAbs(program) {
+		program = filepath.Join(daemon.WorkingDirectory, program)
+	}
+
+	info, err := os.Stat(program)
+	if err != nil {
+		return []string{fmt.Sprintf("program %s does not exist", program)}
+	}
+	if info.IsDir() {
+		return []string{fmt.Sprintf("program %s is a directory", program)}
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		return []string{fmt.Sprintf("program %s is not executable", program)}
+	}
+
+	return nil
+}
+
+// checkLogPathWritable verifies log destinations can be created
+func checkLogPathWritable(daemon *config.Daemon) []string {
+	var messages []string
+
+	for _, logPath := range []struct {
+		key  string
+		path string
+	}{
+		{"standard_out_path", daemon.StandardOutPath},
+		{"standard_error_path", daemon.StandardErrorPath},
+	} {
+		if logPath.path == "" {
+			continue
+		}
+
+		dir := filepath.Dir(logPath.path)
+		info, err := os.Stat(dir)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s directory %s does not exist", logPath.key, dir))
+			continue
+		}
+		if !info.IsDir() {
+			messages = append(messages, fmt.Sprintf("%s parent %s is not a directory", logPath.key, dir))
+			continue
+		}
+		if err := unix.Access(dir, unix.W_OK); err != nil {
+			messages = append(messages, fmt.Sprintf("%s directory %s is not writable", logPath.key, dir))
+		}
+	}
+
+	return messages
+}
+
+// checkUserExists verifies UserName resolves to a user
+func checkUserExists(daemon *config.Daemon) []string {
+	if daemon.UserName == "" {
+		return nil
+	}
+	if _, err := user.Lookup(daemon.UserName); err != nil {
+		return []string{fmt.Sprintf("user %q does not exist", daemon.UserName)}
+	}
+	return nil
+}
+
+// checkGroupExists verifies GroupName resolves to a group
+func checkGroupExists(daemon *config.Daemon) []string {
+	if daemon.GroupName == "" {
+		return nil
+	}
+	if _, err := user.LookupGroup(daemon.GroupName); err != nil {
+		return []string{fmt.Sprintf("group %q does not exist", daemon.GroupName)}
+	}
+	return nil
+}
+
+// checkIntervalWithKeepAlive flags periodic jobs that launchd restarts
+// after every successful exit anyway
+func checkIntervalWithKeepAlive(daemon *config.Daemon) []string {
+	if daemon.StartInterval <= 0 || !keepAliveAlwaysOn(daemon.KeepAlive) {
+		return nil
+	}
+	return []string{fmt.Sprintf("start_interval %d is ignored because keep_alive restarts the job after every exit", daemon.StartInterval)}
+}
+
+// keepAliveAlwaysOn reports whether launchd restarts the job after a clean exit
+func keepAliveAlwaysOn(keepAlive *config.KeepAlive) bool {
+	if keepAlive == nil {
+		return false
+	}
+	if keepAlive.Always != nil {
+		return *keepAlive.Always
+	}
+	return keepAlive.SuccessfulExit != nil && *keepAlive.SuccessfulExit
+}
+
+// checkNoTrigger flags jobs that launchd will never start on its own
+func checkNoTrigger(daemon *config.Daemon) []string {
+	if daemon.RunAtLoad ||
+		daemon.StartInterval > 0 ||
+		len(daemon.StartCalendarInterval) > 0 ||
+		len(daemon.WatchPaths) > 0 ||
+		len(daemon.QueuePaths) > 0 ||
+		len(daemon.Sockets) > 0 ||
+		len(daemon.MachServices) > 0 ||
+		len(daemon.LaunchEvents) > 0 ||
+		daemon.StartOnMount ||
+		daemon.KeptAlive() {
+		return nil
+	}
+	return []string{"run_at_load is false and no start_interval, start_calendar_interval, watch_paths, queue_paths, sockets, mach_services, launch_events, start_on_mount or keep_alive is set; the job will only start manually"}
+}
+
+// checkLabelReverseDNS flags labels that are not reverse-DNS
+func checkLabelReverseDNS(daemon *config.Daemon) []string {
+	if daemon.Label == "" || reverseDNSPattern.MatchString(daemon.Label) {
+		return nil
+	}
+	return []string{fmt.Sprintf("label %q is not in reverse-DNS form (e.g. com.example.%s)", daemon.Label, daemon.Name)}
+}
+
+// checkSocketConflicts flags contradictory socket settings
+func checkSocketConflicts(daemon *config.Daemon) []string {
+	names := make([]string, 0, len(daemon.Sockets))
+	for name := range daemon.Sockets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var messages []string
+	for _, name := range names {
+		socket := daemon.Sockets[name]
+		isUnix := socket.SockPathName != "" || strings.EqualFold(socket.SockFamily, "Unix")
+
+		if isUnix && (socket.SockNodeName != "" || socket.SockServiceName != "") {
+			messages = append(messages, fmt.Sprintf("socket %s mixes a Unix domain path with sock_node_name/sock_service_name", name))
+		}
+		if socket.SockPathName != "" && socket.SockFamily != "" && !strings.EqualFold(socket.SockFamily, "Unix") {
+			messages = append(messages, fmt.Sprintf("socket %s sets sock_path_name with sock_family %s", name, socket.SockFamily))
+		}
+		if socket.SockPathMode != nil && socket.SockPathName == "" {
+			messages = append(messages, fmt.Sprintf("socket %s sets sock_path_mode without sock_path_name", name))
+		}
+		if strings.EqualFold(socket.SockProtocol, "UDP") && strings.EqualFold(socket.SockType, "stream") {
+			messages = append(messages, fmt.Sprintf("socket %s uses sock_protocol UDP with sock_type stream", name))
+		}
+		if strings.EqualFold(socket.SockProtocol, "TCP") && strings.EqualFold(socket.SockType, "dgram") {
+			messages = append(messages, fmt.Sprintf("socket %s uses sock_protocol TCP with sock_type dgram", name))
+		}
+		if socket.Bonjour != nil && len(socket.BonjourMultiple) > 0 {
+			messages = append(messages, fmt.Sprintf("socket %s sets both bonjour and bonjour_multiple", name))
+		}
+	}
+
+	return messages
+}
+
+// checkNiceRange verifies Nice is within the range accepted by setpriority(2)
+func checkNiceRange(daemon *config.Daemon) []string {
+	if daemon.Nice == nil {
+		return nil
+	}
+	if *daemon.Nice < -20 || *daemon.Nice > 20 {
+		return []string{fmt.Sprintf("nice %d is outside -20..20", *daemon.Nice)}
+	}
+	return nil
+}
+
+// isExecutableFile reports whether path is a regular file with an execute bit
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return info.Mode().Perm()&0111 != 0
+}
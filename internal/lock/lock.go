@@ -0,0 +1,128 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrLocked means another process holds the lock
+var ErrLocked = errors.New("another daemon-control process is running")
+
+// pollInterval is how often a waiting Acquire retries the lock
+var pollInterval = 100 * time.Millisecond
+
+// Lock is an advisory lock on a file, held with flock(2). The kernel
+// releases it when the process exits, so a crash never leaves it stuck.
+type Lock struct {
+	file *os.File
+}
+
+// Options control how Acquire waits for a lock held by another process
+type Options struct {
+	// Wait retries until the lock is free instead of failing at once
+	Wait bool
+	// Timeout bounds the wait; zero waits indefinitely
+	Timeout time.Duration
+	// OnWait is called once with the holder's PID when Acquire has to
+	// wait. The PID is 0 if it is unknown.
+	OnWait func(pid int)
+}
+
+// Acquire takes the exclusive lock on path, creating the file if needed.
+// The holder's PID is written to the file so that others can name it.
+func Acquire(path string, opts Options) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600) // #nosec G304 - path is under the config directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
+	waiting := false
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		pid := holder(file)
+		if !opts.Wait || (!deadline.IsZero() && time.Now().After(deadline)) {
+			file.Close()
+			return nil, heldError(path, pid, opts.Wait)
+		}
+		if !waiting && opts.OnWait != nil {
+			opts.OnWait(pid)
+		}
+		waiting = true
+		time.Sleep(pollInterval)
+	}
+
+	if err := writePID(file); err != nil {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Release gives up the lock. The lock file stays in place for the next
+// holder.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	_ = l.file.Truncate(0)
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// holder returns the PID recorded in the lock file, or 0 if unknown
+func holder(file *os.File) int {
+	data := make([]byte, 32)
+	n, _ := file.ReadAt(data, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+func writePID(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func heldError(path string, pid int, waited bool) error {
+	holder := "another process"
+	if pid > 0 {
+		holder = fmt.Sprintf("PID %d", pid)
+	}
+	if waited {
+		return fmt.Errorf("%w: timed out waiting for %s to release %s", ErrLocked, holder, path)
+	}
+	return fmt.Errorf("%w: %s holds %s, retry with --wait to wait for it", ErrLocked, holder, path)
+}
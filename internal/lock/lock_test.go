@@ -0,0 +1,79 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "daemon-control.lock")
+
+	l, err := Acquire(path, Options{})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(data))
+
+	// flock locks belong to the open file, so a second Acquire in the
+	// same process conflicts
+	_, err = Acquire(path, Options{})
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.ErrorContains(t, err, "PID "+strconv.Itoa(os.Getpid()))
+	assert.ErrorContains(t, err, "--wait")
+
+	require.NoError(t, l.Release())
+	require.NoError(t, l.Release(), "releasing twice is harmless")
+
+	l, err = Acquire(path, Options{})
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
+
+func TestAcquire_Wait(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon-control.lock")
+
+	held, err := Acquire(path, Options{})
+	require.NoError(t, err)
+
+	var waitedFor int
+	done := make(chan error)
+	go func() {
+		l, err := Acquire(path, Options{Wait: true, OnWait: func(pid int) { waitedFor = pid }})
+		if err == nil {
+			err = l.Release()
+		}
+		done <- err
+	}()
+
+	time.Sleep(3 * pollInterval)
+	require.NoError(t, held.Release())
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Acquire did not return after the lock was released")
+	}
+	assert.Equal(t, os.Getpid(), waitedFor)
+}
+
+func TestAcquire_Timeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon-control.lock")
+
+	held, err := Acquire(path, Options{})
+	require.NoError(t, err)
+	defer held.Release()
+
+	start := time.Now()
+	_, err = Acquire(path, Options{Wait: true, Timeout: 300 * time.Millisecond})
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.ErrorContains(t, err, "timed out")
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+}
@@ -0,0 +1,315 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/health"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+)
+
+// Sample is the state of one daemon at a collection
+type Sample struct {
+	Daemon         string
+	Label          string
+	Tags           []string
+	Loaded         bool
+	PID            *int
+	LastExitStatus *int
+	// Restarts counts the times launchd restarted a keep_alive daemon after
+	// a failed exit since the collector started
+	Restarts int
+	// Process is nil when the daemon is not running or ps failed
+	Process *Process
+	// Healthy is nil when the daemon has no health check
+	Healthy *bool
+	// LogSizes maps "stdout" and "stderr" to the size of existing log files
+	LogSizes map[string]int64
+}
+
+// Collector samples the configured daemons on an interval and serves the
+// latest samples in the Prometheus text format
+type Collector struct {
+	daemons func() ([]config.Daemon, error)
+	backend launchd.Backend
+	inspect func(ctx context.Context, pid int) (*Process, error)
+	check   func(ctx context.Context, check *config.HealthCheck) error
+	now     func() time.Time
+
+	mu          sync.Mutex
+	last        map[string]launchd.Sighting
+	restarts    map[string]int
+	samples     []Sample
+	collectedAt time.Time
+	errors      int
+}
+
+// NewCollector creates a collector for the daemons returned by daemons,
+// which is called at every collection so configuration changes are seen
+func NewCollector(daemons func() ([]config.Daemon, error), backend launchd.Backend) *Collector {
+	return &Collector{
+		daemons:  daemons,
+		backend:  backend,
+		inspect:  Inspect,
+		check:    health.Check,
+		now:      time.Now,
+		last:     map[string]launchd.Sighting{},
+		restarts: map[string]int{},
+	}
+}
+
+// Run collects immediately and then every interval until ctx is done.
+// Failed collections are logged and counted; the previous samples are
+// served until the next collection succeeds.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Collect(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to collect daemon metrics")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect samples every daemon once
+func (c *Collector) Collect(ctx context.Context) error {
+	daemons, err := c.daemons()
+	if err != nil {
+		c.failed()
+		return err
+	}
+	jobs, err := c.backend.List()
+	if err != nil {
+		c.failed()
+		return fmt.Errorf("launchctl list: %w", err)
+	}
+
+	samples := make([]Sample, 0, len(daemons))
+	seen := make([]launchd.Sighting, 0, len(daemons))
+	for i := range daemons {
+		job, loaded := launchd.Find(jobs, daemons[i].Label)
+		samples = append(samples, c.sample(ctx, &daemons[i], job, loaded))
+		seen = append(seen, launchd.Observe(c.backend, job, loaded))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range samples {
+		c.countRestarts(&samples[i], &daemons[i], seen[i])
+	}
+	c.samples = samples
+	c.collectedAt = c.now()
+	return nil
+}
+
+// sample reads one daemon's state
+func (c *Collector) sample(ctx context.Context, daemon *config.Daemon, job launchd.Job, loaded bool) Sample {
+	s := Sample{
+		Daemon:         daemon.Name,
+		Label:          daemon.Label,
+		Tags:           daemon.Tags,
+		Loaded:         loaded,
+		PID:            job.PID,
+		LastExitStatus: job.LastExitStatus,
+		LogSizes:       map[string]int64{},
+	}
+
+	if job.Running() {
+		process, err := c.inspect(ctx, *job.PID)
+		if err != nil {
+			log.Debug().Err(err).Str("daemon", daemon.Name).Msg("Failed to read process usage")
+		}
+		s.Process = process
+	}
+
+	if daemon.HealthCheck != nil {
+		healthy := false
+		if job.Running() {
+			err := c.check(ctx, daemon.HealthCheck)
+			if err != nil {
+				log.Debug().Err(err).Str("daemon", daemon.Name).Msg("Health check failed")
+			}
+			healthy = err == nil
+		}
+		s.Healthy = &healthy
+	}
+
+	for stream, path := range map[string]string{"stdout": daemon.StandardOutPath, "stderr": daemon.StandardErrorPath} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			s.LogSizes[stream] = info.Size()
+		}
+	}
+	return s
+}
+
+// countRestarts counts the exits since the last collection that launchd
+// restarts the daemon after: failed exits of a keep_alive daemon. Clean
+// exits and the runs of scheduled daemons are not restarts. The caller
+// holds c.mu.
+func (c *Collector) countRestarts(s *Sample, daemon *config.Daemon, seen launchd.Sighting) {
+	if last, ok := c.last[s.Daemon]; ok && daemon.KeptAlive() && launchd.Crashed(s.LastExitStatus) {
+		c.restarts[s.Daemon] += seen.ExitsSince(last)
+	}
+	c.last[s.Daemon] = seen
+	s.Restarts = c.restarts[s.Daemon]
+}
+
+func (c *Collector) failed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors++
+}
+
+// Samples returns the latest samples
+func (c *Collector) Samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Sample(nil), c.samples...)
+}
+
+// ServeHTTP serves the latest samples in the Prometheus text format
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := c.Write(w); err != nil {
+		log.Warn().Err(err).Msg("Failed to write metrics")
+	}
+}
+
+// Write writes the latest samples in the Prometheus text format
+func (c *Collector) Write(w io.Writer) error {
+	c.mu.Lock()
+	samples := append([]Sample(nil), c.samples...)
+	collectedAt := c.collectedAt
+	errors := c.errors
+	c.mu.Unlock()
+
+	up := newFamily("daemon_control_up", "gauge", "Whether the daemon's process is running.")
+	loaded := newFamily("daemon_control_loaded", "gauge", "Whether the daemon is loaded into launchd.")
+	pid := newFamily("daemon_control_pid", "gauge", "Process ID of the running daemon.")
+	restarts := newFamily("daemon_control_restarts_total", "counter", "Times launchd restarted the keep_alive daemon after a failed exit since the exporter started.")
+	lastExit := newFamily("daemon_control_last_exit_code", "gauge", "Exit status of the daemon's last exit, as reported by launchd.")
+	uptime := newFamily("daemon_control_uptime_seconds", "gauge", "Seconds since the daemon's process started.")
+	healthy := newFamily("daemon_control_health_check_up", "gauge", "Whether the daemon's health check passed.")
+	rss := newFamily("daemon_control_resident_memory_bytes", "gauge", "Resident memory of the daemon's process.")
+	cpu := newFamily("daemon_control_cpu_seconds_total", "counter", "CPU time used by the daemon's process.")
+	logSize := newFamily("daemon_control_log_size_bytes", "gauge", "Size of the daemon's log file.")
+	collected := newFamily("daemon_control_last_collect_timestamp_seconds", "gauge", "Time of the last successful collection.")
+	failures := newFamily("daemon_control_collect_errors_total", "counter", "Collections that failed.")
+
+	for _, s := range samples {
+		labels := daemonLabels(s)
+		up.add(labels, boolValue(s.PID != nil))
+		loaded.add(labels, boolValue(s.Loaded))
+		restarts.add(labels, float64(s.Restarts))
+		if s.PID != nil {
+			pid.add(labels, float64(*s.PID))
+		}
+		if s.LastExitStatus != nil {
+			lastExit.add(labels, float64(*s.LastExitStatus))
+		}
+		if s.Process != nil {
+			uptime.add(labels, s.Process.Uptime.Seconds())
+			rss.add(labels, float64(s.Process.RSSBytes))
+			cpu.add(labels, s.Process.CPUSeconds)
+		}
+		if s.Healthy != nil {
+			healthy.add(labels, boolValue(*s.Healthy))
+		}
+		for _, stream := range sortedStreams(s.LogSizes) {
+			logSize.add(labels+`,stream="`+escape(stream)+`"`, float64(s.LogSizes[stream]))
+		}
+	}
+
+	if !collectedAt.IsZero() {
+		collected.add("", float64(collectedAt.UnixNano())/1e9)
+	}
+	failures.add("", float64(errors))
+
+	var buf bytes.Buffer
+	for _, f := range []*family{up, loaded, pid, restarts, lastExit, uptime, healthy, rss, cpu, logSize, collected, failures} {
+		f.write(&buf)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// family is a metric and its samples
+type family struct {
+	name   string
+	help   string
+	kind   string
+	points []point
+}
+
+func newFamily(name, kind, help string) *family {
+	return &family{name: name, kind: kind, help: help}
+}
+
+type point struct {
+	labels string
+	value  float64
+}
+
+func (f *family) add(labels string, value float64) {
+	f.points = append(f.points, point{labels: labels, value: value})
+}
+
+func (f *family) write(buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	for _, p := range f.points {
+		buf.WriteString(f.name)
+		if p.labels != "" {
+			buf.WriteString("{" + p.labels + "}")
+		}
+		buf.WriteString(" " + strconv.FormatFloat(p.value, 'g', -1, 64) + "\n")
+	}
+}
+
+// daemonLabels returns the labels identifying a daemon's samples. Tags are
+// sorted and joined with commas, so they can be matched with a regex.
+func daemonLabels(s Sample) string {
+	tags := append([]string(nil), s.Tags...)
+	sort.Strings(tags)
+	return fmt.Sprintf(`daemon="%s",label="%s",tags="%s"`, escape(s.Daemon), escape(s.Label), escape(strings.Join(tags, ",")))
+}
+
+// escape escapes a label value for the text format
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedStreams(sizes map[string]int64) []string {
+	streams := make([]string, 0, len(sizes))
+	for stream := range sizes {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	return streams
+}
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+	"github.com/mjmorales/daemon-control/internal/launchd/launchdtest"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// newTestCollector returns a collector whose process and health checks
+// are faked: every process uses 1MiB and 2.5s of CPU for a minute, and
+// health checks fail for daemons whose check command is "fail"
+func newTestCollector(daemons []config.Daemon, backend *launchdtest.Backend) *Collector {
+	c := NewCollector(func() ([]config.Daemon, error) { return daemons, nil }, backend)
+	c.inspect = func(ctx context.Context, pid int) (*Process, error) {
+		return &Process{RSSBytes: 1 << 20, CPUSeconds: 2.5, Uptime: time.Minute}, nil
+	}
+	c.check = func(ctx context.Context, check *config.HealthCheck) error {
+		if check.Command[0] == "fail" {
+			return errors.New("unhealthy")
+		}
+		return nil
+	}
+	c.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return c
+}
+
+func TestCollector_Write(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "web.log")
+	require.NoError(t, os.WriteFile(logFile, []byte("hello\n"), 0600))
+
+	daemons := []config.Daemon{
+		{
+			Name:            "web",
+			Label:           "com.example.web",
+			Tags:            []string{"prod", "api"},
+			StandardOutPath: logFile,
+			HealthCheck:     &config.HealthCheck{Command: []string{"ok"}},
+		},
+		{
+			Name:        "worker",
+			Label:       "com.example.worker",
+			HealthCheck: &config.HealthCheck{Command: []string{"fail"}},
+		},
+		{Name: "cron", Label: "com.example.cron"},
+	}
+	backend := &launchdtest.Backend{Jobs: []launchd.Job{
+		{Label: "com.example.web", PID: intPtr(42), LastExitStatus: intPtr(0)},
+		{Label: "com.example.worker", LastExitStatus: intPtr(78)},
+	}}
+	c := newTestCollector(daemons, backend)
+	require.NoError(t, c.Collect(context.Background()))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	out := buf.String()
+
+	web := `{daemon="web",label="com.example.web",tags="api,prod"}`
+	worker := `{daemon="worker",label="com.example.worker",tags=""}`
+	cron := `{daemon="cron",label="com.example.cron",tags=""}`
+	for _, line := range []string{
+		"# TYPE daemon_control_up gauge",
+		"daemon_control_up" + web + " 1",
+		"daemon_control_up" + worker + " 0",
+		"daemon_control_loaded" + worker + " 1",
+		"daemon_control_loaded" + cron + " 0",
+		"daemon_control_pid" + web + " 42",
+		"daemon_control_last_exit_code" + worker + " 78",
+		"daemon_control_uptime_seconds" + web + " 60",
+		"daemon_control_resident_memory_bytes" + web + " 1.048576e+06",
+		"daemon_control_cpu_seconds_total" + web + " 2.5",
+		"daemon_control_health_check_up" + web + " 1",
+		"daemon_control_health_check_up" + worker + " 0",
+		`daemon_control_log_size_bytes{daemon="web",label="com.example.web",tags="api,prod",stream="stdout"} 6`,
+		"# TYPE daemon_control_restarts_total counter",
+		"daemon_control_restarts_total" + web + " 0",
+		"daemon_control_last_collect_timestamp_seconds 1.7e+09",
+		"daemon_control_collect_errors_total 0",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.NotContains(t, out, "daemon_control_pid"+worker, "stopped daemons have no PID")
+	assert.NotContains(t, out, "daemon_control_health_check_up"+cron, "daemons without a health check have no health metric")
+}
+
+func TestCollector_Restarts(t *testing.T) {
+	keepAlive := &config.KeepAlive{Always: boolPtr(true)}
+	job := func(pid *int, status int) launchd.Job {
+		return launchd.Job{Label: "com.example.web", PID: pid, LastExitStatus: intPtr(status)}
+	}
+	tests := []struct {
+		name   string
+		daemon config.Daemon
+		jobs   []launchd.Job
+		runs   []int
+		want   int
+	}{
+		{
+			name:   "failed exits of a kept alive daemon",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", KeepAlive: keepAlive},
+			jobs:   []launchd.Job{job(intPtr(10), 0), job(intPtr(10), 0), job(nil, 1), job(intPtr(11), 1), job(intPtr(12), -9)},
+			want:   2,
+		},
+		{
+			name:   "clean exits of a kept alive daemon",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", KeepAlive: keepAlive},
+			jobs:   []launchd.Job{job(intPtr(10), 0), job(intPtr(11), 0), job(intPtr(12), 0)},
+			want:   0,
+		},
+		{
+			name:   "start_interval daemon",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", StartInterval: 60},
+			jobs:   []launchd.Job{job(intPtr(10), 0), job(nil, 1), job(intPtr(11), 1), job(nil, 0), job(intPtr(12), 0)},
+			want:   0,
+		},
+		{
+			name:   "failing before any collection sees it running",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", KeepAlive: keepAlive},
+			jobs:   []launchd.Job{job(nil, 1), job(nil, 1), job(nil, 1)},
+			runs:   []int{1, 3, 6},
+			want:   5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &launchdtest.Backend{}
+			c := newTestCollector([]config.Daemon{tt.daemon}, backend)
+			for i, j := range tt.jobs {
+				backend.Jobs = []launchd.Job{j}
+				if tt.runs != nil {
+					backend.RunCounts = map[string]int{j.Label: tt.runs[i]}
+				}
+				require.NoError(t, c.Collect(context.Background()))
+			}
+
+			samples := c.Samples()
+			require.Len(t, samples, 1)
+			assert.Equal(t, tt.want, samples[0].Restarts)
+		})
+	}
+}
+
+func TestCollector_Errors(t *testing.T) {
+	daemons := []config.Daemon{{Name: "web", Label: "com.example.web"}}
+	backend := &launchdtest.Backend{Jobs: []launchd.Job{{Label: "com.example.web", PID: intPtr(10)}}}
+	c := newTestCollector(daemons, backend)
+	require.NoError(t, c.Collect(context.Background()))
+
+	backend.ListErr = errors.New("launchctl failed")
+	assert.ErrorContains(t, c.Collect(context.Background()), "launchctl failed")
+
+	// The last good samples are still served
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	assert.Contains(t, rec.Body.String(), `daemon_control_up{daemon="web",label="com.example.web",tags=""} 1`)
+	assert.Contains(t, rec.Body.String(), "daemon_control_collect_errors_total 1\n")
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\"b\\c\nd`, escape("a\"b\\c\nd"))
+}
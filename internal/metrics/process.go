@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Process is resource usage of a running daemon
+type Process struct {
+	RSSBytes   int64
+	CPUSeconds float64
+	Uptime     time.Duration
+}
+
+// Inspect reads a process's resource usage with ps, which reports it the
+// same way on macOS and Linux
+func Inspect(ctx context.Context, pid int) (*Process, error) {
+	output, err := exec.CommandContext(ctx, "ps", "-o", "rss=,time=,etime=", "-p", strconv.Itoa(pid)).Output() // #nosec G204 - arguments are not user input
+	if err != nil {
+		return nil, fmt.Errorf("ps -p %d: %w", pid, err)
+	}
+	return parsePS(output)
+}
+
+// parsePS parses one line of "ps -o rss=,time=,etime=" output
+func parsePS(output []byte) (*Process, error) {
+	fields := strings.Fields(string(output))
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected ps output %q", strings.TrimSpace(string(output)))
+	}
+
+	rss, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rss %q", fields[0])
+	}
+	cpu, err := parseClock(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	uptime, err := parseClock(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	return &Process{
+		RSSBytes:   rss * 1024,
+		CPUSeconds: cpu,
+		Uptime:     time.Duration(uptime * float64(time.Second)),
+	}, nil
+}
+
+// parseClock parses a ps time such as "1-02:03:04", "03:04" or "0:00.25"
+// into seconds
+func parseClock(value string) (float64, error) {
+	var days float64
+	clock := value
+	if d, rest, ok := strings.Cut(value, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+		days, clock = float64(n), rest
+	}
+
+	parts := strings.Split(clock, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	var seconds float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+		seconds = seconds*60 + n
+	}
+	return days*86400 + seconds, nil
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePS(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    *Process
+		wantErr string
+	}{
+		{
+			name:   "macOS",
+			output: " 5120   0:01.50    01:02:03\n",
+			want:   &Process{RSSBytes: 5120 * 1024, CPUSeconds: 1.5, Uptime: time.Hour + 2*time.Minute + 3*time.Second},
+		},
+		{
+			name:   "Linux with days",
+			output: "2048 00:00:02 2-00:00:10\n",
+			want:   &Process{RSSBytes: 2048 * 1024, CPUSeconds: 2, Uptime: 48*time.Hour + 10*time.Second},
+		},
+		{name: "no process", output: "", wantErr: "unexpected ps output"},
+		{name: "bad rss", output: "x 0:00.00 00:01", wantErr: "invalid rss"},
+		{name: "bad time", output: "1 a:b 00:01", wantErr: "invalid time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePS([]byte(tt.output))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	process, err := Inspect(context.Background(), os.Getpid())
+	require.NoError(t, err)
+	assert.Positive(t, process.RSSBytes)
+}
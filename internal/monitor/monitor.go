@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/health"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+	"github.com/mjmorales/daemon-control/internal/notify"
+)
+
+// Delivery is a detected event and the result of sending it
+type Delivery struct {
+	Event notify.Event
+	// Err is set when a sink failed
+	Err error
+}
+
+// Monitor polls launchd for the configured daemons and sends an event when
+// one crashes, flaps or fails its health check
+type Monitor struct {
+	daemons func() ([]config.Daemon, error)
+	backend launchd.Backend
+	global  *config.Notify
+	host    string
+	check   func(ctx context.Context, check *config.HealthCheck) error
+	send    func(ctx context.Context, n *config.Notify, event notify.Event) error
+	now     func() time.Time
+
+	states map[string]*daemonState
+}
+
+// daemonState is what the monitor remembers about a daemon between polls
+type daemonState struct {
+	last launchd.Sighting
+	// restarts holds the times of the restarts within the flapping window
+	restarts []time.Time
+	flapping bool
+	failing  bool
+}
+
+// New creates a monitor for the daemons returned by daemons, which is
+// called at every poll so configuration changes are seen. global holds the
+// core config's notify block, which may be nil.
+func New(daemons func() ([]config.Daemon, error), backend launchd.Backend, global *config.Notify) *Monitor {
+	host, _ := os.Hostname()
+	return &Monitor{
+		daemons: daemons,
+		backend: backend,
+		global:  global,
+		host:    host,
+		check:   health.Check,
+		send:    notify.Send,
+		now:     time.Now,
+		states:  map[string]*daemonState{},
+	}
+}
+
+// Run polls immediately and then every interval until ctx is done,
+// passing every delivery to report. Failed polls are logged.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration, report func(Delivery)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deliveries, err := m.Poll(ctx)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to poll daemons")
+		}
+		for _, d := range deliveries {
+			report(d)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every daemon once and sends the events it detects. The
+// first poll of a daemon only records its state: exits that happened
+// before the monitor started are not reported.
+func (m *Monitor) Poll(ctx context.Context) ([]Delivery, error) {
+	daemons, err := m.daemons()
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := m.backend.List()
+	if err != nil {
+		return nil, fmt.Errorf("launchctl list: %w", err)
+	}
+
+	var deliveries []Delivery
+	seen := map[string]bool{}
+	for i := range daemons {
+		daemon := &daemons[i]
+		n := daemon.EffectiveNotify(m.global)
+		if !n.HasSinks() {
+			continue
+		}
+		seen[daemon.Name] = true
+
+		job, loaded := launchd.Find(jobs, daemon.Label)
+		for _, event := range m.detect(ctx, daemon, n, job, loaded) {
+			if !n.Wants(event.Event) {
+				continue
+			}
+			err := m.send(ctx, n, event)
+			if err != nil {
+				log.Warn().Err(err).Str("daemon", daemon.Name).Str("event", event.Event).Msg("Failed to send notification")
+			}
+			deliveries = append(deliveries, Delivery{Event: event, Err: err})
+		}
+	}
+
+	// Forget daemons that were removed or no longer notify
+	for name := range m.states {
+		if !seen[name] {
+			delete(m.states, name)
+		}
+	}
+	return deliveries, nil
+}
+
+// detect compares a daemon's job with its state at the last poll
+func (m *Monitor) detect(ctx context.Context, daemon *config.Daemon, n *config.Notify, job launchd.Job, loaded bool) []notify.Event {
+	now := m.now()
+	seen := launchd.Observe(m.backend, job, loaded)
+
+	st, known := m.states[daemon.Name]
+	if !known {
+		m.states[daemon.Name] = &daemonState{last: seen}
+		return nil
+	}
+
+	event := func(kind, message string) notify.Event {
+		return notify.Event{
+			Event:   kind,
+			Daemon:  daemon.Name,
+			Label:   daemon.Label,
+			Tags:    daemon.Tags,
+			Time:    now,
+			Message: message,
+			Host:    m.host,
+			PID:     job.PID,
+		}
+	}
+
+	var events []notify.Event
+
+	// launchd only reports the status of the last exit, so several exits
+	// between polls are one crash. An unloaded daemon was stopped or
+	// uninstalled on purpose.
+	exits := seen.ExitsSince(st.last)
+	if exits > 0 && launchd.Crashed(job.LastExitStatus) {
+		e := event(config.EventCrash, crashMessage(*job.LastExitStatus, seen.PID != 0))
+		e.ExitStatus = job.LastExitStatus
+		events = append(events, e)
+
+		// launchd restarts a kept alive daemon after each failed exit.
+		// Scheduled and on-demand daemons are started again by their
+		// trigger, which is not a restart.
+		if daemon.KeptAlive() {
+			for i := 0; i < exits; i++ {
+				st.restarts = append(st.restarts, now)
+			}
+		}
+	}
+
+	restarts, window := n.FlapThreshold()
+	st.restarts = within(st.restarts, now.Add(-window))
+	if len(st.restarts) >= restarts {
+		if !st.flapping {
+			e := event(config.EventFlapping, fmt.Sprintf("restarted %d times in the last %s", len(st.restarts), window))
+			e.Restarts = len(st.restarts)
+			events = append(events, e)
+		}
+		st.flapping = true
+	} else {
+		st.flapping = false
+	}
+
+	// Health is only checked while running; a failing check is reported
+	// once until it passes again
+	if daemon.HealthCheck != nil && seen.PID != 0 && n.Wants(config.EventHealthCheck) {
+		if err := m.check(ctx, daemon.HealthCheck); err != nil {
+			if !st.failing {
+				events = append(events, event(config.EventHealthCheck, "health check failed: "+err.Error()))
+			}
+			st.failing = true
+		} else {
+			st.failing = false
+		}
+	}
+
+	st.last = seen
+	return events
+}
+
+func crashMessage(status int, restarted bool) string {
+	message := fmt.Sprintf("exited with status %d", status)
+	if status < 0 {
+		message = fmt.Sprintf("was killed by signal %d", -status)
+	}
+	if restarted {
+		message += " and was restarted"
+	}
+	return message
+}
+
+// within returns the times after since
+func within(times []time.Time, since time.Time) []time.Time {
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(since) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,293 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/launchd"
+	"github.com/mjmorales/daemon-control/internal/launchd/launchdtest"
+	"github.com/mjmorales/daemon-control/internal/notify"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// testMonitor is a monitor with a fake clock that advances a minute per
+// poll, health checks that fail while unhealthy is set, and sinks that
+// record what was sent
+type testMonitor struct {
+	*Monitor
+	backend   *launchdtest.Backend
+	sent      []notify.Event
+	unhealthy bool
+	sendErr   error
+	clock     time.Time
+}
+
+func newTestMonitor(daemons []config.Daemon, global *config.Notify) *testMonitor {
+	tm := &testMonitor{backend: &launchdtest.Backend{}, clock: time.Unix(1700000000, 0)}
+	tm.Monitor = New(func() ([]config.Daemon, error) { return daemons, nil }, tm.backend, global)
+	tm.host = "test-host"
+	tm.check = func(ctx context.Context, check *config.HealthCheck) error {
+		if tm.unhealthy {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	tm.send = func(ctx context.Context, n *config.Notify, event notify.Event) error {
+		tm.sent = append(tm.sent, event)
+		return tm.sendErr
+	}
+	tm.now = func() time.Time { return tm.clock }
+	return tm
+}
+
+// poll sets the job and polls, returning the events sent
+func (tm *testMonitor) poll(t *testing.T, job launchd.Job) []notify.Event {
+	t.Helper()
+	tm.clock = tm.clock.Add(time.Minute)
+	tm.backend.Jobs = []launchd.Job{job}
+	tm.sent = nil
+	_, err := tm.Poll(context.Background())
+	require.NoError(t, err)
+	return tm.sent
+}
+
+// pollRuns polls with launchd reporting runs starts of the job
+func (tm *testMonitor) pollRuns(t *testing.T, job launchd.Job, runs int) []notify.Event {
+	t.Helper()
+	tm.backend.RunCounts = map[string]int{job.Label: runs}
+	return tm.poll(t, job)
+}
+
+func eventKinds(events []notify.Event) []string {
+	kinds := []string{}
+	for _, e := range events {
+		kinds = append(kinds, e.Event)
+	}
+	return kinds
+}
+
+var keepAlive = &config.KeepAlive{Always: boolPtr(true)}
+
+var webhook = &config.Notify{Webhook: "https://hooks.example.com/daemons"}
+
+func running(pid int) launchd.Job {
+	return launchd.Job{Label: "com.example.web", PID: intPtr(pid), LastExitStatus: intPtr(0)}
+}
+
+func restarted(pid, status int) launchd.Job {
+	return launchd.Job{Label: "com.example.web", PID: intPtr(pid), LastExitStatus: intPtr(status)}
+}
+
+func exited(status int) launchd.Job {
+	return launchd.Job{Label: "com.example.web", LastExitStatus: intPtr(status)}
+}
+
+func TestMonitor_Crash(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  launchd.Job
+		then   launchd.Job
+		want   []string
+		status *int
+	}{
+		{name: "still running", first: running(10), then: running(10), want: []string{}},
+		{name: "exit with failure", first: running(10), then: exited(1), want: []string{config.EventCrash}, status: intPtr(1)},
+		{name: "killed and restarted", first: running(10), then: restarted(11, -9), want: []string{config.EventCrash}, status: intPtr(-9)},
+		{name: "clean exit", first: running(10), then: exited(0), want: []string{}},
+		{name: "stopped by launchd", first: running(10), then: exited(-15), want: []string{}},
+		{name: "unloaded", first: running(10), then: launchd.Job{}, want: []string{}},
+		{name: "failure before monitoring", first: exited(1), then: exited(1), want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := newTestMonitor([]config.Daemon{{Name: "web", Label: "com.example.web", Notify: webhook}}, nil)
+			assert.Empty(t, tm.poll(t, tt.first), "first poll only records state")
+
+			events := tm.poll(t, tt.then)
+			assert.Equal(t, tt.want, eventKinds(events))
+			if tt.status != nil {
+				e := events[0]
+				assert.Equal(t, tt.status, e.ExitStatus)
+				assert.Equal(t, "web", e.Daemon)
+				assert.Equal(t, "com.example.web", e.Label)
+				assert.Equal(t, "test-host", e.Host)
+				assert.Equal(t, tm.clock, e.Time)
+			}
+		})
+	}
+}
+
+func TestMonitor_CrashMessage(t *testing.T) {
+	assert.Equal(t, "exited with status 2", crashMessage(2, false))
+	assert.Equal(t, "was killed by signal 9 and was restarted", crashMessage(-9, true))
+}
+
+func TestMonitor_Flapping(t *testing.T) {
+	daemons := []config.Daemon{{Name: "web", Label: "com.example.web", KeepAlive: keepAlive, Notify: &config.Notify{
+		Command:           []string{"/usr/local/bin/page"},
+		Events:            []string{config.EventFlapping},
+		FlapRestarts:      3,
+		FlapWindowMinutes: 5,
+	}}}
+	tm := newTestMonitor(daemons, nil)
+
+	tm.poll(t, running(10))
+	assert.Empty(t, tm.poll(t, restarted(11, 1)), "crash is filtered out by events")
+	assert.Empty(t, tm.poll(t, exited(1)))
+	assert.Empty(t, tm.poll(t, restarted(12, 1)), "restart after an exit already counted")
+
+	events := tm.poll(t, restarted(13, -9))
+	require.Equal(t, []string{config.EventFlapping}, eventKinds(events))
+	assert.Equal(t, 3, events[0].Restarts)
+	assert.Equal(t, "restarted 3 times in the last 5m0s", events[0].Message)
+
+	assert.Empty(t, tm.poll(t, restarted(14, 1)), "flapping is reported once")
+
+	// Restarts age out of the window, then flapping can be reported again
+	for i := 0; i < 5; i++ {
+		assert.Empty(t, tm.poll(t, restarted(14, 1)))
+	}
+	tm.poll(t, restarted(15, 1))
+	tm.poll(t, restarted(16, 1))
+	assert.Equal(t, []string{config.EventFlapping}, eventKinds(tm.poll(t, restarted(17, 1))))
+}
+
+func TestMonitor_NotRestarts(t *testing.T) {
+	flapping := &config.Notify{Webhook: "https://hooks.example.com/daemons", FlapRestarts: 2}
+	tests := []struct {
+		name   string
+		daemon config.Daemon
+		jobs   []launchd.Job
+		want   []string
+	}{
+		{
+			name:   "start_interval daemon exits cleanly",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", StartInterval: 60, Notify: flapping},
+			jobs:   []launchd.Job{running(10), exited(0), running(11), exited(0), running(12), running(13)},
+		},
+		{
+			name:   "calendar daemon exits cleanly",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", StartCalendarInterval: []config.CalendarInterval{{Minute: intPtr(0)}}, Notify: flapping},
+			jobs:   []launchd.Job{running(10), exited(0), running(11), exited(0), running(12)},
+		},
+		{
+			name:   "kept alive daemon exits cleanly",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", KeepAlive: keepAlive, Notify: flapping},
+			jobs:   []launchd.Job{running(10), running(11), running(12), running(13)},
+		},
+		{
+			name:   "kept alive daemon stopped by launchd",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", KeepAlive: keepAlive, Notify: flapping},
+			jobs:   []launchd.Job{running(10), restarted(11, -15), restarted(12, -15)},
+		},
+		{
+			name:   "start_interval daemon fails each run",
+			daemon: config.Daemon{Name: "web", Label: "com.example.web", StartInterval: 60, Notify: flapping},
+			jobs:   []launchd.Job{running(10), exited(1), running(11), exited(2)},
+			want:   []string{config.EventCrash, config.EventCrash},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := newTestMonitor([]config.Daemon{tt.daemon}, nil)
+			got := []string{}
+			for _, job := range tt.jobs {
+				got = append(got, eventKinds(tm.poll(t, job))...)
+			}
+			assert.Equal(t, append([]string{}, tt.want...), got)
+		})
+	}
+}
+
+func TestMonitor_RunCounts(t *testing.T) {
+	// The daemon crashes right after each launch, so no poll sees it running
+	daemons := []config.Daemon{{Name: "web", Label: "com.example.web", KeepAlive: keepAlive, Notify: &config.Notify{
+		Webhook:      "https://hooks.example.com/daemons",
+		FlapRestarts: 3,
+	}}}
+	tm := newTestMonitor(daemons, nil)
+
+	assert.Empty(t, tm.pollRuns(t, exited(1), 1))
+	assert.Empty(t, tm.pollRuns(t, exited(1), 1), "no new runs")
+
+	events := tm.pollRuns(t, exited(1), 2)
+	require.Equal(t, []string{config.EventCrash}, eventKinds(events))
+	assert.Equal(t, "exited with status 1", events[0].Message)
+
+	events = tm.pollRuns(t, exited(1), 4)
+	require.Equal(t, []string{config.EventCrash, config.EventFlapping}, eventKinds(events))
+	assert.Equal(t, 3, events[1].Restarts)
+
+	// A launch that is still running has not exited yet
+	assert.Empty(t, tm.pollRuns(t, restarted(20, 1), 5))
+	assert.Equal(t, []string{config.EventCrash}, eventKinds(tm.pollRuns(t, exited(1), 5)))
+}
+
+func TestMonitor_HealthCheck(t *testing.T) {
+	daemons := []config.Daemon{{
+		Name:        "web",
+		Label:       "com.example.web",
+		HealthCheck: &config.HealthCheck{HTTP: "http://127.0.0.1:8080/healthz"},
+	}}
+	tm := newTestMonitor(daemons, &config.Notify{Desktop: boolPtr(true)})
+
+	tm.poll(t, running(10))
+	assert.Empty(t, tm.poll(t, running(10)))
+
+	tm.unhealthy = true
+	events := tm.poll(t, running(10))
+	require.Equal(t, []string{config.EventHealthCheck}, eventKinds(events))
+	assert.Equal(t, "health check failed: connection refused", events[0].Message)
+	assert.Equal(t, intPtr(10), events[0].PID)
+
+	assert.Empty(t, tm.poll(t, running(10)), "a failing check is reported once")
+	assert.Empty(t, tm.poll(t, exited(0)), "stopped daemons are not checked")
+
+	tm.unhealthy = false
+	assert.Empty(t, tm.poll(t, running(11)))
+	tm.unhealthy = true
+	assert.Equal(t, []string{config.EventHealthCheck}, eventKinds(tm.poll(t, running(11))))
+}
+
+func TestMonitor_Sinks(t *testing.T) {
+	daemons := []config.Daemon{
+		{Name: "web", Label: "com.example.web"},
+		{Name: "quiet", Label: "com.example.quiet", Notify: &config.Notify{Desktop: boolPtr(false)}},
+	}
+	tm := newTestMonitor(daemons, &config.Notify{Desktop: boolPtr(true)})
+	tm.sendErr = errors.New("osascript failed")
+
+	tm.backend.Jobs = []launchd.Job{running(10), {Label: "com.example.quiet", PID: intPtr(20)}}
+	_, err := tm.Poll(context.Background())
+	require.NoError(t, err)
+	assert.NotContains(t, tm.states, "quiet", "daemons without sinks are not tracked")
+
+	tm.backend.Jobs = []launchd.Job{exited(1), {Label: "com.example.quiet", LastExitStatus: intPtr(1)}}
+	deliveries, err := tm.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "web", deliveries[0].Event.Daemon)
+	assert.EqualError(t, deliveries[0].Err, "osascript failed")
+}
+
+func TestMonitor_PollError(t *testing.T) {
+	tm := newTestMonitor(nil, webhook)
+	tm.backend.ListErr = errors.New("launchctl not found")
+	_, err := tm.Poll(context.Background())
+	assert.ErrorContains(t, err, "launchctl list: launchctl not found")
+}
@@ -0,0 +1,181 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+// Timeouts for delivering one event to one sink
+var (
+	webhookTimeout = 10 * time.Second
+	commandTimeout = 30 * time.Second
+)
+
+// desktopSupported reports whether desktop notifications can be shown
+var desktopSupported = runtime.GOOS == "darwin"
+
+// desktopCommand shows desktop notifications. The message and title are
+// passed as arguments rather than spliced into the script, so they need
+// no quoting.
+var desktopCommand = []string{
+	"osascript",
+	"-e", "on run argv",
+	"-e", "display notification (item 1 of argv) with title (item 2 of argv)",
+	"-e", "end run",
+}
+
+// Event is something that happened to a daemon. It is the JSON body POSTed
+// to webhooks and written to commands' standard input.
+type Event struct {
+	Event   string    `json:"event" yaml:"event"`
+	Daemon  string    `json:"daemon" yaml:"daemon"`
+	Label   string    `json:"label" yaml:"label"`
+	Tags    []string  `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Time    time.Time `json:"time" yaml:"time"`
+	Message string    `json:"message" yaml:"message"`
+	Host    string    `json:"host,omitempty" yaml:"host,omitempty"`
+	// ExitStatus is the status of the exit a crash reports
+	ExitStatus *int `json:"exit_status,omitempty" yaml:"exit_status,omitempty"`
+	// PID is the daemon's process after the event, if it is running
+	PID *int `json:"pid,omitempty" yaml:"pid,omitempty"`
+	// Restarts is the number of restarts within the flapping window
+	Restarts int `json:"restarts,omitempty" yaml:"restarts,omitempty"`
+}
+
+// Title is a one-line summary of the event
+func (e Event) Title() string {
+	switch e.Event {
+	case config.EventCrash:
+		return e.Daemon + " crashed"
+	case config.EventFlapping:
+		return e.Daemon + " is flapping"
+	case config.EventHealthCheck:
+		return e.Daemon + " failed its health check"
+	default:
+		return e.Daemon + ": " + e.Event
+	}
+}
+
+// Send delivers the event to every sink in n. Every sink is tried; the
+// errors of those that failed are returned together.
+func Send(ctx context.Context, n *config.Notify, event Event) error {
+	var errs []error
+	if n.Webhook != "" {
+		if err := sendWebhook(ctx, n.Webhook, event); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if len(n.Command) > 0 {
+		if err := runCommand(ctx, n.Command, event); err != nil {
+			errs = append(errs, fmt.Errorf("command: %w", err))
+		}
+	}
+	if n.Desktop != nil && *n.Desktop {
+		if err := showDesktop(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("desktop: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendWebhook POSTs the event as JSON and expects a 2xx response
+func sendWebhook(ctx context.Context, url string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "daemon-control")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// runCommand runs the command with the event as JSON on its standard input
+// and in DAEMON_CONTROL_* environment variables
+func runCommand(ctx context.Context, command []string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...) // #nosec G204 - command comes from the user's config
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), eventEnv(event)...)
+	return run(cmd)
+}
+
+// eventEnv describes the event in environment variables
+func eventEnv(event Event) []string {
+	env := []string{
+		"DAEMON_CONTROL_EVENT=" + event.Event,
+		"DAEMON_CONTROL_DAEMON=" + event.Daemon,
+		"DAEMON_CONTROL_LABEL=" + event.Label,
+		"DAEMON_CONTROL_TAGS=" + strings.Join(event.Tags, ","),
+		"DAEMON_CONTROL_MESSAGE=" + event.Message,
+		"DAEMON_CONTROL_TIME=" + event.Time.Format(time.RFC3339),
+		"DAEMON_CONTROL_HOST=" + event.Host,
+	}
+	if event.ExitStatus != nil {
+		env = append(env, "DAEMON_CONTROL_EXIT_STATUS="+strconv.Itoa(*event.ExitStatus))
+	}
+	if event.PID != nil {
+		env = append(env, "DAEMON_CONTROL_PID="+strconv.Itoa(*event.PID))
+	}
+	if event.Restarts > 0 {
+		env = append(env, "DAEMON_CONTROL_RESTARTS="+strconv.Itoa(event.Restarts))
+	}
+	return env
+}
+
+// showDesktop shows the event in Notification Center
+func showDesktop(ctx context.Context, event Event) error {
+	if !desktopSupported {
+		return fmt.Errorf("desktop notifications are only supported on macOS")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	args := append(append([]string(nil), desktopCommand[1:]...), event.Message, "daemon-control: "+event.Title())
+	cmd := exec.CommandContext(ctx, desktopCommand[0], args...) // #nosec G204 - fixed program, event text passed as arguments
+	return run(cmd)
+}
+
+// run runs cmd, including its output in the error if it fails
+func run(cmd *exec.Cmd) error {
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+	}
+	return err
+}
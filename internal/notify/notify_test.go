@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+func testEvent() Event {
+	status := 1
+	return Event{
+		Event:      config.EventCrash,
+		Daemon:     "web",
+		Label:      "com.example.web",
+		Tags:       []string{"api", "prod"},
+		Time:       time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		Message:    "exited with status 1",
+		Host:       "build-mac",
+		ExitStatus: &status,
+	}
+}
+
+func TestEvent_Title(t *testing.T) {
+	tests := []struct {
+		event string
+		want  string
+	}{
+		{config.EventCrash, "web crashed"},
+		{config.EventFlapping, "web is flapping"},
+		{config.EventHealthCheck, "web failed its health check"},
+		{"other", "web: other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			assert.Equal(t, tt.want, Event{Event: tt.event, Daemon: "web"}.Title())
+		})
+	}
+}
+
+func TestSend_Webhook(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, Send(context.Background(), &config.Notify{Webhook: srv.URL}, testEvent()))
+	assert.Equal(t, map[string]any{
+		"event":       "crash",
+		"daemon":      "web",
+		"label":       "com.example.web",
+		"tags":        []any{"api", "prod"},
+		"time":        "2026-10-18T09:30:00Z",
+		"message":     "exited with status 1",
+		"host":        "build-mac",
+		"exit_status": float64(1),
+	}, got)
+}
+
+func TestSend_WebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := Send(context.Background(), &config.Notify{Webhook: srv.URL}, testEvent())
+	assert.ErrorContains(t, err, "webhook: ")
+	assert.ErrorContains(t, err, "returned 502 Bad Gateway")
+}
+
+func TestSend_Command(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "notify.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+{
+  echo "$1 $DAEMON_CONTROL_EVENT $DAEMON_CONTROL_DAEMON $DAEMON_CONTROL_TAGS $DAEMON_CONTROL_EXIT_STATUS"
+  echo "$DAEMON_CONTROL_MESSAGE"
+  cat
+} > "`+out+`"
+`), 0700)) // #nosec G306 - test script must be executable
+
+	require.NoError(t, Send(context.Background(), &config.Notify{Command: []string{script, "--page"}}, testEvent()))
+
+	data, err := os.ReadFile(out) // #nosec G304 - test file
+	require.NoError(t, err)
+	lines := strings.SplitN(string(data), "\n", 3)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "--page crash web api,prod 1", lines[0])
+	assert.Equal(t, "exited with status 1", lines[1])
+
+	var event Event
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &event))
+	assert.Equal(t, testEvent(), event)
+}
+
+func TestSend_CommandError(t *testing.T) {
+	n := &config.Notify{Command: []string{"/bin/sh", "-c", "echo no route >&2; exit 3"}}
+	err := Send(context.Background(), n, testEvent())
+	assert.ErrorContains(t, err, "command: exit status 3: no route")
+}
+
+func TestSend_Desktop(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "args")
+	oldSupported, oldCommand := desktopSupported, desktopCommand
+	defer func() { desktopSupported, desktopCommand = oldSupported, oldCommand }()
+	desktopSupported = true
+	desktopCommand = []string{"/bin/sh", "-c", `printf '%s\n' "$@" > "` + out + `"`, "sh"}
+
+	require.NoError(t, Send(context.Background(), &config.Notify{Desktop: boolPtr(true)}, testEvent()))
+	data, err := os.ReadFile(out) // #nosec G304 - test file
+	require.NoError(t, err)
+	assert.Equal(t, "exited with status 1\ndaemon-control: web crashed\n", string(data))
+
+	desktopSupported = false
+	err = Send(context.Background(), &config.Notify{Desktop: boolPtr(true)}, testEvent())
+	assert.EqualError(t, err, "desktop: desktop notifications are only supported on macOS")
+}
+
+func TestSend_AllSinksTried(t *testing.T) {
+	oldSupported := desktopSupported
+	defer func() { desktopSupported = oldSupported }()
+	desktopSupported = false
+
+	n := &config.Notify{
+		Webhook: "http://127.0.0.1:1/unreachable",
+		Command: []string{"/bin/sh", "-c", "exit 1"},
+		Desktop: boolPtr(true),
+	}
+	err := Send(context.Background(), n, testEvent())
+	assert.ErrorContains(t, err, "webhook: ")
+	assert.ErrorContains(t, err, "command: exit status 1")
+	assert.ErrorContains(t, err, "desktop: ")
+
+	assert.NoError(t, Send(context.Background(), &config.Notify{Desktop: boolPtr(false)}, testEvent()))
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,345 @@
+package plist
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBinaryPlist is returned when asked to parse a binary property list
+var ErrBinaryPlist = errors.New("binary plists are not supported; convert with 'plutil -convert xml1'")
+
+// binaryPlistMagic prefixes every binary property list
+var binaryPlistMagic = []byte("bplist")
+
+// Parse decodes an XML property list whose root is a dictionary
+func Parse(data []byte) (*Plist, error) {
+	if bytes.HasPrefix(data, binaryPlistMagic) {
+		return nil, ErrBinaryPlist
+	}
+
+	var p Plist
+	if err := xml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plist: %w", err)
+	}
+	if p.Dict == nil {
+		return nil, fmt.Errorf("plist root is not a dict")
+	}
+
+	return &p, nil
+}
+
+// Decode reads and parses an XML property list
+func Decode(r io.Reader) (*Plist, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// ParseFile reads and parses a property list file. Binary plists are
+// converted to XML with plutil when it is available.
+func ParseFile(path string) (*Plist, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path comes from daemon directories
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, binaryPlistMagic) {
+		if _, err := exec.LookPath("plutil"); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, ErrBinaryPlist)
+		}
+		cmd := exec.CommandContext(context.Background(), "plutil", "-convert", "xml1", "-o", "-", path)
+		data, err = cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert binary plist %s: %w", path, err)
+		}
+	}
+
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ReadLabel returns the Label of the plist file at path
+func ReadLabel(path string) (string, error) {
+	p, err := ParseFile(path)
+	if err != nil {
+		return "", err
+	}
+	label, ok := p.Dict.GetString("Label")
+	if !ok || label == "" {
+		return "", fmt.Errorf("%s: plist has no Label", path)
+	}
+	return label, nil
+}
+
+// UnmarshalXML custom unmarshaler for Dict
+func (d *Dict) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	d.Items = nil
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "key" {
+				return fmt.Errorf("expected <key> in dict, found <%s>", t.Name.Local)
+			}
+			key, err := readText(decoder, t)
+			if err != nil {
+				return err
+			}
+
+			valueStart, err := nextStartElement(decoder)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", key, err)
+			}
+			value, err := decodeValue(decoder, valueStart)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", key, err)
+			}
+
+			d.Items = append(d.Items, Key{Value: key}, value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// UnmarshalXML custom unmarshaler for Array
+func (a *Array) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	a.Items = nil
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := decodeValue(decoder, t)
+			if err != nil {
+				return fmt.Errorf("[%d]: %w", len(a.Items), err)
+			}
+			a.Items = append(a.Items, value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// UnmarshalXML custom unmarshaler for Real
+func (r *Real) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	text, err := readText(decoder, start)
+	if err != nil {
+		return err
+	}
+	value, err := parseReal(text)
+	if err != nil {
+		return err
+	}
+	r.Value = value
+	return nil
+}
+
+// UnmarshalXML custom unmarshaler for Date
+func (d *Date) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	text, err := readText(decoder, start)
+	if err != nil {
+		return err
+	}
+	value, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+	if err != nil {
+		return fmt.Errorf("invalid date %q: %w", text, err)
+	}
+	d.Value = value.UTC()
+	return nil
+}
+
+// UnmarshalXML custom unmarshaler for Data
+func (d *Data) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	text, err := readText(decoder, start)
+	if err != nil {
+		return err
+	}
+
+	// Data is often wrapped across indented lines
+	compact := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, text)
+
+	value, err := base64.StdEncoding.DecodeString(compact)
+	if err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+	d.Value = value
+	return nil
+}
+
+// decodeValue decodes the element opened by start into its plist type
+func decodeValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "string":
+		text, err := readText(decoder, start)
+		if err != nil {
+			return nil, err
+		}
+		return String{Value: text}, nil
+	case "integer":
+		text, err := readText(decoder, start)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseInteger(text)
+		if err != nil {
+			return nil, err
+		}
+		return Integer{Value: value}, nil
+	case "real":
+		var r Real
+		if err := r.UnmarshalXML(decoder, start); err != nil {
+			return nil, err
+		}
+		return r, nil
+	case "date":
+		var d Date
+		if err := d.UnmarshalXML(decoder, start); err != nil {
+			return nil, err
+		}
+		return d, nil
+	case "data":
+		var d Data
+		if err := d.UnmarshalXML(decoder, start); err != nil {
+			return nil, err
+		}
+		return d, nil
+	case "true":
+		return True{}, decoder.Skip()
+	case "false":
+		return False{}, decoder.Skip()
+	case "dict":
+		dict := &Dict{}
+		if err := dict.UnmarshalXML(decoder, start); err != nil {
+			return nil, err
+		}
+		return dict, nil
+	case "array":
+		array := &Array{}
+		if err := array.UnmarshalXML(decoder, start); err != nil {
+			return nil, err
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unsupported plist element <%s>", start.Name.Local)
+	}
+}
+
+// parseInteger parses an integer value. Like CoreFoundation it reads
+// decimal, so a leading zero as in a Umask of 022 is not octal, and hex
+// only with an explicit 0x prefix.
+func parseInteger(text string) (int, error) {
+	trimmed := strings.TrimSpace(text)
+	digits, negative := strings.CutPrefix(trimmed, "-")
+	if !negative {
+		digits = strings.TrimPrefix(digits, "+")
+	}
+
+	base := 10
+	if hex, ok := strings.CutPrefix(strings.ToLower(digits), "0x"); ok {
+		base, digits = 16, hex
+	}
+	// One sign, before any 0x prefix
+	if digits == "" || strings.ContainsAny(digits, "+-_") {
+		return 0, fmt.Errorf("invalid integer %q", text)
+	}
+	if negative {
+		digits = "-" + digits
+	}
+
+	value, err := strconv.ParseInt(digits, base, strconv.IntSize)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("integer %q is out of range", text)
+		}
+		return 0, fmt.Errorf("invalid integer %q", text)
+	}
+	return int(value), nil
+}
+
+// parseReal parses a real value, including the infinity and nan spellings
+// written by CoreFoundation
+func parseReal(text string) (float64, error) {
+	trimmed := strings.TrimSpace(text)
+	switch strings.ToLower(trimmed) {
+	case "+infinity", "infinity", "inf", "+inf":
+		return math.Inf(1), nil
+	case "-infinity", "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid real %q: %w", text, err)
+	}
+	return value, nil
+}
+
+// readText collects the character data of a leaf element
+func readText(decoder *xml.Decoder, start xml.StartElement) (string, error) {
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			return "", fmt.Errorf("unexpected <%s> inside <%s>", t.Name.Local, start.Name.Local)
+		case xml.EndElement:
+			return text.String(), nil
+		}
+	}
+}
+
+// nextStartElement skips whitespace and comments up to the next element
+func nextStartElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, fmt.Errorf("missing value for key")
+		}
+	}
+}
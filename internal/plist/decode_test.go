@@ -0,0 +1,337 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.test</string>
+	<!-- comments are ignored -->
+	<key>Nice</key>
+	<integer>-5</integer>
+	<key>Ratio</key>
+	<real>0.25</real>
+	<key>Since</key>
+	<date>2024-01-02T03:04:05Z</date>
+	<key>Blob</key>
+	<data>
+	aGVs
+	bG8=
+	</data>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>Disabled</key>
+	<false/>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/usr/bin/true</string>
+		<string>  padded  </string>
+	</array>
+	<key>Nested</key>
+	<dict>
+		<key>Empty</key>
+		<dict/>
+	</dict>
+</dict>
+</plist>`
+
+	p, err := Parse([]byte(content))
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", p.Version)
+
+	expected := []interface{}{
+		Key{Value: "Label"}, String{Value: "com.example.test"},
+		Key{Value: "Nice"}, Integer{Value: -5},
+		Key{Value: "Ratio"}, Real{Value: 0.25},
+		Key{Value: "Since"}, Date{Value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Key{Value: "Blob"}, Data{Value: []byte("hello")},
+		Key{Value: "RunAtLoad"}, True{},
+		Key{Value: "Disabled"}, False{},
+		Key{Value: "ProgramArguments"}, &Array{Items: []interface{}{
+			String{Value: "/usr/bin/true"},
+			String{Value: "  padded  "},
+		}},
+		Key{Value: "Nested"}, &Dict{Items: []interface{}{
+			Key{Value: "Empty"}, &Dict{},
+		}},
+	}
+	assert.Equal(t, expected, p.Dict.Items)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{
+			name:    "binary plist",
+			content: "bplist00\x00\x01",
+			errMsg:  "binary plists are not supported",
+		},
+		{
+			name:    "array root",
+			content: `<plist version="1.0"><array></array></plist>`,
+			errMsg:  "plist root is not a dict",
+		},
+		{
+			name:    "value without key",
+			content: `<plist><dict><string>orphan</string></dict></plist>`,
+			errMsg:  "expected <key> in dict, found <string>",
+		},
+		{
+			name:    "key without value",
+			content: `<plist><dict><key>Label</key></dict></plist>`,
+			errMsg:  "key Label: missing value for key",
+		},
+		{
+			name:    "invalid integer",
+			content: `<plist><dict><key>Nice</key><integer>five</integer></dict></plist>`,
+			errMsg:  "invalid integer",
+		},
+		{
+			name:    "invalid real",
+			content: `<plist><dict><key>Ratio</key><real>half</real></dict></plist>`,
+			errMsg:  "invalid real",
+		},
+		{
+			name:    "invalid date",
+			content: `<plist><dict><key>Since</key><date>yesterday</date></dict></plist>`,
+			errMsg:  "invalid date",
+		},
+		{
+			name:    "invalid data",
+			content: `<plist><dict><key>Blob</key><data>!!!</data></dict></plist>`,
+			errMsg:  "invalid data",
+		},
+		{
+			name:    "unknown element",
+			content: `<plist><dict><key>Odd</key><set></set></dict></plist>`,
+			errMsg:  "unsupported plist element <set>",
+		},
+		{
+			name:    "element inside string",
+			content: `<plist><dict><key>Label</key><string><b>x</b></string></dict></plist>`,
+			errMsg:  "unexpected <b> inside <string>",
+		},
+		{
+			name:    "truncated",
+			content: `<plist><dict><key>Label</key>`,
+			errMsg:  "failed to parse plist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestParse_Reals(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{text: "3", want: 3},
+		{text: " -0.5 ", want: -0.5},
+		{text: "1e300", want: 1e300},
+		{text: "+infinity", want: math.Inf(1)},
+		{text: "inf", want: math.Inf(1)},
+		{text: "-infinity", want: math.Inf(-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var r Real
+			require.NoError(t, xml.Unmarshal([]byte("<real>"+tt.text+"</real>"), &r))
+			assert.Equal(t, tt.want, r.Value)
+		})
+	}
+
+	var r Real
+	require.NoError(t, xml.Unmarshal([]byte("<real>nan</real>"), &r))
+	assert.True(t, math.IsNaN(r.Value))
+}
+
+func TestParse_Integers(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int
+		wantErr string
+	}{
+		{text: "22", want: 22},
+		{text: "022", want: 22},
+		{text: "0755", want: 755},
+		{text: " -5 ", want: -5},
+		{text: "+7", want: 7},
+		{text: "0x1F", want: 31},
+		{text: "-0x10", want: -16},
+		{text: "0", want: 0},
+		{text: "1_000", wantErr: "invalid integer"},
+		{text: "0o17", wantErr: "invalid integer"},
+		{text: "0b101", wantErr: "invalid integer"},
+		{text: "0x", wantErr: "invalid integer"},
+		{text: "+-5", wantErr: "invalid integer"},
+		{text: "0x-5", wantErr: "invalid integer"},
+		{text: "", wantErr: "invalid integer"},
+		{text: "99999999999999999999", wantErr: "out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			p, err := Parse([]byte("<plist><dict><key>Umask</key><integer>" + tt.text + "</integer></dict></plist>"))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []interface{}{Key{Value: "Umask"}, Integer{Value: tt.want}}, p.Dict.Items)
+		})
+	}
+}
+
+func TestParse_DateOffsetNormalizedToUTC(t *testing.T) {
+	var d Date
+	require.NoError(t, xml.Unmarshal([]byte("<date>2024-01-02T05:04:05+02:00</date>"), &d))
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), d.Value)
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	nested := &Dict{}
+	nested.AddString("Key", "<escaped & \"quoted\">")
+	nested.AddBool("Flag", false)
+
+	dict := &Dict{}
+	dict.AddString("Label", "com.example.test")
+	dict.AddInteger("Nice", -5)
+	dict.AddStringArray("ProgramArguments", []string{"/bin/sh", "-c", "echo hi"})
+	dict.AddDict("Nested", nested)
+	dict.AddDictArray("Calendar", []*Dict{nested})
+	dict.Items = append(dict.Items,
+		Key{Value: "Ratio"}, Real{Value: 1.0 / 3},
+		Key{Value: "Since"}, Date{Value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Key{Value: "Blob"}, Data{Value: []byte{0, 1, 2, 0xff}},
+	)
+
+	original := &Plist{Version: "1.0", Dict: dict}
+	data, err := xml.MarshalIndent(original, "", "\t")
+	require.NoError(t, err)
+
+	decoded, err := Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	again, err := xml.MarshalIndent(decoded, "", "\t")
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+}
+
+func TestDecode_GeneratedPlist(t *testing.T) {
+	dict := &Dict{}
+	dict.AddString("Label", "com.example.test")
+	dict.AddBool("RunAtLoad", true)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "\t")
+	require.NoError(t, encoder.Encode(&Plist{Version: "1.0", Dict: dict}))
+
+	decoded, err := Decode(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	assert.Equal(t, dict.Items, decoded.Dict.Items)
+}
+
+func FuzzReal(f *testing.F) {
+	for _, seed := range []float64{0, -0.0, 1, -1.5, 1.0 / 3, 1e-310, math.MaxFloat64, math.Inf(1), math.Inf(-1), math.NaN()} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value float64) {
+		data, err := xml.Marshal(Real{Value: value})
+		require.NoError(t, err)
+
+		var decoded Real
+		require.NoError(t, xml.Unmarshal(data, &decoded))
+
+		if math.IsNaN(value) {
+			assert.True(t, math.IsNaN(decoded.Value))
+			return
+		}
+		assert.Equal(t, math.Float64bits(value), math.Float64bits(decoded.Value), "round trip of %s", data)
+	})
+}
+
+func FuzzDate(f *testing.F) {
+	for _, seed := range []int64{0, 1704164645, -62135596800, 253402300799} {
+		f.Add(seed, int64(0))
+	}
+	f.Add(int64(1704164645), int64(999999999))
+
+	f.Fuzz(func(t *testing.T, seconds int64, nanos int64) {
+		value := time.Unix(seconds, nanos)
+		// Plist dates are four-digit years only
+		if value.UTC().Year() < 1 || value.UTC().Year() > 9999 {
+			t.Skip()
+		}
+
+		data, err := xml.Marshal(Date{Value: value})
+		require.NoError(t, err)
+
+		var decoded Date
+		require.NoError(t, xml.Unmarshal(data, &decoded))
+		assert.Equal(t, value.UTC().Truncate(time.Second), decoded.Value, "round trip of %s", data)
+	})
+}
+
+func FuzzData(f *testing.F) {
+	for _, seed := range [][]byte{nil, {0}, []byte("hello"), {0xff, 0xfe, 0x00, '<', '&'}} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value []byte) {
+		data, err := xml.Marshal(Data{Value: value})
+		require.NoError(t, err)
+
+		var decoded Data
+		require.NoError(t, xml.Unmarshal(data, &decoded))
+		assert.Equal(t, len(value), len(decoded.Value))
+		assert.True(t, bytes.Equal(value, decoded.Value), "round trip of %s", data)
+	})
+}
+
+func TestReadLabel(t *testing.T) {
+	dir := t.TempDir()
+	labeled := filepath.Join(dir, "labeled.plist")
+	require.NoError(t, os.WriteFile(labeled, []byte(`<plist><dict><key>Label</key><string>com.example.test</string></dict></plist>`), 0600))
+	unlabeled := filepath.Join(dir, "unlabeled.plist")
+	require.NoError(t, os.WriteFile(unlabeled, []byte(`<plist><dict><key>Program</key><string>/bin/true</string></dict></plist>`), 0600))
+
+	label, err := ReadLabel(labeled)
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.test", label)
+
+	_, err = ReadLabel(unlabeled)
+	assert.ErrorContains(t, err, "plist has no Label")
+
+	_, err = ReadLabel(filepath.Join(dir, "missing.plist"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,239 @@
+package plist
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ChangeKind describes how a key path differs between two plists
+type ChangeKind string
+
+const (
+	// ChangeAdded means the path exists only in the new plist
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved means the path exists only in the old plist
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified means the path exists in both with different values
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is a single leaf-level difference between two plists
+type Change struct {
+	Path string     `json:"path" yaml:"path"`
+	Kind ChangeKind `json:"kind" yaml:"kind"`
+	Old  string     `json:"old,omitempty" yaml:"old,omitempty"`
+	New  string     `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// String formats the change as "Path: old → new"
+func (c Change) String() string {
+	from, to := c.Old, c.New
+	switch c.Kind {
+	case ChangeAdded:
+		from = "(absent)"
+	case ChangeRemoved:
+		to = "(absent)"
+	}
+	return fmt.Sprintf("%s: %s → %s", c.Path, from, to)
+}
+
+// plainKeyPattern matches keys that need no quoting in a key path
+var plainKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Diff compares two dicts and returns their leaf-level differences in
+// document order: keys of from in their original order, then keys that
+// only appear in to.
+func Diff(from, to *Dict) []Change {
+	var changes []Change
+	diffDicts("", from, to, &changes)
+	return changes
+}
+
+// diffDicts appends the differences between two dicts
+func diffDicts(path string, from, to *Dict, changes *[]Change) {
+	if from == nil {
+		from = &Dict{}
+	}
+	if to == nil {
+		to = &Dict{}
+	}
+
+	for _, key := range from.Keys() {
+		oldValue, _ := from.Get(key)
+		newValue, ok := to.Get(key)
+		if !ok {
+			leaves(joinKey(path, key), oldValue, ChangeRemoved, changes)
+			continue
+		}
+		diffValues(joinKey(path, key), oldValue, newValue, changes)
+	}
+
+	for _, key := range to.Keys() {
+		if _, ok := from.Get(key); ok {
+			continue
+		}
+		newValue, _ := to.Get(key)
+		leaves(joinKey(path, key), newValue, ChangeAdded, changes)
+	}
+}
+
+// diffValues appends the differences between two values at path
+func diffValues(path string, from, to interface{}, changes *[]Change) {
+	oldDict, oldIsDict := from.(*Dict)
+	newDict, newIsDict := to.(*Dict)
+	if oldIsDict && newIsDict {
+		diffDicts(path, oldDict, newDict, changes)
+		return
+	}
+
+	oldArray, oldIsArray := from.(*Array)
+	newArray, newIsArray := to.(*Array)
+	if oldIsArray && newIsArray {
+		for i := 0; i < len(oldArray.Items) || i < len(newArray.Items); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(newArray.Items):
+				leaves(itemPath, oldArray.Items[i], ChangeRemoved, changes)
+			case i >= len(oldArray.Items):
+				leaves(itemPath, newArray.Items[i], ChangeAdded, changes)
+			default:
+				diffValues(itemPath, oldArray.Items[i], newArray.Items[i], changes)
+			}
+		}
+		return
+	}
+
+	oldText, newText := FormatValue(from), FormatValue(to)
+	if oldText != newText || fmt.Sprintf("%T", from) != fmt.Sprintf("%T", to) {
+		*changes = append(*changes, Change{Path: path, Kind: ChangeModified, Old: oldText, New: newText})
+	}
+}
+
+// leaves appends one change per leaf of value, so that an added or removed
+// dict is reported key by key
+func leaves(path string, value interface{}, kind ChangeKind, changes *[]Change) {
+	switch v := value.(type) {
+	case *Dict:
+		if len(v.Items) > 0 {
+			for _, key := range v.Keys() {
+				item, _ := v.Get(key)
+				leaves(joinKey(path, key), item, kind, changes)
+			}
+			return
+		}
+	case *Array:
+		if len(v.Items) > 0 {
+			for i, item := range v.Items {
+				leaves(fmt.Sprintf("%s[%d]", path, i), item, kind, changes)
+			}
+			return
+		}
+	}
+
+	change := Change{Path: path, Kind: kind}
+	if kind == ChangeAdded {
+		change.New = FormatValue(value)
+	} else {
+		change.Old = FormatValue(value)
+	}
+	*changes = append(*changes, change)
+}
+
+// joinKey appends a dict key to a key path, quoting keys that contain
+// separators such as the dots in labels and file paths
+func joinKey(path, key string) string {
+	if !plainKeyPattern.MatchString(key) {
+		return fmt.Sprintf("%s[%s]", path, strconv.Quote(key))
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// FormatValue renders a plist element for display
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case String:
+		return strconv.Quote(v.Value)
+	case Integer:
+		return strconv.Itoa(v.Value)
+	case Real:
+		// Keep reals distinguishable from integers
+		text := strconv.FormatFloat(v.Value, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eInN") {
+			text += ".0"
+		}
+		return text
+	case Date:
+		return v.Value.UTC().Format(dateFormat)
+	case Data:
+		return "<" + base64.StdEncoding.EncodeToString(v.Value) + ">"
+	case True:
+		return "true"
+	case False:
+		return "false"
+	case *Dict:
+		parts := make([]string, 0, len(v.Items)/2)
+		for _, key := range v.Keys() {
+			item, _ := v.Get(key)
+			parts = append(parts, key+": "+FormatValue(item))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case *Array:
+		parts := make([]string, 0, len(v.Items))
+		for _, item := range v.Items {
+			parts = append(parts, FormatValue(item))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// UnifiedDiff returns a unified text diff of two plists, each encoded the
+// same way so that only content differences are shown. A nil plist is
+// treated as an absent file.
+func UnifiedDiff(from, to *Plist, oldName, newName string, context int) (string, error) {
+	oldText, err := encodeOrEmpty(from)
+	if err != nil {
+		return "", err
+	}
+	newText, err := encodeOrEmpty(to)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(oldText),
+		B:        splitLines(newText),
+		FromFile: oldName,
+		ToFile:   newName,
+		Context:  context,
+	})
+}
+
+// encodeOrEmpty encodes p, returning an empty string for nil
+func encodeOrEmpty(p *Plist) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+	data, err := Encode(p)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// splitLines splits text into lines, treating empty text as no lines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return difflib.SplitLines(text)
+}
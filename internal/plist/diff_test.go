@@ -0,0 +1,246 @@
+package plist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+func TestDiff(t *testing.T) {
+	keepAlive := func(crashed bool) *Dict {
+		dict := &Dict{}
+		dict.AddBool("Crashed", crashed)
+		return dict
+	}
+
+	tests := []struct {
+		name  string
+		from  func() *Dict
+		to    func() *Dict
+		want  []Change
+		lines []string
+	}{
+		{
+			name: "identical",
+			from: func() *Dict {
+				d := &Dict{}
+				d.AddString("Label", "com.example.test")
+				d.AddDict("KeepAlive", keepAlive(true))
+				return d
+			},
+			to: func() *Dict {
+				d := &Dict{}
+				d.AddDict("KeepAlive", keepAlive(true))
+				d.AddString("Label", "com.example.test")
+				return d
+			},
+		},
+		{
+			name: "nested value changed",
+			from: func() *Dict {
+				d := &Dict{}
+				d.AddDict("KeepAlive", keepAlive(true))
+				return d
+			},
+			to: func() *Dict {
+				d := &Dict{}
+				d.AddDict("KeepAlive", keepAlive(false))
+				return d
+			},
+			want:  []Change{{Path: "KeepAlive.Crashed", Kind: ChangeModified, Old: "true", New: "false"}},
+			lines: []string{"KeepAlive.Crashed: true → false"},
+		},
+		{
+			name: "added and removed keys",
+			from: func() *Dict {
+				d := &Dict{}
+				d.AddString("Label", "com.example.test")
+				d.AddInteger("Nice", 5)
+				return d
+			},
+			to: func() *Dict {
+				d := &Dict{}
+				d.AddString("Label", "com.example.test")
+				env := &Dict{}
+				env.AddString("PATH", "/usr/bin")
+				env.AddString("LOG_LEVEL", "debug")
+				d.AddDict("EnvironmentVariables", env)
+				return d
+			},
+			want: []Change{
+				{Path: "Nice", Kind: ChangeRemoved, Old: "5"},
+				{Path: "EnvironmentVariables.PATH", Kind: ChangeAdded, New: `"/usr/bin"`},
+				{Path: "EnvironmentVariables.LOG_LEVEL", Kind: ChangeAdded, New: `"debug"`},
+			},
+			lines: []string{
+				"Nice: 5 → (absent)",
+				`EnvironmentVariables.PATH: (absent) → "/usr/bin"`,
+				`EnvironmentVariables.LOG_LEVEL: (absent) → "debug"`,
+			},
+		},
+		{
+			name: "array items",
+			from: func() *Dict {
+				d := &Dict{}
+				d.AddStringArray("ProgramArguments", []string{"/bin/app", "--verbose"})
+				return d
+			},
+			to: func() *Dict {
+				d := &Dict{}
+				d.AddStringArray("ProgramArguments", []string{"/bin/app", "--quiet", "--once"})
+				return d
+			},
+			want: []Change{
+				{Path: "ProgramArguments[1]", Kind: ChangeModified, Old: `"--verbose"`, New: `"--quiet"`},
+				{Path: "ProgramArguments[2]", Kind: ChangeAdded, New: `"--once"`},
+			},
+		},
+		{
+			name: "keys with separators are quoted",
+			from: func() *Dict {
+				jobs := &Dict{}
+				jobs.AddBool("com.example.other", true)
+				ka := &Dict{}
+				ka.AddDict("OtherJobEnabled", jobs)
+				d := &Dict{}
+				d.AddDict("KeepAlive", ka)
+				return d
+			},
+			to: func() *Dict {
+				jobs := &Dict{}
+				jobs.AddBool("com.example.other", false)
+				ka := &Dict{}
+				ka.AddDict("OtherJobEnabled", jobs)
+				d := &Dict{}
+				d.AddDict("KeepAlive", ka)
+				return d
+			},
+			lines: []string{`KeepAlive.OtherJobEnabled["com.example.other"]: true → false`},
+		},
+		{
+			name: "type change",
+			from: func() *Dict {
+				d := &Dict{}
+				d.AddBool("KeepAlive", true)
+				return d
+			},
+			to: func() *Dict {
+				d := &Dict{}
+				d.AddDict("KeepAlive", keepAlive(true))
+				return d
+			},
+			lines: []string{"KeepAlive: true → {Crashed: true}"},
+		},
+		{
+			name: "integer and real with same text differ",
+			from: func() *Dict {
+				d := &Dict{}
+				d.AddInteger("Value", 1)
+				return d
+			},
+			to: func() *Dict {
+				d := &Dict{}
+				d.Items = append(d.Items, Key{Value: "Value"}, Real{Value: 1})
+				return d
+			},
+			lines: []string{"Value: 1 → 1.0"},
+		},
+		{
+			name: "scalar types",
+			from: func() *Dict {
+				d := &Dict{}
+				d.Items = append(d.Items,
+					Key{Value: "Since"}, Date{Value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+					Key{Value: "Blob"}, Data{Value: []byte("hi")},
+				)
+				return d
+			},
+			to: func() *Dict {
+				d := &Dict{}
+				d.Items = append(d.Items,
+					Key{Value: "Since"}, Date{Value: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+					Key{Value: "Blob"}, Data{Value: []byte("hi")},
+				)
+				return d
+			},
+			lines: []string{"Since: 2024-01-02T03:04:05Z → 2025-01-02T03:04:05Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Diff(tt.from(), tt.to())
+			if tt.want == nil && tt.lines == nil {
+				assert.Empty(t, changes)
+			}
+			if tt.want != nil {
+				assert.Equal(t, tt.want, changes)
+			}
+			if tt.lines != nil {
+				lines := make([]string, 0, len(changes))
+				for _, change := range changes {
+					lines = append(lines, change.String())
+				}
+				assert.Equal(t, tt.lines, lines)
+			}
+		})
+	}
+}
+
+func TestDiff_GeneratedAgainstParsed(t *testing.T) {
+	daemon := &config.Daemon{
+		Name:                 "test",
+		Label:                "com.example.test",
+		Program:              "/usr/bin/test",
+		EnvironmentVariables: map[string]string{"B": "2", "A": "1", "C": "3"},
+		KeepAlive:            &config.KeepAlive{Crashed: boolPtr(true)},
+	}
+
+	generator := NewGenerator("")
+	data, err := generator.Render(daemon)
+	require.NoError(t, err)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Empty(t, Diff(parsed.Dict, generator.Build(daemon).Dict))
+
+	// Rendering is deterministic
+	again, err := generator.Render(daemon)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+
+	daemon.KeepAlive.Crashed = boolPtr(false)
+	changes := Diff(parsed.Dict, generator.Build(daemon).Dict)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "KeepAlive.Crashed: true → false", changes[0].String())
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := &Dict{}
+	from.AddString("Label", "com.example.test")
+	from.AddBool("RunAtLoad", true)
+
+	to := &Dict{}
+	to.AddString("Label", "com.example.test")
+	to.AddBool("RunAtLoad", false)
+
+	text, err := UnifiedDiff(&Plist{Version: "1.0", Dict: from}, &Plist{Version: "1.0", Dict: to}, "installed", "generated", 1)
+	require.NoError(t, err)
+	assert.Contains(t, text, "--- installed")
+	assert.Contains(t, text, "+++ generated")
+	assert.Contains(t, text, "-        <true></true>")
+	assert.Contains(t, text, "+        <false></false>")
+
+	same, err := UnifiedDiff(&Plist{Version: "1.0", Dict: from}, &Plist{Version: "1.0", Dict: from}, "a", "b", 3)
+	require.NoError(t, err)
+	assert.Empty(t, same)
+
+	missing, err := UnifiedDiff(nil, &Plist{Version: "1.0", Dict: to}, "installed", "generated", 3)
+	require.NoError(t, err)
+	assert.Contains(t, missing, "+<?xml")
+	assert.NotContains(t, missing, "\n-\n")
+}
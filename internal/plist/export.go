@@ -0,0 +1,405 @@
+package plist
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+// ToDaemon converts a launchd plist back to a daemon definition, the inverse
+// of the generator. Keys the schema does not model are kept in
+// ExtraPlistKeys. The returned notes list every difference between the
+// original plist and the one the definition would generate, such as false
+// booleans the generator omits or values whose type the schema cannot hold.
+func ToDaemon(name string, p *Plist) (*config.Daemon, []string, error) {
+	if p == nil || p.Dict == nil {
+		return nil, nil, fmt.Errorf("plist dict is nil")
+	}
+
+	daemon := &config.Daemon{Name: name}
+	for _, key := range p.Dict.Keys() {
+		value, _ := p.Dict.Get(key)
+		if _, modeled := config.ModeledPlistKeys[key]; !modeled {
+			extra, err := GoValue(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", key, err)
+			}
+			if daemon.ExtraPlistKeys == nil {
+				daemon.ExtraPlistKeys = make(map[string]interface{})
+			}
+			daemon.ExtraPlistKeys[key] = extra
+			continue
+		}
+		setDaemonKey(daemon, key, value)
+	}
+
+	// Identical soft and hard limits collapse into resource_limits
+	if daemon.SoftResourceLimits != nil && reflect.DeepEqual(daemon.SoftResourceLimits, daemon.HardResourceLimits) {
+		daemon.ResourceLimits = daemon.SoftResourceLimits
+		daemon.SoftResourceLimits, daemon.HardResourceLimits = nil, nil
+	}
+
+	var notes []string
+	for _, change := range Diff(p.Dict, NewGenerator("").Build(daemon).Dict) {
+		notes = append(notes, change.String())
+	}
+
+	return daemon, notes, nil
+}
+
+// setDaemonKey sets the field for a modeled key. Values of an unexpected
+// type are left unset and surface as notes in ToDaemon.
+func setDaemonKey(daemon *config.Daemon, key string, value interface{}) {
+	switch key {
+	case "Label":
+		daemon.Label = stringValue(value)
+	case "Program":
+		daemon.Program = stringValue(value)
+	case "ProgramArguments":
+		daemon.ProgramArguments = stringsValue(value)
+	case "WorkingDirectory":
+		daemon.WorkingDirectory = stringValue(value)
+	case "EnvironmentVariables":
+		daemon.EnvironmentVariables = stringMapValue(value)
+	case "StandardOutPath":
+		daemon.StandardOutPath = stringValue(value)
+	case "StandardErrorPath":
+		daemon.StandardErrorPath = stringValue(value)
+	case "RunAtLoad":
+		daemon.RunAtLoad = boolValue(value)
+	case "StartInterval":
+		daemon.StartInterval = intValue(value)
+	case "KeepAlive":
+		daemon.KeepAlive = keepAliveValue(value)
+	case "ThrottleInterval":
+		daemon.ThrottleInterval = intValue(value)
+	case "SoftResourceLimits":
+		daemon.SoftResourceLimits = resourceLimitsValue(value)
+	case "HardResourceLimits":
+		daemon.HardResourceLimits = resourceLimitsValue(value)
+	case "ProcessType":
+		daemon.ProcessType = stringValue(value)
+	case "Nice":
+		daemon.Nice = intPtrValue(value)
+	case "InitGroups":
+		daemon.InitGroups = boolValue(value)
+	case "UserName":
+		daemon.UserName = stringValue(value)
+	case "GroupName":
+		daemon.GroupName = stringValue(value)
+	case "RootDirectory":
+		daemon.RootDirectory = stringValue(value)
+	case "Sockets":
+		daemon.Sockets = socketsValue(value)
+	case "StartCalendarInterval":
+		daemon.StartCalendarInterval = calendarIntervalsValue(value)
+	case "WatchPaths":
+		daemon.WatchPaths = stringsValue(value)
+	case "QueueDirectories":
+		daemon.QueuePaths = stringsValue(value)
+	case "EnableGlobbing":
+		daemon.EnableGlobbing = boolValue(value)
+	case "EnableTransactions":
+		daemon.EnableTransactions = boolValue(value)
+	case "EnablePressuredExit":
+		daemon.EnablePressuredExit = boolValue(value)
+	case "ExitTimeOut":
+		daemon.ExitTimeOut = intValue(value)
+	case "Disabled":
+		daemon.Disabled = boolValue(value)
+	case "LimitLoadToSessionType":
+		if s, ok := value.(String); ok {
+			daemon.LimitLoadToSessionType = []string{s.Value}
+		} else {
+			daemon.LimitLoadToSessionType = stringsValue(value)
+		}
+	case "LimitLoadToHosts":
+		daemon.LimitLoadToHosts = stringsValue(value)
+	case "MachServices":
+		daemon.MachServices = machServicesValue(value)
+	case "LaunchEvents":
+		daemon.LaunchEvents = launchEventsValue(value)
+	case "StartOnMount":
+		daemon.StartOnMount = boolValue(value)
+	case "inetdCompatibility":
+		if dict, ok := value.(*Dict); ok {
+			daemon.InetdCompatibility = &config.InetdCompatibility{Wait: boolPtrValue(dictValue(dict, "Wait"))}
+		}
+	case "Umask":
+		daemon.Umask = intPtrValue(value)
+	case "AbandonProcessGroup":
+		daemon.AbandonProcessGroup = boolValue(value)
+	case "LowPriorityIO":
+		daemon.LowPriorityIO = boolValue(value)
+	case "LowPriorityBackgroundIO":
+		daemon.LowPriorityBackgroundIO = boolValue(value)
+	case "SessionCreate":
+		daemon.SessionCreate = boolValue(value)
+	case "Debug":
+		daemon.Debug = boolValue(value)
+	case "WaitForDebugger":
+		daemon.WaitForDebugger = boolValue(value)
+	case "AssociatedBundleIdentifiers":
+		daemon.AssociatedBundleIdentifiers = stringsValue(value)
+	}
+}
+
+// keepAliveValue converts a KeepAlive boolean or conditions dict
+func keepAliveValue(value interface{}) *config.KeepAlive {
+	if b := boolPtrValue(value); b != nil {
+		return &config.KeepAlive{Always: b}
+	}
+	dict, ok := value.(*Dict)
+	if !ok {
+		return nil
+	}
+
+	return &config.KeepAlive{
+		SuccessfulExit:     boolPtrValue(dictValue(dict, "SuccessfulExit")),
+		NetworkState:       boolPtrValue(dictValue(dict, "NetworkState")),
+		Crashed:            boolPtrValue(dictValue(dict, "Crashed")),
+		AfterInitialDemand: boolPtrValue(dictValue(dict, "AfterInitialDemand")),
+		PathState:          boolMapValue(dictValue(dict, "PathState")),
+		OtherJobEnabled:    boolMapValue(dictValue(dict, "OtherJobEnabled")),
+	}
+}
+
+// resourceLimitsValue converts a resource limits dict
+func resourceLimitsValue(value interface{}) *config.ResourceLimits {
+	dict, ok := value.(*Dict)
+	if !ok {
+		return nil
+	}
+
+	return &config.ResourceLimits{
+		CPU:               intPtrValue(dictValue(dict, "CPU")),
+		FileSize:          sizePtrValue(dictValue(dict, "FileSize")),
+		NumberOfFiles:     intPtrValue(dictValue(dict, "NumberOfFiles")),
+		Core:              sizePtrValue(dictValue(dict, "Core")),
+		Data:              sizePtrValue(dictValue(dict, "Data")),
+		MemoryLock:        sizePtrValue(dictValue(dict, "MemoryLock")),
+		NumberOfProcesses: intPtrValue(dictValue(dict, "NumberOfProcesses")),
+		ResidentSetSize:   sizePtrValue(dictValue(dict, "ResidentSetSize")),
+		Stack:             sizePtrValue(dictValue(dict, "Stack")),
+	}
+}
+
+// socketsValue converts a dict of named socket dicts
+func socketsValue(value interface{}) map[string]config.Socket {
+	dict, ok := value.(*Dict)
+	if !ok {
+		return nil
+	}
+
+	sockets := make(map[string]config.Socket, len(dict.Items)/2)
+	for _, name := range dict.Keys() {
+		item, _ := dict.Get(name)
+		socketDict, ok := item.(*Dict)
+		if !ok {
+			continue
+		}
+
+		socket := config.Socket{
+			SockType:        stringValue(dictValue(socketDict, "SockType")),
+			SockPassive:     boolPtrValue(dictValue(socketDict, "SockPassive")),
+			SockNodeName:    stringValue(dictValue(socketDict, "SockNodeName")),
+			SockServiceName: stringValue(dictValue(socketDict, "SockServiceName")),
+			SockFamily:      stringValue(dictValue(socketDict, "SockFamily")),
+			SockProtocol:    stringValue(dictValue(socketDict, "SockProtocol")),
+			SockPathName:    stringValue(dictValue(socketDict, "SockPathName")),
+			SockPathMode:    intPtrValue(dictValue(socketDict, "SockPathMode")),
+		}
+		if bonjour := dictValue(socketDict, "Bonjour"); bonjour != nil {
+			if _, isArray := bonjour.(*Array); isArray {
+				socket.BonjourMultiple = stringsValue(bonjour)
+			} else {
+				socket.Bonjour = boolPtrValue(bonjour)
+			}
+		}
+		sockets[name] = socket
+	}
+	return sockets
+}
+
+// calendarIntervalsValue converts a calendar dict or array of dicts
+func calendarIntervalsValue(value interface{}) []config.CalendarInterval {
+	var dicts []*Dict
+	switch v := value.(type) {
+	case *Dict:
+		dicts = []*Dict{v}
+	case *Array:
+		for _, item := range v.Items {
+			if dict, ok := item.(*Dict); ok {
+				dicts = append(dicts, dict)
+			}
+		}
+	}
+
+	intervals := make([]config.CalendarInterval, 0, len(dicts))
+	for _, dict := range dicts {
+		intervals = append(intervals, config.CalendarInterval{
+			Minute:  intPtrValue(dictValue(dict, "Minute")),
+			Hour:    intPtrValue(dictValue(dict, "Hour")),
+			Day:     intPtrValue(dictValue(dict, "Day")),
+			Weekday: intPtrValue(dictValue(dict, "Weekday")),
+			Month:   intPtrValue(dictValue(dict, "Month")),
+		})
+	}
+	return intervals
+}
+
+// machServicesValue converts a dict of Mach service registrations
+func machServicesValue(value interface{}) map[string]config.MachService {
+	dict, ok := value.(*Dict)
+	if !ok {
+		return nil
+	}
+
+	services := make(map[string]config.MachService, len(dict.Items)/2)
+	for _, name := range dict.Keys() {
+		item, _ := dict.Get(name)
+		switch v := item.(type) {
+		case True:
+			services[name] = config.MachService{}
+		case *Dict:
+			services[name] = config.MachService{
+				ResetAtClose:     boolPtrValue(dictValue(v, "ResetAtClose")),
+				HideUntilCheckIn: boolPtrValue(dictValue(v, "HideUntilCheckIn")),
+			}
+		}
+	}
+	return services
+}
+
+// launchEventsValue converts subsystem → event → descriptor dicts
+func launchEventsValue(value interface{}) map[string]map[string]map[string]interface{} {
+	dict, ok := value.(*Dict)
+	if !ok {
+		return nil
+	}
+
+	events := make(map[string]map[string]map[string]interface{}, len(dict.Items)/2)
+	for _, subsystem := range dict.Keys() {
+		item, _ := dict.Get(subsystem)
+		subsystemDict, ok := item.(*Dict)
+		if !ok {
+			continue
+		}
+
+		events[subsystem] = make(map[string]map[string]interface{}, len(subsystemDict.Items)/2)
+		for _, event := range subsystemDict.Keys() {
+			descriptor, _ := subsystemDict.Get(event)
+			if _, ok := descriptor.(*Dict); !ok {
+				continue
+			}
+			converted, err := GoValue(descriptor)
+			if err != nil {
+				continue
+			}
+			events[subsystem][event] = converted.(map[string]interface{})
+		}
+	}
+	return events
+}
+
+// dictValue returns the value for key, or nil when absent
+func dictValue(dict *Dict, key string) interface{} {
+	value, _ := dict.Get(key)
+	return value
+}
+
+// stringValue returns a string element's value, or "" for other types
+func stringValue(value interface{}) string {
+	s, _ := value.(String)
+	return s.Value
+}
+
+// boolValue reports whether value is a true element
+func boolValue(value interface{}) bool {
+	_, ok := value.(True)
+	return ok
+}
+
+// boolPtrValue returns a boolean element's value, or nil for other types
+func boolPtrValue(value interface{}) *bool {
+	switch value.(type) {
+	case True:
+		b := true
+		return &b
+	case False:
+		b := false
+		return &b
+	}
+	return nil
+}
+
+// intValue returns an integer element's value, or 0 for other types
+func intValue(value interface{}) int {
+	i, _ := value.(Integer)
+	return i.Value
+}
+
+// intPtrValue returns an integer element's value, or nil for other types
+func intPtrValue(value interface{}) *int {
+	i, ok := value.(Integer)
+	if !ok {
+		return nil
+	}
+	return &i.Value
+}
+
+// sizePtrValue returns an integer element as a size, or nil for other types
+func sizePtrValue(value interface{}) *config.ByteSize {
+	i, ok := value.(Integer)
+	if !ok {
+		return nil
+	}
+	size := config.ByteSize(i.Value)
+	return &size
+}
+
+// stringsValue returns the strings in an array element
+func stringsValue(value interface{}) []string {
+	array, ok := value.(*Array)
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(array.Items))
+	for _, item := range array.Items {
+		if s, ok := item.(String); ok {
+			values = append(values, s.Value)
+		}
+	}
+	return values
+}
+
+// stringMapValue returns the string values in a dict element
+func stringMapValue(value interface{}) map[string]string {
+	dict, ok := value.(*Dict)
+	if !ok {
+		return nil
+	}
+	m := make(map[string]string, len(dict.Items)/2)
+	for _, key := range dict.Keys() {
+		if s, ok := dictValue(dict, key).(String); ok {
+			m[key] = s.Value
+		}
+	}
+	return m
+}
+
+// boolMapValue returns the boolean values in a dict element
+func boolMapValue(value interface{}) map[string]bool {
+	dict, ok := value.(*Dict)
+	if !ok {
+		return nil
+	}
+	m := make(map[string]bool, len(dict.Items)/2)
+	for _, key := range dict.Keys() {
+		if b := boolPtrValue(dictValue(dict, key)); b != nil {
+			m[key] = *b
+		}
+	}
+	return m
+}
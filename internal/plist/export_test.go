@@ -0,0 +1,198 @@
+package plist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+)
+
+// reloadDaemons writes daemons through config.Marshal and loads them back
+func reloadDaemons(t *testing.T, daemons ...config.Daemon) []config.Daemon {
+	t.Helper()
+
+	data, err := config.Marshal(&config.Config{Daemons: daemons})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "daemons.yaml")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	cfg, err := config.NewLoader(path).Load()
+	require.NoError(t, err, string(data))
+	return cfg.Daemons
+}
+
+func TestToDaemon_RoundTrip(t *testing.T) {
+	daemons := []config.Daemon{
+		{
+			Name:                 "full",
+			Label:                "com.example.full",
+			ProgramArguments:     []string{"/usr/local/bin/app", "--port", "8080"},
+			WorkingDirectory:     "/var/lib/app",
+			EnvironmentVariables: map[string]string{"PATH": "/usr/bin", "Mixed_Case": "yes"},
+			StandardOutPath:      "/var/log/app.log",
+			StandardErrorPath:    "/var/log/app.err",
+			RunAtLoad:            true,
+			KeepAlive: &config.KeepAlive{
+				SuccessfulExit:  boolPtr(false),
+				Crashed:         boolPtr(true),
+				PathState:       map[string]bool{"/tmp/ready": true},
+				OtherJobEnabled: map[string]bool{"com.example.db": true},
+			},
+			ThrottleInterval:   30,
+			ResourceLimits:     &config.ResourceLimits{NumberOfFiles: intPtr(1024)},
+			SoftResourceLimits: &config.ResourceLimits{ResidentSetSize: sizePtr(512 << 20)},
+			HardResourceLimits: &config.ResourceLimits{ResidentSetSize: sizePtr(1 << 30)},
+			ProcessType:        "Background",
+			Nice:               intPtr(5),
+			UserName:           "nobody",
+			Sockets: map[string]config.Socket{
+				"Listener": {SockServiceName: "8080", SockType: "stream", SockPassive: boolPtr(true)},
+				"Bonjour":  {SockPathName: "/tmp/app.sock", SockPathMode: intPtr(0600), BonjourMultiple: []string{"_http._tcp"}},
+			},
+			StartCalendarInterval:  []config.CalendarInterval{{Hour: intPtr(2)}, {Hour: intPtr(14), Minute: intPtr(30)}},
+			WatchPaths:             []string{"/etc/app.conf"},
+			QueuePaths:             []string{"/var/spool/app"},
+			ExitTimeOut:            20,
+			LimitLoadToSessionType: []string{"Aqua", "Background"},
+			MachServices: map[string]config.MachService{
+				"com.example.full.xpc":    {},
+				"com.example.full.hidden": {HideUntilCheckIn: boolPtr(true)},
+			},
+			LaunchEvents: map[string]map[string]map[string]interface{}{
+				"com.apple.notifyd.matching": {"wake": {"Notification": "com.apple.wake", "Count": 2}},
+			},
+			InetdCompatibility:          &config.InetdCompatibility{Wait: boolPtr(true)},
+			Umask:                       intPtr(0o022),
+			LowPriorityIO:               true,
+			AssociatedBundleIdentifiers: []string{"com.example.App"},
+			ExtraPlistKeys: map[string]interface{}{
+				"Ratio":     3.0,
+				"Blob":      []byte{0, 1, 2, 0xff},
+				"Since":     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				"Nested":    map[string]interface{}{"List": []interface{}{"a", 1, 0.5, true}},
+				"Disabled2": false,
+			},
+		},
+		{
+			Name:      "simple",
+			Label:     "com.example.simple",
+			Program:   "/usr/bin/true",
+			KeepAlive: &config.KeepAlive{Always: boolPtr(true)},
+			ResourceLimits: &config.ResourceLimits{
+				Core: sizePtr(0),
+			},
+		},
+	}
+
+	generator := NewGenerator("")
+	for _, daemon := range daemons {
+		t.Run(daemon.Name, func(t *testing.T) {
+			original, err := generator.Render(&daemon)
+			require.NoError(t, err)
+
+			parsed, err := Parse(original)
+			require.NoError(t, err)
+
+			exported, notes, err := ToDaemon(daemon.Name, parsed)
+			require.NoError(t, err)
+			assert.Empty(t, notes)
+
+			reloaded := reloadDaemons(t, *exported)
+			require.Len(t, reloaded, 1)
+
+			regenerated, err := generator.Render(&reloaded[0])
+			require.NoError(t, err)
+			assert.Equal(t, string(original), string(regenerated))
+		})
+	}
+}
+
+func TestToDaemon_ThirdPartyPlist(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.vendor.agent</string>
+	<key>Program</key>
+	<string>/Applications/Vendor.app/Contents/MacOS/agent</string>
+	<key>RunAtLoad</key>
+	<false/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>LimitLoadToSessionType</key>
+	<string>Aqua</string>
+	<key>MaterializeDatalessFiles</key>
+	<true/>
+	<key>POSIXSpawnType</key>
+	<string>Interactive</string>
+	<key>Weight</key>
+	<real>2</real>
+</dict>
+</plist>`
+
+	parsed, err := Parse([]byte(content))
+	require.NoError(t, err)
+
+	daemon, notes, err := ToDaemon("agent", parsed)
+	require.NoError(t, err)
+
+	assert.Equal(t, "agent", daemon.Name)
+	assert.Equal(t, "com.vendor.agent", daemon.Label)
+	assert.Equal(t, "/Applications/Vendor.app/Contents/MacOS/agent", daemon.Program)
+	assert.False(t, daemon.RunAtLoad)
+	require.NotNil(t, daemon.KeepAlive)
+	assert.Equal(t, boolPtr(true), daemon.KeepAlive.Always)
+	assert.Equal(t, []string{"Aqua"}, daemon.LimitLoadToSessionType)
+	assert.Equal(t, map[string]interface{}{
+		"MaterializeDatalessFiles": true,
+		"POSIXSpawnType":           "Interactive",
+		"Weight":                   2.0,
+	}, daemon.ExtraPlistKeys)
+
+	// Program is written as ProgramArguments and false defaults are omitted
+	assert.Equal(t, []string{
+		`Program: "/Applications/Vendor.app/Contents/MacOS/agent" → (absent)`,
+		"RunAtLoad: false → (absent)",
+		`ProgramArguments[0]: (absent) → "/Applications/Vendor.app/Contents/MacOS/agent"`,
+	}, notes)
+
+	// Everything else survives the trip through YAML
+	reloaded := reloadDaemons(t, *daemon)
+	changes := Diff(parsed.Dict, NewGenerator("").Build(&reloaded[0]).Dict)
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	assert.Equal(t, notes, lines)
+}
+
+func TestToDaemon_UnrepresentableValues(t *testing.T) {
+	dict := &Dict{}
+	dict.AddString("Label", "com.example.odd")
+	dict.AddStringArray("ProgramArguments", []string{"/bin/odd"})
+	dict.AddString("StartInterval", "60")
+	keepAlive := &Dict{}
+	keepAlive.AddBool("Crashed", true)
+	keepAlive.AddBool("Unknown", true)
+	dict.AddDict("KeepAlive", keepAlive)
+
+	daemon, notes, err := ToDaemon("odd", &Plist{Version: "1.0", Dict: dict})
+	require.NoError(t, err)
+	assert.Equal(t, 0, daemon.StartInterval)
+	assert.Equal(t, []string{
+		`StartInterval: "60" → (absent)`,
+		"KeepAlive.Unknown: true → (absent)",
+	}, notes)
+}
+
+func TestToDaemon_NilPlist(t *testing.T) {
+	_, _, err := ToDaemon("none", &Plist{})
+	assert.Error(t, err)
+}
@@ -6,15 +6,40 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/utils"
 )
 
 // Generator creates plist files from daemon configurations
 type Generator struct {
 	outputDir string
+	// environment is added to every daemon's EnvironmentVariables
+	environment map[string]string
+	// validate checks every plist against launchd's rules before any is
+	// written
+	validate bool
+	// force makes GenerateAll write plists whose config has not changed
+	force bool
+	// complete means GenerateAll is given every daemon in the config, so
+	// the manifest keeps no other daemons
+	complete bool
+}
+
+// Summary reports which plists GenerateAll wrote, by daemon name
+type Summary struct {
+	Added     []string `json:"added" yaml:"added"`
+	Changed   []string `json:"changed" yaml:"changed"`
+	Unchanged []string `json:"unchanged" yaml:"unchanged"`
+}
+
+// Written returns the daemons whose plist was added or changed
+func (s *Summary) Written() []string {
+	return append(append([]string{}, s.Added...), s.Changed...)
 }
 
 // NewGenerator creates a new plist generator
@@ -24,41 +49,126 @@ func NewGenerator(outputDir string) *Generator {
 	}
 }
 
-// GenerateAll generates plist files for all daemons
-func (g *Generator) GenerateAll(daemons []config.Daemon) error {
+// WithEnvironment sets environment variables added to every generated
+// plist. Variables a daemon defines itself take precedence.
+func (g *Generator) WithEnvironment(env map[string]string) *Generator {
+	g.environment = env
+	return g
+}
+
+// WithValidation enables checking plists against launchd's rules before
+// GenerateAll writes them
+func (g *Generator) WithValidation(enabled bool) *Generator {
+	g.validate = enabled
+	return g
+}
+
+// WithForce makes GenerateAll write every plist, even those whose config
+// has not changed
+func (g *Generator) WithForce(enabled bool) *Generator {
+	g.force = enabled
+	return g
+}
+
+// WithComplete tells GenerateAll it is given every daemon in the config,
+// so manifest entries for removed daemons are dropped
+func (g *Generator) WithComplete(enabled bool) *Generator {
+	g.complete = enabled
+	return g
+}
+
+// GenerateAll generates plist files for all daemons, writing only those
+// whose resolved config changed since the last run. A manifest in the
+// output directory records the config each plist was generated from. With
+// validation enabled, nothing is written unless every plist is valid.
+func (g *Generator) GenerateAll(daemons []config.Daemon) (*Summary, error) {
+	if g.validate {
+		for _, daemon := range daemons {
+			if err := g.Validate(&daemon); err != nil {
+				return nil, fmt.Errorf("invalid plist for %s: %w", daemon.Name, err)
+			}
+		}
+	}
+
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(g.outputDir, 0750); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	m := readManifest(g.outputDir)
+	summary := &Summary{Added: []string{}, Changed: []string{}, Unchanged: []string{}}
 	for _, daemon := range daemons {
-		if err := g.Generate(&daemon); err != nil {
-			return fmt.Errorf("failed to generate plist for %s: %w", daemon.Name, err)
+		configHash := g.ConfigHash(&daemon)
+		existing, readErr := os.ReadFile(g.outputPath(&daemon)) // #nosec G304 - path is in the output directory
+		entry, known := m.Daemons[daemon.Name]
+
+		// The plist on disk is still the one generated from this config
+		if !g.force && readErr == nil && known && entry.ConfigHash == configHash && history.Hash(existing) == entry.PlistHash {
+			summary.Unchanged = append(summary.Unchanged, daemon.Name)
+			continue
+		}
+
+		data, err := g.Render(&daemon)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate plist for %s: %w", daemon.Name, err)
+		}
+		m.Daemons[daemon.Name] = manifestEntry{ConfigHash: configHash, PlistHash: history.Hash(data)}
+
+		switch {
+		case readErr == nil && !g.force && bytes.Equal(existing, data):
+			summary.Unchanged = append(summary.Unchanged, daemon.Name)
+			continue
+		case readErr == nil:
+			summary.Changed = append(summary.Changed, daemon.Name)
+		default:
+			summary.Added = append(summary.Added, daemon.Name)
+		}
+
+		if err := g.write(&daemon, data); err != nil {
+			return nil, fmt.Errorf("failed to generate plist for %s: %w", daemon.Name, err)
 		}
 	}
 
-	return nil
+	if g.complete {
+		generated := make(map[string]bool, len(daemons))
+		for _, daemon := range daemons {
+			generated[daemon.Name] = true
+		}
+		for name := range m.Daemons {
+			if !generated[name] {
+				delete(m.Daemons, name)
+			}
+		}
+	}
+
+	if err := m.write(g.outputDir); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// ConfigHash returns the hash of a daemon's config as it is rendered, with
+// the generator's environment merged in
+func (g *Generator) ConfigHash(daemon *config.Daemon) string {
+	resolved := *daemon
+	resolved.EnvironmentVariables = daemon.EffectiveEnvironment(g.environment)
+	return resolved.Hash()
 }
 
 // Generate creates a plist file for a single daemon
 func (g *Generator) Generate(daemon *config.Daemon) error {
-	plist := g.daemonToPlist(daemon)
-
-	// Marshal to XML
-	var buf bytes.Buffer
-	buf.WriteString(xml.Header)
-	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
-
-	encoder := xml.NewEncoder(&buf)
-	encoder.Indent("", "    ")
-
-	if err := encoder.Encode(plist); err != nil {
-		return fmt.Errorf("failed to encode plist: %w", err)
+	data, err := g.Render(daemon)
+	if err != nil {
+		return err
 	}
 
-	// Write to file
-	outputPath := filepath.Join(g.outputDir, daemon.Name+".plist")
-	if err := os.WriteFile(outputPath, buf.Bytes(), 0600); err != nil {
+	return g.write(daemon, data)
+}
+
+// write saves a daemon's rendered plist to the output directory
+func (g *Generator) write(daemon *config.Daemon, data []byte) error {
+	outputPath := g.outputPath(daemon)
+	if err := utils.WriteFileAtomic(outputPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write plist file: %w", err)
 	}
 
@@ -70,6 +180,44 @@ func (g *Generator) Generate(daemon *config.Daemon) error {
 	return nil
 }
 
+// outputPath returns the path a daemon's plist is generated at
+func (g *Generator) outputPath(daemon *config.Daemon) string {
+	return filepath.Join(g.outputDir, daemon.Name+".plist")
+}
+
+// Build converts a daemon config to its plist structure without writing it
+func (g *Generator) Build(daemon *config.Daemon) *Plist {
+	return g.daemonToPlist(daemon)
+}
+
+// Validate checks the plist generated for a daemon against launchd's
+// rules. The daemon's extra_plist_keys are accepted as given.
+func (g *Generator) Validate(daemon *config.Daemon) error {
+	validator := Validator{AllowedKeys: sortedKeys(daemon.ExtraPlistKeys)}
+	return ProblemsError(validator.Validate(g.daemonToPlist(daemon).Dict))
+}
+
+// Render returns the plist XML for a daemon without writing it
+func (g *Generator) Render(daemon *config.Daemon) ([]byte, error) {
+	return Encode(g.daemonToPlist(daemon))
+}
+
+// Encode marshals a plist to XML with the standard header and doctype
+func Encode(plist *Plist) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "    ")
+
+	if err := encoder.Encode(plist); err != nil {
+		return nil, fmt.Errorf("failed to encode plist: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
 // daemonToPlist converts a daemon config to plist structure
 func (g *Generator) daemonToPlist(daemon *config.Daemon) *Plist {
 	dict := &Dict{}
@@ -90,10 +238,10 @@ func (g *Generator) daemonToPlist(daemon *config.Daemon) *Plist {
 	}
 
 	// Environment Variables
-	if len(daemon.EnvironmentVariables) > 0 {
+	if env := daemon.EffectiveEnvironment(g.environment); len(env) > 0 {
 		envDict := &Dict{}
-		for k, v := range daemon.EnvironmentVariables {
-			envDict.AddString(k, v)
+		for _, k := range sortedKeys(env) {
+			envDict.AddString(k, env[k])
 		}
 		dict.AddDict("EnvironmentVariables", envDict)
 	}
@@ -116,7 +264,9 @@ func (g *Generator) daemonToPlist(daemon *config.Daemon) *Plist {
 	}
 
 	// Keep Alive
-	if daemon.KeepAlive != nil {
+	if daemon.KeepAlive != nil && daemon.KeepAlive.Always != nil {
+		dict.AddBool("KeepAlive", *daemon.KeepAlive.Always)
+	} else if daemon.KeepAlive != nil {
 		keepAliveDict := &Dict{}
 
 		if daemon.KeepAlive.SuccessfulExit != nil {
@@ -135,8 +285,8 @@ func (g *Generator) daemonToPlist(daemon *config.Daemon) *Plist {
 		// PathState
 		if len(daemon.KeepAlive.PathState) > 0 {
 			pathDict := &Dict{}
-			for path, state := range daemon.KeepAlive.PathState {
-				pathDict.AddBool(path, state)
+			for _, path := range sortedKeys(daemon.KeepAlive.PathState) {
+				pathDict.AddBool(path, daemon.KeepAlive.PathState[path])
 			}
 			keepAliveDict.AddDict("PathState", pathDict)
 		}
@@ -144,8 +294,8 @@ func (g *Generator) daemonToPlist(daemon *config.Daemon) *Plist {
 		// OtherJobEnabled
 		if len(daemon.KeepAlive.OtherJobEnabled) > 0 {
 			jobDict := &Dict{}
-			for job, enabled := range daemon.KeepAlive.OtherJobEnabled {
-				jobDict.AddBool(job, enabled)
+			for _, job := range sortedKeys(daemon.KeepAlive.OtherJobEnabled) {
+				jobDict.AddBool(job, daemon.KeepAlive.OtherJobEnabled[job])
 			}
 			keepAliveDict.AddDict("OtherJobEnabled", jobDict)
 		}
@@ -181,47 +331,19 @@ func (g *Generator) daemonToPlist(daemon *config.Daemon) *Plist {
 	}
 
 	// Resource Limits
-	if daemon.ResourceLimits != nil {
-		limitsDict := &Dict{}
-
-		if daemon.ResourceLimits.CPU != nil {
-			limitsDict.AddInteger("CPU", *daemon.ResourceLimits.CPU)
-		}
-		if daemon.ResourceLimits.FileSize != nil {
-			limitsDict.AddInteger("FileSize", *daemon.ResourceLimits.FileSize)
-		}
-		if daemon.ResourceLimits.NumberOfFiles != nil {
-			limitsDict.AddInteger("NumberOfFiles", *daemon.ResourceLimits.NumberOfFiles)
-		}
-		if daemon.ResourceLimits.Core != nil {
-			limitsDict.AddInteger("Core", *daemon.ResourceLimits.Core)
-		}
-		if daemon.ResourceLimits.Data != nil {
-			limitsDict.AddInteger("Data", *daemon.ResourceLimits.Data)
-		}
-		if daemon.ResourceLimits.MemoryLock != nil {
-			limitsDict.AddInteger("MemoryLock", *daemon.ResourceLimits.MemoryLock)
-		}
-		if daemon.ResourceLimits.NumberOfProcesses != nil {
-			limitsDict.AddInteger("NumberOfProcesses", *daemon.ResourceLimits.NumberOfProcesses)
-		}
-		if daemon.ResourceLimits.ResidentSetSize != nil {
-			limitsDict.AddInteger("ResidentSetSize", *daemon.ResourceLimits.ResidentSetSize)
-		}
-		if daemon.ResourceLimits.Stack != nil {
-			limitsDict.AddInteger("Stack", *daemon.ResourceLimits.Stack)
-		}
-
-		if len(limitsDict.Items) > 0 {
-			dict.AddDict("SoftResourceLimits", limitsDict)
-			dict.AddDict("HardResourceLimits", limitsDict) // Same as soft for now
-		}
+	soft, hard := daemon.EffectiveResourceLimits()
+	if softDict := g.resourceLimitsToDict(soft); len(softDict.Items) > 0 {
+		dict.AddDict("SoftResourceLimits", softDict)
+	}
+	if hardDict := g.resourceLimitsToDict(hard); len(hardDict.Items) > 0 {
+		dict.AddDict("HardResourceLimits", hardDict)
 	}
 
 	// Socket Activation
 	if len(daemon.Sockets) > 0 {
 		socketsDict := &Dict{}
-		for name, socket := range daemon.Sockets {
+		for _, name := range sortedKeys(daemon.Sockets) {
+			socket := daemon.Sockets[name]
 			socketDict := &Dict{}
 
 			if socket.SockType != "" {
@@ -305,12 +427,156 @@ func (g *Generator) daemonToPlist(daemon *config.Daemon) *Plist {
 		dict.AddInteger("ExitTimeOut", daemon.ExitTimeOut)
 	}
 
+	// Launch Constraints
+	if daemon.Disabled {
+		dict.AddBool("Disabled", daemon.Disabled)
+	}
+	if len(daemon.LimitLoadToSessionType) == 1 {
+		dict.AddString("LimitLoadToSessionType", daemon.LimitLoadToSessionType[0])
+	} else if len(daemon.LimitLoadToSessionType) > 1 {
+		dict.AddStringArray("LimitLoadToSessionType", daemon.LimitLoadToSessionType)
+	}
+	if len(daemon.LimitLoadToHosts) > 0 {
+		dict.AddStringArray("LimitLoadToHosts", daemon.LimitLoadToHosts)
+	}
+
+	// Mach Services
+	if len(daemon.MachServices) > 0 {
+		servicesDict := &Dict{}
+		for _, name := range sortedKeys(daemon.MachServices) {
+			service := daemon.MachServices[name]
+			if service.IsEmpty() {
+				servicesDict.AddBool(name, true)
+				continue
+			}
+
+			serviceDict := &Dict{}
+			if service.ResetAtClose != nil {
+				serviceDict.AddBool("ResetAtClose", *service.ResetAtClose)
+			}
+			if service.HideUntilCheckIn != nil {
+				serviceDict.AddBool("HideUntilCheckIn", *service.HideUntilCheckIn)
+			}
+			servicesDict.AddDict(name, serviceDict)
+		}
+		dict.AddDict("MachServices", servicesDict)
+	}
+
+	// Launch Events
+	if len(daemon.LaunchEvents) > 0 {
+		eventsDict := &Dict{}
+		for _, subsystem := range sortedKeys(daemon.LaunchEvents) {
+			subsystemDict := &Dict{}
+			for _, event := range sortedKeys(daemon.LaunchEvents[subsystem]) {
+				if err := subsystemDict.AddValue(event, daemon.LaunchEvents[subsystem][event]); err != nil {
+					log.Warn().
+						Err(err).
+						Str("daemon", daemon.Name).
+						Str("event", subsystem+"."+event).
+						Msg("Skipping launch event")
+				}
+			}
+			eventsDict.AddDict(subsystem, subsystemDict)
+		}
+		dict.AddDict("LaunchEvents", eventsDict)
+	}
+	if daemon.StartOnMount {
+		dict.AddBool("StartOnMount", daemon.StartOnMount)
+	}
+
+	// inetd Compatibility
+	if daemon.InetdCompatibility != nil {
+		inetdDict := &Dict{}
+		wait := daemon.InetdCompatibility.Wait != nil && *daemon.InetdCompatibility.Wait
+		inetdDict.AddBool("Wait", wait)
+		dict.AddDict("inetdCompatibility", inetdDict)
+	}
+
+	// Process Behavior
+	if daemon.Umask != nil {
+		dict.AddInteger("Umask", *daemon.Umask)
+	}
+	if daemon.AbandonProcessGroup {
+		dict.AddBool("AbandonProcessGroup", daemon.AbandonProcessGroup)
+	}
+	if daemon.LowPriorityIO {
+		dict.AddBool("LowPriorityIO", daemon.LowPriorityIO)
+	}
+	if daemon.LowPriorityBackgroundIO {
+		dict.AddBool("LowPriorityBackgroundIO", daemon.LowPriorityBackgroundIO)
+	}
+	if daemon.SessionCreate {
+		dict.AddBool("SessionCreate", daemon.SessionCreate)
+	}
+
+	// Debugging
+	if daemon.Debug {
+		dict.AddBool("Debug", daemon.Debug)
+	}
+	if daemon.WaitForDebugger {
+		dict.AddBool("WaitForDebugger", daemon.WaitForDebugger)
+	}
+
+	// Bundle Association
+	if len(daemon.AssociatedBundleIdentifiers) > 0 {
+		dict.AddStringArray("AssociatedBundleIdentifiers", daemon.AssociatedBundleIdentifiers)
+	}
+
+	// Raw passthrough keys
+	for _, key := range sortedKeys(daemon.ExtraPlistKeys) {
+		if err := dict.AddValue(key, daemon.ExtraPlistKeys[key]); err != nil {
+			log.Warn().
+				Err(err).
+				Str("daemon", daemon.Name).
+				Str("key", key).
+				Msg("Skipping extra plist key")
+		}
+	}
+
 	return &Plist{
 		Version: "1.0",
 		Dict:    dict,
 	}
 }
 
+// resourceLimitsToDict converts resource limits to a dict
+func (g *Generator) resourceLimitsToDict(limits *config.ResourceLimits) *Dict {
+	dict := &Dict{}
+	if limits == nil {
+		return dict
+	}
+
+	if limits.CPU != nil {
+		dict.AddInteger("CPU", *limits.CPU)
+	}
+	if limits.FileSize != nil {
+		dict.AddInteger("FileSize", int(*limits.FileSize))
+	}
+	if limits.NumberOfFiles != nil {
+		dict.AddInteger("NumberOfFiles", *limits.NumberOfFiles)
+	}
+	if limits.Core != nil {
+		dict.AddInteger("Core", int(*limits.Core))
+	}
+	if limits.Data != nil {
+		dict.AddInteger("Data", int(*limits.Data))
+	}
+	if limits.MemoryLock != nil {
+		dict.AddInteger("MemoryLock", int(*limits.MemoryLock))
+	}
+	if limits.NumberOfProcesses != nil {
+		dict.AddInteger("NumberOfProcesses", *limits.NumberOfProcesses)
+	}
+	if limits.ResidentSetSize != nil {
+		dict.AddInteger("ResidentSetSize", int(*limits.ResidentSetSize))
+	}
+	if limits.Stack != nil {
+		dict.AddInteger("Stack", int(*limits.Stack))
+	}
+
+	return dict
+}
+
 // calendarIntervalToDict converts a calendar interval to a dict
 func (g *Generator) calendarIntervalToDict(interval config.CalendarInterval) *Dict {
 	dict := &Dict{}
@@ -333,3 +599,13 @@ func (g *Generator) calendarIntervalToDict(interval config.CalendarInterval) *Di
 
 	return dict
 }
+
+// sortedKeys returns the keys of a string-keyed map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
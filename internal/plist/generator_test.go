@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -112,7 +113,7 @@ func TestGenerator_Generate(t *testing.T) {
 				Program: "/usr/bin/limited",
 				ResourceLimits: &config.ResourceLimits{
 					CPU:               intPtr(80),
-					FileSize:          intPtr(1048576),
+					FileSize:          sizePtr(1048576),
 					NumberOfFiles:     intPtr(256),
 					NumberOfProcesses: intPtr(64),
 				},
@@ -249,6 +250,157 @@ func TestGenerator_Generate(t *testing.T) {
 				assert.Contains(t, content, "<key>ExitTimeOut</key>")
 			},
 		},
+		{
+			name: "daemon with separate soft and hard limits",
+			daemon: config.Daemon{
+				Name:    "split-limits",
+				Label:   "com.example.split-limits",
+				Program: "/usr/bin/split",
+				ResourceLimits: &config.ResourceLimits{
+					NumberOfFiles: intPtr(256),
+				},
+				SoftResourceLimits: &config.ResourceLimits{
+					ResidentSetSize: sizePtr(256 << 20),
+				},
+				HardResourceLimits: &config.ResourceLimits{
+					ResidentSetSize: sizePtr(512 << 20),
+				},
+			},
+			wantError: false,
+			validate: func(t *testing.T, content string) {
+				softStart := strings.Index(content, "<key>SoftResourceLimits</key>")
+				hardStart := strings.Index(content, "<key>HardResourceLimits</key>")
+				require.NotEqual(t, -1, softStart)
+				require.NotEqual(t, -1, hardStart)
+				require.Less(t, softStart, hardStart)
+
+				soft := content[softStart:hardStart]
+				hard := content[hardStart:]
+				assert.Contains(t, soft, "<integer>268435456</integer>")
+				assert.Contains(t, soft, "<key>NumberOfFiles</key>")
+				assert.Contains(t, hard, "<integer>536870912</integer>")
+				assert.Contains(t, hard, "<key>NumberOfFiles</key>")
+			},
+		},
+		{
+			name: "daemon with keep alive boolean",
+			daemon: config.Daemon{
+				Name:    "always-daemon",
+				Label:   "com.example.always",
+				Program: "/usr/bin/always",
+				KeepAlive: &config.KeepAlive{
+					Always: boolPtr(true),
+				},
+			},
+			wantError: false,
+			validate: func(t *testing.T, content string) {
+				assert.Contains(t, content, "<key>KeepAlive</key>\n        <true></true>")
+			},
+		},
+		{
+			name: "daemon with extended launchd keys",
+			daemon: config.Daemon{
+				Name:                   "extended-daemon",
+				Label:                  "com.example.extended",
+				Program:                "/usr/bin/extended",
+				Disabled:               true,
+				LimitLoadToSessionType: []string{"Aqua"},
+				LimitLoadToHosts:       []string{"build-host"},
+				MachServices: map[string]config.MachService{
+					"com.example.extended.xpc":   {},
+					"com.example.extended.reset": {ResetAtClose: boolPtr(true)},
+				},
+				LaunchEvents: map[string]map[string]map[string]interface{}{
+					"com.apple.iokit.matching": {
+						"usb-device": {
+							"IOProviderClass":     "IOUSBDevice",
+							"idVendor":            1452,
+							"IOMatchLaunchStream": true,
+						},
+					},
+				},
+				StartOnMount: true,
+				Sockets: map[string]config.Socket{
+					"listener": {SockServiceName: "8080"},
+				},
+				InetdCompatibility:          &config.InetdCompatibility{Wait: boolPtr(true)},
+				Umask:                       intPtr(0o022),
+				AbandonProcessGroup:         true,
+				LowPriorityIO:               true,
+				LowPriorityBackgroundIO:     true,
+				SessionCreate:               true,
+				Debug:                       true,
+				WaitForDebugger:             true,
+				AssociatedBundleIdentifiers: []string{"com.example.app"},
+			},
+			wantError: false,
+			validate: func(t *testing.T, content string) {
+				assert.Contains(t, content, "<key>Disabled</key>")
+				assert.Contains(t, content, "<key>LimitLoadToSessionType</key>\n        <string>Aqua</string>")
+				assert.Contains(t, content, "<key>LimitLoadToHosts</key>")
+				assert.Contains(t, content, "<key>MachServices</key>")
+				assert.Contains(t, content, "<key>com.example.extended.xpc</key>\n            <true></true>")
+				assert.Contains(t, content, "<key>ResetAtClose</key>")
+				assert.Contains(t, content, "<key>LaunchEvents</key>")
+				assert.Contains(t, content, "<key>com.apple.iokit.matching</key>")
+				assert.Contains(t, content, "<key>IOProviderClass</key>")
+				assert.Contains(t, content, "<integer>1452</integer>")
+				assert.Contains(t, content, "<key>StartOnMount</key>")
+				assert.Contains(t, content, "<key>inetdCompatibility</key>")
+				assert.Contains(t, content, "<key>Wait</key>")
+				assert.Contains(t, content, "<key>Umask</key>\n        <integer>18</integer>")
+				assert.Contains(t, content, "<key>AbandonProcessGroup</key>")
+				assert.Contains(t, content, "<key>LowPriorityIO</key>")
+				assert.Contains(t, content, "<key>LowPriorityBackgroundIO</key>")
+				assert.Contains(t, content, "<key>SessionCreate</key>")
+				assert.Contains(t, content, "<key>Debug</key>")
+				assert.Contains(t, content, "<key>WaitForDebugger</key>")
+				assert.Contains(t, content, "<key>AssociatedBundleIdentifiers</key>")
+			},
+		},
+		{
+			name: "daemon with extra plist keys",
+			daemon: config.Daemon{
+				Name:    "extra-daemon",
+				Label:   "com.example.extra",
+				Program: "/usr/bin/extra",
+				ExtraPlistKeys: map[string]interface{}{
+					"ProcessPriority": 5,
+					"Weight":          0.75,
+					"Since":           time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					"Token":           []byte("hello"),
+					"Flag":            true,
+					"Nested": map[string]interface{}{
+						"List": []interface{}{"a", 1},
+					},
+				},
+			},
+			wantError: false,
+			validate: func(t *testing.T, content string) {
+				assert.Contains(t, content, "<key>ProcessPriority</key>\n        <integer>5</integer>")
+				assert.Contains(t, content, "<key>Weight</key>\n        <real>0.75</real>")
+				assert.Contains(t, content, "<key>Since</key>\n        <date>2024-01-02T03:04:05Z</date>")
+				assert.Contains(t, content, "<key>Token</key>\n        <data>aGVsbG8=</data>")
+				assert.Contains(t, content, "<key>Flag</key>\n        <true></true>")
+				assert.Contains(t, content, "<key>Nested</key>\n        <dict>\n            <key>List</key>")
+
+				// Passthrough keys are written in sorted order
+				assert.Less(t, strings.Index(content, "<key>Flag</key>"), strings.Index(content, "<key>Weight</key>"))
+			},
+		},
+		{
+			name: "multiple session types",
+			daemon: config.Daemon{
+				Name:                   "sessions-daemon",
+				Label:                  "com.example.sessions",
+				Program:                "/usr/bin/sessions",
+				LimitLoadToSessionType: []string{"Aqua", "Background"},
+			},
+			wantError: false,
+			validate: func(t *testing.T, content string) {
+				assert.Contains(t, content, "<key>LimitLoadToSessionType</key>\n        <array>")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -313,8 +465,9 @@ func TestGenerator_GenerateAll(t *testing.T) {
 	gen := NewGenerator(tempDir)
 
 	// Generate all plists
-	err := gen.GenerateAll(daemons)
+	summary, err := gen.GenerateAll(daemons)
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"daemon1", "daemon2", "daemon3"}, summary.Added)
 
 	// Check all files were created
 	for _, daemon := range daemons {
@@ -324,6 +477,109 @@ func TestGenerator_GenerateAll(t *testing.T) {
 	}
 }
 
+func TestGenerator_GenerateAllChanged(t *testing.T) {
+	daemons := []config.Daemon{
+		{Name: "daemon1", Label: "com.example.daemon1", Program: "/usr/bin/daemon1"},
+		{Name: "daemon2", Label: "com.example.daemon2", Program: "/usr/bin/daemon2"},
+		{Name: "daemon3", Label: "com.example.daemon3", Program: "/usr/bin/daemon3"},
+	}
+	tempDir := t.TempDir()
+	path := func(name string) string { return filepath.Join(tempDir, name+".plist") }
+
+	_, err := NewGenerator(tempDir).GenerateAll(daemons)
+	require.NoError(t, err)
+
+	// Backdate the plists so rewrites are visible in their mtimes
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, daemon := range daemons {
+		require.NoError(t, os.Chtimes(path(daemon.Name), past, past))
+	}
+
+	daemons[0].Program = "/usr/bin/changed"
+	require.NoError(t, os.WriteFile(path("daemon2"), []byte("edited by hand"), 0600))
+	daemons = append(daemons, config.Daemon{Name: "daemon4", Label: "com.example.daemon4", Program: "/usr/bin/daemon4"})
+
+	summary, err := NewGenerator(tempDir).GenerateAll(daemons)
+	require.NoError(t, err)
+	assert.Equal(t, &Summary{
+		Added:     []string{"daemon4"},
+		Changed:   []string{"daemon1", "daemon2"},
+		Unchanged: []string{"daemon3"},
+	}, summary)
+	assert.Equal(t, []string{"daemon4", "daemon1", "daemon2"}, summary.Written())
+
+	info, err := os.Stat(path("daemon3"))
+	require.NoError(t, err)
+	assert.Equal(t, past, info.ModTime(), "unchanged plist should not be rewritten")
+
+	data, err := os.ReadFile(path("daemon2"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "com.example.daemon2")
+
+	// The environment is part of the resolved config
+	summary, err = NewGenerator(tempDir).WithEnvironment(map[string]string{"PATH": "/usr/bin"}).GenerateAll(daemons[3:])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"daemon4"}, summary.Changed)
+
+	// Forcing rewrites plists that have not changed
+	summary, err = NewGenerator(tempDir).WithForce(true).GenerateAll(daemons[2:3])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"daemon3"}, summary.Changed)
+
+	// Without a manifest, identical plists are still left alone
+	require.NoError(t, os.Remove(filepath.Join(tempDir, manifestFile)))
+	summary, err = NewGenerator(tempDir).GenerateAll(daemons[2:3])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"daemon3"}, summary.Unchanged)
+}
+
+func TestGenerator_GenerateAllComplete(t *testing.T) {
+	daemons := []config.Daemon{
+		{Name: "daemon1", Label: "com.example.daemon1", Program: "/usr/bin/daemon1"},
+		{Name: "daemon2", Label: "com.example.daemon2", Program: "/usr/bin/daemon2"},
+	}
+	tempDir := t.TempDir()
+
+	_, err := NewGenerator(tempDir).GenerateAll(daemons)
+	require.NoError(t, err)
+
+	// A subset keeps the other daemons' entries
+	_, err = NewGenerator(tempDir).GenerateAll(daemons[:1])
+	require.NoError(t, err)
+	assert.Len(t, readManifest(tempDir).Daemons, 2)
+
+	// The whole config drops entries for removed daemons
+	_, err = NewGenerator(tempDir).WithComplete(true).GenerateAll(daemons[:1])
+	require.NoError(t, err)
+	m := readManifest(tempDir)
+	assert.Contains(t, m.Daemons, "daemon1")
+	assert.NotContains(t, m.Daemons, "daemon2")
+}
+
+func TestGenerator_WithEnvironment(t *testing.T) {
+	gen := NewGenerator("").WithEnvironment(map[string]string{
+		"PATH": "/usr/bin:/bin",
+		"LANG": "en_US.UTF-8",
+	})
+
+	p := gen.Build(&config.Daemon{
+		Name:                 "test",
+		Label:                "com.example.test",
+		Program:              "/usr/bin/test",
+		EnvironmentVariables: map[string]string{"PATH": "/opt/test/bin"},
+	})
+
+	env, ok := p.Dict.Get("EnvironmentVariables")
+	require.True(t, ok)
+	envDict, ok := env.(*Dict)
+	require.True(t, ok)
+
+	path, _ := envDict.GetString("PATH")
+	lang, _ := envDict.GetString("LANG")
+	assert.Equal(t, "/opt/test/bin", path)
+	assert.Equal(t, "en_US.UTF-8", lang)
+}
+
 func TestGenerator_DirectoryCreation(t *testing.T) {
 	// Use a nested path that doesn't exist
 	tempDir := t.TempDir()
@@ -337,7 +593,7 @@ func TestGenerator_DirectoryCreation(t *testing.T) {
 	}
 
 	// GenerateAll creates the directory, not Generate
-	err := gen.GenerateAll([]config.Daemon{daemon})
+	_, err := gen.GenerateAll([]config.Daemon{daemon})
 	assert.NoError(t, err)
 
 	// Check directory exists
@@ -367,6 +623,64 @@ func TestGenerator_FilePermissions(t *testing.T) {
 	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
 }
 
+func TestModeledPlistKeysCoverGenerator(t *testing.T) {
+	daemon := config.Daemon{
+		Name:                 "everything",
+		Label:                "com.example.everything",
+		ProgramArguments:     []string{"/usr/bin/everything"},
+		WorkingDirectory:     "/tmp",
+		EnvironmentVariables: map[string]string{"A": "b"},
+		StandardOutPath:      "/tmp/out.log",
+		StandardErrorPath:    "/tmp/err.log",
+		RunAtLoad:            true,
+		StartInterval:        60,
+		KeepAlive:            &config.KeepAlive{Crashed: boolPtr(true)},
+		ThrottleInterval:     10,
+		ResourceLimits:       &config.ResourceLimits{CPU: intPtr(1)},
+		ProcessType:          "Background",
+		Nice:                 intPtr(1),
+		InitGroups:           true,
+		UserName:             "nobody",
+		GroupName:            "nobody",
+		RootDirectory:        "/",
+		Sockets:              map[string]config.Socket{"s": {SockServiceName: "80"}},
+		StartCalendarInterval: []config.CalendarInterval{
+			{Hour: intPtr(1)},
+		},
+		WatchPaths:                  []string{"/tmp"},
+		QueuePaths:                  []string{"/tmp"},
+		EnableGlobbing:              true,
+		EnableTransactions:          true,
+		EnablePressuredExit:         true,
+		ExitTimeOut:                 5,
+		Disabled:                    true,
+		LimitLoadToSessionType:      []string{"Aqua"},
+		LimitLoadToHosts:            []string{"host"},
+		MachServices:                map[string]config.MachService{"com.example.xpc": {}},
+		LaunchEvents:                map[string]map[string]map[string]interface{}{"com.apple.notifyd.matching": {"e": {"Notification": "n"}}},
+		StartOnMount:                true,
+		InetdCompatibility:          &config.InetdCompatibility{},
+		Umask:                       intPtr(0o22),
+		AbandonProcessGroup:         true,
+		LowPriorityIO:               true,
+		LowPriorityBackgroundIO:     true,
+		SessionCreate:               true,
+		Debug:                       true,
+		WaitForDebugger:             true,
+		AssociatedBundleIdentifiers: []string{"com.example.app"},
+	}
+
+	plist := NewGenerator("").daemonToPlist(&daemon)
+	for i := 0; i < len(plist.Dict.Items); i += 2 {
+		key, ok := plist.Dict.Items[i].(Key)
+		require.True(t, ok)
+		_, modeled := config.ModeledPlistKeys[key.Value]
+		assert.True(t, modeled, "generated key %s missing from config.ModeledPlistKeys", key.Value)
+	}
+	// Everything the generator writes passes launchd validation
+	assert.Empty(t, Validator{}.Validate(plist.Dict))
+}
+
 func TestCalendarIntervalToDict(t *testing.T) {
 	gen := NewGenerator("")
 
@@ -425,3 +739,8 @@ func intPtr(i int) *int {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func sizePtr(n int64) *config.ByteSize {
+	size := config.ByteSize(n)
+	return &size
+}
@@ -0,0 +1,60 @@
+package plist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mjmorales/daemon-control/internal/utils"
+)
+
+// manifestFile records what generate last wrote to the output directory
+const manifestFile = ".manifest.json"
+
+// manifestFormat identifies the plist output of this generator. Bump it
+// whenever the generator renders the same config differently, so plists
+// from older builds are regenerated.
+const manifestFormat = 1
+
+// manifestEntry records the config a plist was generated from and the
+// plist written
+type manifestEntry struct {
+	ConfigHash string `json:"config_hash"`
+	PlistHash  string `json:"plist_hash"`
+}
+
+// manifest maps daemon names to their last generated plist
+type manifest struct {
+	Format  int                      `json:"format"`
+	Daemons map[string]manifestEntry `json:"daemons"`
+}
+
+// readManifest loads the output directory's manifest. A missing or
+// unreadable manifest, or one from another format, is empty, which makes
+// every plist count as changed.
+func readManifest(dir string) *manifest {
+	empty := &manifest{Format: manifestFormat, Daemons: map[string]manifestEntry{}}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile)) // #nosec G304 - path is in the output directory
+	if err != nil {
+		return empty
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil || m.Format != manifestFormat || m.Daemons == nil {
+		return empty
+	}
+	return &m
+}
+
+// write saves the manifest to the output directory
+func (m *manifest) write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(filepath.Join(dir, manifestFile), append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write generate manifest: %w", err)
+	}
+	return nil
+}
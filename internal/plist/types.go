@@ -1,8 +1,13 @@
 package plist
 
 import (
+	"encoding/base64"
 	"encoding/xml"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
 )
 
 // Plist represents the root plist structure
@@ -50,6 +55,50 @@ type Integer struct {
 	Value   int      `xml:",chardata"`
 }
 
+// Real represents a floating point element
+type Real struct {
+	Value float64
+}
+
+// Date represents a date element, stored in UTC with second precision
+type Date struct {
+	Value time.Time
+}
+
+// Data represents a base64-encoded data element
+type Data struct {
+	Value []byte
+}
+
+// dateFormat is the ISO 8601 form used by plist dates
+const dateFormat = "2006-01-02T15:04:05Z"
+
+// MarshalXML custom marshaler for Real
+func (r Real) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	var text string
+	switch {
+	case math.IsInf(r.Value, 1):
+		text = "+infinity"
+	case math.IsInf(r.Value, -1):
+		text = "-infinity"
+	case math.IsNaN(r.Value):
+		text = "nan"
+	default:
+		text = strconv.FormatFloat(r.Value, 'g', -1, 64)
+	}
+	return e.EncodeElement(text, xml.StartElement{Name: xml.Name{Local: "real"}})
+}
+
+// MarshalXML custom marshaler for Date
+func (d Date) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(d.Value.UTC().Format(dateFormat), xml.StartElement{Name: xml.Name{Local: "date"}})
+}
+
+// MarshalXML custom marshaler for Data
+func (d Data) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(base64.StdEncoding.EncodeToString(d.Value), xml.StartElement{Name: xml.Name{Local: "data"}})
+}
+
 // True represents a boolean true element
 type True struct {
 	XMLName xml.Name `xml:"true"`
@@ -109,6 +158,146 @@ func (d *Dict) AddDictArray(key string, dicts []*Dict) {
 	d.Items = append(d.Items, Key{Value: key}, array)
 }
 
+// AddValue adds a key with an arbitrary value, converting Go values
+// (as decoded from YAML) to their plist element types
+func (d *Dict) AddValue(key string, value interface{}) error {
+	element, err := Value(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	d.Items = append(d.Items, Key{Value: key}, element)
+	return nil
+}
+
+// Keys returns the dictionary keys in document order
+func (d *Dict) Keys() []string {
+	keys := make([]string, 0, len(d.Items)/2)
+	for i := 0; i+1 < len(d.Items); i += 2 {
+		if key, ok := d.Items[i].(Key); ok {
+			keys = append(keys, key.Value)
+		}
+	}
+	return keys
+}
+
+// Get returns the value stored under key
+func (d *Dict) Get(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(d.Items); i += 2 {
+		if k, ok := d.Items[i].(Key); ok && k.Value == key {
+			return d.Items[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// GetString returns the string stored under key
+func (d *Dict) GetString(key string) (string, bool) {
+	value, ok := d.Get(key)
+	if !ok {
+		return "", false
+	}
+	s, ok := value.(String)
+	return s.Value, ok
+}
+
+// Value converts a Go value to the matching plist element. Maps become
+// dicts with sorted keys and slices become arrays.
+func Value(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return String{Value: v}, nil
+	case bool:
+		if v {
+			return True{}, nil
+		}
+		return False{}, nil
+	case int:
+		return Integer{Value: v}, nil
+	case int64:
+		return Integer{Value: int(v)}, nil
+	case uint64:
+		return Integer{Value: int(v)}, nil
+	case float64:
+		return Real{Value: v}, nil
+	case float32:
+		return Real{Value: float64(v)}, nil
+	case time.Time:
+		return Date{Value: v}, nil
+	case []byte:
+		return Data{Value: v}, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		dict := &Dict{}
+		for _, k := range keys {
+			if err := dict.AddValue(k, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	case []interface{}:
+		array := &Array{}
+		for i, item := range v {
+			element, err := Value(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			array.Items = append(array.Items, element)
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unsupported plist value type %T", value)
+	}
+}
+
+// GoValue converts a plist element back to the Go value Value accepts.
+// Dicts become maps and arrays become slices.
+func GoValue(element interface{}) (interface{}, error) {
+	switch v := element.(type) {
+	case String:
+		return v.Value, nil
+	case Integer:
+		return v.Value, nil
+	case Real:
+		return v.Value, nil
+	case Date:
+		return v.Value, nil
+	case Data:
+		return v.Value, nil
+	case True:
+		return true, nil
+	case False:
+		return false, nil
+	case *Dict:
+		m := make(map[string]interface{}, len(v.Items)/2)
+		for _, key := range v.Keys() {
+			item, _ := v.Get(key)
+			value, err := GoValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			m[key] = value
+		}
+		return m, nil
+	case *Array:
+		items := make([]interface{}, 0, len(v.Items))
+		for i, item := range v.Items {
+			value, err := GoValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unsupported plist element %T", element)
+	}
+}
+
 // MarshalXML custom marshaler for Array
 func (a *Array) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	if err := e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "array"}}); err != nil {
@@ -124,24 +313,8 @@ func (a *Array) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "array"}})
 }
 
-// Validate checks if the plist structure is valid
+// Validate checks the plist against the keys, value types and key
+// combinations launchd accepts. Use a Validator to allow extra keys.
 func (p *Plist) Validate() error {
-	if p.Dict == nil {
-		return fmt.Errorf("plist dict is nil")
-	}
-
-	// Check for required Label key
-	hasLabel := false
-	for i := 0; i < len(p.Dict.Items); i += 2 {
-		if key, ok := p.Dict.Items[i].(Key); ok && key.Value == "Label" {
-			hasLabel = true
-			break
-		}
-	}
-
-	if !hasLabel {
-		return fmt.Errorf("plist missing required Label key")
-	}
-
-	return nil
+	return ProblemsError(Validator{}.Validate(p.Dict))
 }
@@ -2,8 +2,10 @@ package plist
 
 import (
 	"encoding/xml"
+	"math"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -131,6 +133,134 @@ func TestDict_AddDictArray(t *testing.T) {
 	assert.Len(t, array.Items, 2)
 }
 
+func TestDict_AddValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		wantError bool
+		validate  func(*testing.T, interface{})
+	}{
+		{
+			name:  "string",
+			value: "text",
+			validate: func(t *testing.T, v interface{}) {
+				assert.Equal(t, String{Value: "text"}, v)
+			},
+		},
+		{
+			name:  "integer",
+			value: 7,
+			validate: func(t *testing.T, v interface{}) {
+				assert.Equal(t, Integer{Value: 7}, v)
+			},
+		},
+		{
+			name:  "boolean",
+			value: false,
+			validate: func(t *testing.T, v interface{}) {
+				assert.IsType(t, False{}, v)
+			},
+		},
+		{
+			name: "nested dict with sorted keys",
+			value: map[string]interface{}{
+				"b": []interface{}{"x", 1},
+				"a": true,
+			},
+			validate: func(t *testing.T, v interface{}) {
+				dict, ok := v.(*Dict)
+				require.True(t, ok)
+				require.Len(t, dict.Items, 4)
+				assert.Equal(t, Key{Value: "a"}, dict.Items[0])
+				assert.Equal(t, Key{Value: "b"}, dict.Items[2])
+
+				array, ok := dict.Items[3].(*Array)
+				require.True(t, ok)
+				assert.Equal(t, []interface{}{String{Value: "x"}, Integer{Value: 1}}, array.Items)
+			},
+		},
+		{
+			name:  "real",
+			value: 1.5,
+			validate: func(t *testing.T, v interface{}) {
+				assert.Equal(t, Real{Value: 1.5}, v)
+			},
+		},
+		{
+			name:  "date",
+			value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			validate: func(t *testing.T, v interface{}) {
+				assert.Equal(t, Date{Value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, v)
+			},
+		},
+		{
+			name:  "data",
+			value: []byte{0x00, 0xff},
+			validate: func(t *testing.T, v interface{}) {
+				assert.Equal(t, Data{Value: []byte{0x00, 0xff}}, v)
+			},
+		},
+		{
+			name:      "unsupported type",
+			value:     struct{}{},
+			wantError: true,
+		},
+		{
+			name:      "unsupported nested type",
+			value:     map[string]interface{}{"key": nil},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dict := &Dict{}
+			err := dict.AddValue("Value", tt.value)
+
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, dict.Items, 2)
+			assert.Equal(t, Key{Value: "Value"}, dict.Items[0])
+			if tt.validate != nil {
+				tt.validate(t, dict.Items[1])
+			}
+		})
+	}
+}
+
+func TestScalarTypes_MarshalXML(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "real", value: Real{Value: 0.75}, want: "<real>0.75</real>"},
+		{name: "whole real", value: Real{Value: 3}, want: "<real>3</real>"},
+		{name: "positive infinity", value: Real{Value: math.Inf(1)}, want: "<real>+infinity</real>"},
+		{name: "negative infinity", value: Real{Value: math.Inf(-1)}, want: "<real>-infinity</real>"},
+		{name: "nan", value: Real{Value: math.NaN()}, want: "<real>nan</real>"},
+		{
+			name:  "date converted to utc",
+			value: Date{Value: time.Date(2024, 1, 2, 5, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))},
+			want:  "<date>2024-01-02T03:04:05Z</date>",
+		},
+		{name: "data", value: Data{Value: []byte("hello")}, want: "<data>aGVsbG8=</data>"},
+		{name: "empty data", value: Data{}, want: "<data></data>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := xml.Marshal(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
 func TestDict_MarshalXML(t *testing.T) {
 	dict := &Dict{}
 	dict.AddString("Name", "TestDaemon")
@@ -186,6 +316,8 @@ func TestPlist_Validate(t *testing.T) {
 					Items: []interface{}{
 						Key{Value: "Label"},
 						String{Value: "com.example.test"},
+						Key{Value: "Program"},
+						String{Value: "/usr/bin/test"},
 					},
 				},
 			},
@@ -212,7 +344,7 @@ func TestPlist_Validate(t *testing.T) {
 				},
 			},
 			wantError: true,
-			errorMsg:  "plist missing required Label key",
+			errorMsg:  "Label: required key is missing",
 		},
 	}
 
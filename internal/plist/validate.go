@@ -0,0 +1,403 @@
+package plist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
+)
+
+// Element type names, as written in plist XML
+const (
+	typeString  = "string"
+	typeInteger = "integer"
+	typeReal    = "real"
+	typeBool    = "boolean"
+	typeDate    = "date"
+	typeData    = "data"
+	typeDict    = "dictionary"
+	typeArray   = "array"
+)
+
+// keySpec describes the values launchd accepts for a key
+type keySpec struct {
+	// types lists the accepted element types; empty accepts any
+	types []string
+	// fields are the known keys of a dictionary; other keys are rejected
+	fields map[string]keySpec
+	// values applies to every value of a dictionary with free-form keys
+	values *keySpec
+	// items applies to every element of an array
+	items *keySpec
+	// oneOf restricts string values
+	oneOf []string
+	// min and max bound integer values when set
+	min, max *int
+}
+
+func intBound(n int) *int {
+	return &n
+}
+
+var (
+	stringSpec  = keySpec{types: []string{typeString}}
+	integerSpec = keySpec{types: []string{typeInteger}}
+	boolSpec    = keySpec{types: []string{typeBool}}
+	anySpec     = keySpec{}
+
+	stringArraySpec = keySpec{types: []string{typeArray}, items: &stringSpec}
+	boolDictSpec    = keySpec{types: []string{typeDict}, values: &boolSpec}
+
+	resourceLimitsSpec = keySpec{types: []string{typeDict}, fields: map[string]keySpec{
+		"CPU":               integerSpec,
+		"Core":              integerSpec,
+		"Data":              integerSpec,
+		"FileSize":          integerSpec,
+		"MemoryLock":        integerSpec,
+		"NumberOfFiles":     integerSpec,
+		"NumberOfProcesses": integerSpec,
+		"ResidentSetSize":   integerSpec,
+		"Stack":             integerSpec,
+	}}
+
+	calendarIntervalSpec = keySpec{types: []string{typeDict}, fields: map[string]keySpec{
+		"Minute":  {types: []string{typeInteger}, min: intBound(0), max: intBound(59)},
+		"Hour":    {types: []string{typeInteger}, min: intBound(0), max: intBound(23)},
+		"Day":     {types: []string{typeInteger}, min: intBound(1), max: intBound(31)},
+		"Weekday": {types: []string{typeInteger}, min: intBound(0), max: intBound(7)},
+		"Month":   {types: []string{typeInteger}, min: intBound(1), max: intBound(12)},
+	}}
+
+	socketSpec = keySpec{types: []string{typeDict}, fields: map[string]keySpec{
+		"SockType":            {types: []string{typeString}, oneOf: []string{"stream", "dgram", "seqpacket"}},
+		"SockPassive":         boolSpec,
+		"SockNodeName":        stringSpec,
+		"SockServiceName":     {types: []string{typeString, typeInteger}},
+		"SockFamily":          {types: []string{typeString}, oneOf: []string{"IPv4", "IPv6", "IPv4v6", "Unix"}},
+		"SockProtocol":        {types: []string{typeString}, oneOf: []string{"TCP", "UDP"}},
+		"SockPathName":        stringSpec,
+		"SockPathOwner":       integerSpec,
+		"SockPathGroup":       integerSpec,
+		"SockPathMode":        integerSpec,
+		"SecureSocketWithKey": stringSpec,
+		"Bonjour":             {types: []string{typeBool, typeString, typeArray}, items: &stringSpec},
+		"MulticastGroup":      stringSpec,
+	}}
+
+	machServiceSpec = keySpec{types: []string{typeBool, typeDict}, fields: map[string]keySpec{
+		"ResetAtClose":     boolSpec,
+		"HideUntilCheckIn": boolSpec,
+	}}
+
+	keepAliveSpec = keySpec{types: []string{typeBool, typeDict}, fields: map[string]keySpec{
+		"SuccessfulExit":     boolSpec,
+		"NetworkState":       boolSpec,
+		"PathState":          boolDictSpec,
+		"OtherJobEnabled":    boolDictSpec,
+		"Crashed":            boolSpec,
+		"AfterInitialDemand": {types: []string{typeBool, typeDict}, values: &boolSpec},
+	}}
+
+	sessionTypeSpec = keySpec{types: []string{typeString}, oneOf: []string{"Aqua", "Background", "LoginWindow", "StandardIO", "System"}}
+)
+
+// launchdKeys lists the top-level keys documented in launchd.plist(5)
+var launchdKeys = map[string]keySpec{
+	"Label":                       stringSpec,
+	"Disabled":                    boolSpec,
+	"UserName":                    stringSpec,
+	"GroupName":                   stringSpec,
+	"inetdCompatibility":          {types: []string{typeDict}, fields: map[string]keySpec{"Wait": boolSpec}},
+	"LimitLoadToHosts":            stringArraySpec,
+	"LimitLoadFromHosts":          stringArraySpec,
+	"LimitLoadToSessionType":      {types: []string{typeString, typeArray}, oneOf: sessionTypeSpec.oneOf, items: &sessionTypeSpec},
+	"LimitLoadToHardware":         {types: []string{typeDict}, values: &stringArraySpec},
+	"LimitLoadFromHardware":       {types: []string{typeDict}, values: &stringArraySpec},
+	"Program":                     stringSpec,
+	"ProgramArguments":            stringArraySpec,
+	"EnableGlobbing":              boolSpec,
+	"EnableTransactions":          boolSpec,
+	"EnablePressuredExit":         boolSpec,
+	"OnDemand":                    boolSpec,
+	"SessionCreate":               boolSpec,
+	"KeepAlive":                   keepAliveSpec,
+	"RunAtLoad":                   boolSpec,
+	"RootDirectory":               stringSpec,
+	"WorkingDirectory":            stringSpec,
+	"EnvironmentVariables":        {types: []string{typeDict}, values: &stringSpec},
+	"Umask":                       {types: []string{typeInteger, typeString}, min: intBound(0), max: intBound(0777)},
+	"TimeOut":                     integerSpec,
+	"ExitTimeOut":                 {types: []string{typeInteger}, min: intBound(0)},
+	"ThrottleInterval":            {types: []string{typeInteger}, min: intBound(0)},
+	"InitGroups":                  boolSpec,
+	"WatchPaths":                  stringArraySpec,
+	"QueueDirectories":            stringArraySpec,
+	"StartOnMount":                boolSpec,
+	"StartInterval":               {types: []string{typeInteger}, min: intBound(1)},
+	"StartCalendarInterval":       {types: []string{typeDict, typeArray}, fields: calendarIntervalSpec.fields, items: &calendarIntervalSpec},
+	"StandardInPath":              stringSpec,
+	"StandardOutPath":             stringSpec,
+	"StandardErrorPath":           stringSpec,
+	"Debug":                       boolSpec,
+	"WaitForDebugger":             boolSpec,
+	"SoftResourceLimits":          resourceLimitsSpec,
+	"HardResourceLimits":          resourceLimitsSpec,
+	"Nice":                        {types: []string{typeInteger}, min: intBound(-20), max: intBound(20)},
+	"ProcessType":                 {types: []string{typeString}, oneOf: []string{"Background", "Standard", "Adaptive", "Interactive"}},
+	"AbandonProcessGroup":         boolSpec,
+	"LowPriorityIO":               boolSpec,
+	"LowPriorityBackgroundIO":     boolSpec,
+	"MaterializeDatalessFiles":    boolSpec,
+	"LaunchOnlyOnce":              boolSpec,
+	"MachServices":                {types: []string{typeDict}, values: &machServiceSpec},
+	"Sockets":                     {types: []string{typeDict}, values: &keySpec{types: []string{typeDict, typeArray}, fields: socketSpec.fields, items: &socketSpec}},
+	"LaunchEvents":                {types: []string{typeDict}, values: &keySpec{types: []string{typeDict}, values: &anySpec}},
+	"HopefullyExitsFirst":         boolSpec,
+	"HopefullyExitsLast":          boolSpec,
+	"ServiceIPC":                  boolSpec,
+	"AssociatedBundleIdentifiers": {types: []string{typeString, typeArray}, items: &stringSpec},
+}
+
+// Problem is a launchd validation failure at a key path
+type Problem struct {
+	Path    string `yaml:"path" json:"path"`
+	Message string `yaml:"message" json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// Validator checks plists against the keys, value types and key
+// combinations launchd accepts
+type Validator struct {
+	// AllowedKeys are top-level keys accepted without checking, in addition
+	// to the keys launchd documents
+	AllowedKeys []string
+	// AllowUnknown accepts any top-level key launchd does not document
+	AllowUnknown bool
+}
+
+// Validate returns every problem found in a plist's top-level dict
+func (v Validator) Validate(dict *Dict) []Problem {
+	if dict == nil {
+		return []Problem{{Message: "plist dict is nil"}}
+	}
+
+	allowed := make(map[string]bool, len(v.AllowedKeys))
+	for _, key := range v.AllowedKeys {
+		allowed[key] = true
+	}
+
+	var problems []Problem
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(dict.Items); i += 2 {
+		key, ok := dict.Items[i].(Key)
+		if !ok {
+			problems = append(problems, Problem{Message: fmt.Sprintf("expected a key at position %d", i)})
+			continue
+		}
+		if seen[key.Value] {
+			problems = append(problems, Problem{Path: key.Value, Message: "duplicate key"})
+		}
+		seen[key.Value] = true
+
+		spec, known := launchdKeys[key.Value]
+		switch {
+		case allowed[key.Value]:
+			continue
+		case !known && v.AllowUnknown:
+			continue
+		case !known:
+			problems = append(problems, Problem{Path: key.Value, Message: "unknown launchd key"})
+			continue
+		}
+		problems = append(problems, checkValue(key.Value, dict.Items[i+1], spec)...)
+	}
+
+	return append(problems, checkCombinations(dict)...)
+}
+
+// checkCombinations checks required keys and keys that depend on or
+// exclude each other
+func checkCombinations(dict *Dict) []Problem {
+	var problems []Problem
+
+	if label, ok := dict.GetString("Label"); !ok || label == "" {
+		problems = append(problems, Problem{Path: "Label", Message: "required key is missing"})
+	}
+
+	_, hasProgram := dict.Get("Program")
+	args, hasArgs := dict.Get("ProgramArguments")
+	if array, ok := args.(*Array); ok && len(array.Items) == 0 {
+		problems = append(problems, Problem{Path: "ProgramArguments", Message: "must not be empty"})
+	}
+	if !hasProgram && !hasArgs {
+		problems = append(problems, Problem{Path: "Program", Message: "Program or ProgramArguments is required"})
+	}
+
+	if _, ok := dict.Get("inetdCompatibility"); ok {
+		if _, ok := dict.Get("Sockets"); !ok {
+			problems = append(problems, Problem{Path: "inetdCompatibility", Message: "requires Sockets"})
+		}
+	}
+
+	if sockets, ok := dict.Get("Sockets"); ok {
+		if socketsDict, ok := sockets.(*Dict); ok {
+			for _, name := range socketsDict.Keys() {
+				value, _ := socketsDict.Get(name)
+				for _, socket := range socketDicts(value) {
+					problems = append(problems, checkSocket("Sockets."+name, socket)...)
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// socketDicts returns the socket dicts under a Sockets entry, which is a
+// dict or an array of dicts
+func socketDicts(value interface{}) []*Dict {
+	switch v := value.(type) {
+	case *Dict:
+		return []*Dict{v}
+	case *Array:
+		dicts := make([]*Dict, 0, len(v.Items))
+		for _, item := range v.Items {
+			if dict, ok := item.(*Dict); ok {
+				dicts = append(dicts, dict)
+			}
+		}
+		return dicts
+	}
+	return nil
+}
+
+// checkSocket rejects sockets that are both Unix-domain and network
+// sockets
+func checkSocket(path string, socket *Dict) []Problem {
+	_, hasPath := socket.Get("SockPathName")
+	_, hasNode := socket.Get("SockNodeName")
+	_, hasService := socket.Get("SockServiceName")
+	family, _ := socket.GetString("SockFamily")
+
+	var problems []Problem
+	if hasPath && (hasNode || hasService) {
+		problems = append(problems, Problem{Path: path, Message: "SockPathName cannot be combined with SockNodeName or SockServiceName"})
+	}
+	if family == "Unix" && !hasPath {
+		problems = append(problems, Problem{Path: path, Message: "SockFamily Unix requires SockPathName"})
+	}
+	if hasPath && family != "" && family != "Unix" {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("SockPathName requires SockFamily Unix, not %s", family)})
+	}
+	return problems
+}
+
+// checkValue checks an element against a key spec
+func checkValue(path string, element interface{}, spec keySpec) []Problem {
+	kind := elementType(element)
+	if len(spec.types) > 0 && !contains(spec.types, kind) {
+		return []Problem{{Path: path, Message: fmt.Sprintf("must be %s, not %s", joinTypes(spec.types), kind)}}
+	}
+
+	var problems []Problem
+	switch v := element.(type) {
+	case String:
+		if len(spec.oneOf) > 0 && !contains(spec.oneOf, v.Value) {
+			problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("invalid value %q, must be one of %s", v.Value, strings.Join(spec.oneOf, ", "))})
+		}
+	case Integer:
+		if spec.min != nil && v.Value < *spec.min {
+			problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("must be at least %d", *spec.min)})
+		}
+		if spec.max != nil && v.Value > *spec.max {
+			problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("must be at most %d", *spec.max)})
+		}
+	case *Dict:
+		for i := 0; i+1 < len(v.Items); i += 2 {
+			key, ok := v.Items[i].(Key)
+			if !ok {
+				problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("expected a key at position %d", i)})
+				continue
+			}
+			child := path + "." + key.Value
+			switch {
+			case spec.fields != nil:
+				field, known := spec.fields[key.Value]
+				if !known {
+					problems = append(problems, Problem{Path: child, Message: "unknown key"})
+					continue
+				}
+				problems = append(problems, checkValue(child, v.Items[i+1], field)...)
+			case spec.values != nil:
+				problems = append(problems, checkValue(child, v.Items[i+1], *spec.values)...)
+			}
+		}
+	case *Array:
+		if spec.items != nil {
+			for i, item := range v.Items {
+				problems = append(problems, checkValue(fmt.Sprintf("%s[%d]", path, i), item, *spec.items)...)
+			}
+		}
+	}
+	return problems
+}
+
+// elementType returns the plist type name of an element
+func elementType(element interface{}) string {
+	switch element.(type) {
+	case String:
+		return typeString
+	case Integer:
+		return typeInteger
+	case Real:
+		return typeReal
+	case True, False:
+		return typeBool
+	case Date:
+		return typeDate
+	case Data:
+		return typeData
+	case *Dict:
+		return typeDict
+	case *Array:
+		return typeArray
+	default:
+		return fmt.Sprintf("%T", element)
+	}
+}
+
+// joinTypes formats accepted types as "a", "a or b" or "a, b or c"
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return strings.Join(types[:len(types)-1], ", ") + " or " + types[len(types)-1]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ProblemsError returns an error listing problems, or nil if there are none
+func ProblemsError(problems []Problem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	return fmt.Errorf("%w: %s", errs.ErrValidation, strings.Join(messages, "; "))
+}
@@ -0,0 +1,176 @@
+package plist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/config"
+	"github.com/mjmorales/daemon-control/internal/errs"
+)
+
+// validDict returns a minimal valid job dict with extra keys appended
+func validDict(build func(*Dict)) *Dict {
+	dict := &Dict{}
+	dict.AddString("Label", "com.example.test")
+	dict.AddStringArray("ProgramArguments", []string{"/usr/bin/test"})
+	if build != nil {
+		build(dict)
+	}
+	return dict
+}
+
+func TestValidator_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		dict      *Dict
+		validator Validator
+		want      []Problem
+	}{
+		{
+			name: "minimal job",
+			dict: validDict(nil),
+		},
+		{
+			name: "missing label and program",
+			dict: &Dict{},
+			want: []Problem{
+				{Path: "Label", Message: "required key is missing"},
+				{Path: "Program", Message: "Program or ProgramArguments is required"},
+			},
+		},
+		{
+			name: "wrong type",
+			dict: validDict(func(d *Dict) { d.AddString("RunAtLoad", "yes") }),
+			want: []Problem{{Path: "RunAtLoad", Message: "must be boolean, not string"}},
+		},
+		{
+			name: "keep alive accepts a boolean or conditions",
+			dict: validDict(func(d *Dict) {
+				conditions := &Dict{}
+				conditions.AddBool("Crashed", true)
+				conditions.AddDict("PathState", &Dict{Items: []interface{}{Key{Value: "/tmp/x"}, True{}}})
+				d.AddDict("KeepAlive", conditions)
+			}),
+		},
+		{
+			name: "unknown nested key",
+			dict: validDict(func(d *Dict) {
+				d.AddDict("KeepAlive", &Dict{Items: []interface{}{Key{Value: "Crashd"}, True{}}})
+			}),
+			want: []Problem{{Path: "KeepAlive.Crashd", Message: "unknown key"}},
+		},
+		{
+			name: "out of range values",
+			dict: validDict(func(d *Dict) {
+				d.AddInteger("Nice", 40)
+				d.AddDictArray("StartCalendarInterval", []*Dict{
+					{Items: []interface{}{Key{Value: "Hour"}, Integer{Value: 24}}},
+				})
+			}),
+			want: []Problem{
+				{Path: "Nice", Message: "must be at most 20"},
+				{Path: "StartCalendarInterval[0].Hour", Message: "must be at most 23"},
+			},
+		},
+		{
+			name: "enumerated value",
+			dict: validDict(func(d *Dict) { d.AddString("ProcessType", "Fast") }),
+			want: []Problem{{Path: "ProcessType", Message: `invalid value "Fast", must be one of Background, Standard, Adaptive, Interactive`}},
+		},
+		{
+			name: "environment values must be strings",
+			dict: validDict(func(d *Dict) {
+				d.AddDict("EnvironmentVariables", &Dict{Items: []interface{}{Key{Value: "PORT"}, Integer{Value: 80}}})
+			}),
+			want: []Problem{{Path: "EnvironmentVariables.PORT", Message: "must be string, not integer"}},
+		},
+		{
+			name: "inetd compatibility requires sockets",
+			dict: validDict(func(d *Dict) {
+				d.AddDict("inetdCompatibility", &Dict{Items: []interface{}{Key{Value: "Wait"}, False{}}})
+			}),
+			want: []Problem{{Path: "inetdCompatibility", Message: "requires Sockets"}},
+		},
+		{
+			name: "unix and network socket",
+			dict: validDict(func(d *Dict) {
+				socket := &Dict{}
+				socket.AddString("SockPathName", "/tmp/test.sock")
+				socket.AddString("SockServiceName", "8080")
+				d.AddDict("Sockets", &Dict{Items: []interface{}{Key{Value: "Listeners"}, socket}})
+			}),
+			want: []Problem{{Path: "Sockets.Listeners", Message: "SockPathName cannot be combined with SockNodeName or SockServiceName"}},
+		},
+		{
+			name: "duplicate key",
+			dict: validDict(func(d *Dict) { d.AddString("Label", "com.example.other") }),
+			want: []Problem{{Path: "Label", Message: "duplicate key"}},
+		},
+		{
+			name: "unknown key",
+			dict: validDict(func(d *Dict) { d.AddInteger("ProcessPriority", 5) }),
+			want: []Problem{{Path: "ProcessPriority", Message: "unknown launchd key"}},
+		},
+		{
+			name:      "allowed key",
+			dict:      validDict(func(d *Dict) { d.AddInteger("ProcessPriority", 5) }),
+			validator: Validator{AllowedKeys: []string{"ProcessPriority"}},
+		},
+		{
+			name:      "unknown keys allowed",
+			dict:      validDict(func(d *Dict) { d.AddInteger("ProcessPriority", 5) }),
+			validator: Validator{AllowUnknown: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.validator.Validate(tt.dict))
+		})
+	}
+}
+
+func TestLaunchdKeysCoverModeledKeys(t *testing.T) {
+	for key := range config.ModeledPlistKeys {
+		_, ok := launchdKeys[key]
+		assert.True(t, ok, "modeled key %s missing from launchdKeys", key)
+	}
+}
+
+func TestProblemsError(t *testing.T) {
+	assert.NoError(t, ProblemsError(nil))
+
+	err := ProblemsError([]Problem{{Path: "Nice", Message: "must be at most 20"}, {Message: "plist dict is nil"}})
+	assert.ErrorIs(t, err, errs.ErrValidation)
+	assert.EqualError(t, err, "validation failed: Nice: must be at most 20; plist dict is nil")
+}
+
+func TestGenerator_GenerateAllValidation(t *testing.T) {
+	daemons := []config.Daemon{
+		{Name: "good", Label: "com.example.good", Program: "/usr/bin/good"},
+		{Name: "bad", Label: "com.example.bad", Program: "/usr/bin/bad", Nice: intPtr(99)},
+	}
+
+	tempDir := t.TempDir()
+	_, err := NewGenerator(tempDir).WithValidation(true).GenerateAll(daemons)
+	assert.ErrorIs(t, err, errs.ErrValidation)
+	assert.ErrorContains(t, err, "bad")
+
+	// Nothing is written when any plist is invalid
+	_, statErr := os.Stat(filepath.Join(tempDir, "good.plist"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	// Extra plist keys are accepted as given
+	passthrough := config.Daemon{
+		Name:           "passthrough",
+		Label:          "com.example.passthrough",
+		Program:        "/usr/bin/passthrough",
+		ExtraPlistKeys: map[string]interface{}{"ProcessPriority": 5},
+	}
+	_, err = NewGenerator(tempDir).WithValidation(true).GenerateAll([]config.Daemon{passthrough})
+	require.NoError(t, err)
+}
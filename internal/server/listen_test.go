@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen_Unix(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "dc")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "api.sock")
+
+	listener, err := Listen("unix://"+path, "")
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, err = Listen("unix://"+path, "")
+	assert.ErrorContains(t, err, "in use")
+
+	// A socket left behind by a server that is gone is replaced
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, listener.Close())
+	listener, err = Listen("unix://"+path, "")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+}
+
+func TestListen_TCP(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		token   string
+		wantErr string
+	}{
+		{name: "loopback", address: "127.0.0.1:0"},
+		{name: "localhost", address: "localhost:0"},
+		{name: "any address with token", address: "0.0.0.0:0", token: "s3cret"},
+		{name: "any address without token", address: "0.0.0.0:0", wantErr: "requires a token"},
+		{name: "missing port", address: "127.0.0.1", wantErr: "invalid listen address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := Listen(tt.address, tt.token)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, listener.Close())
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// pollInterval is how often streamed log files are checked for new lines
+var pollInterval = 250 * time.Millisecond
+
+// tailBlockSize is how much of a log file start reads at a time, from
+// the end, looking for the lines to return
+var tailBlockSize int64 = 64 << 10
+
+// Log lines returned by GET /v1/daemons/{name}/logs, when lines is not
+// given and at most
+const (
+	defaultLogLines = 1000
+	maxLogLines     = 100000
+)
+
+// keepAliveInterval is how often an idle log stream sends a comment, so
+// that proxies and clients do not time it out
+var keepAliveInterval = 15 * time.Second
+
+// LogStream is the content of one log file
+type LogStream struct {
+	Path  string   `json:"path"`
+	Lines []string `json:"lines"`
+}
+
+// logsResponse is the body of GET /v1/daemons/{name}/logs
+type logsResponse struct {
+	Daemon string     `json:"daemon"`
+	Stdout *LogStream `json:"stdout,omitempty"`
+	Stderr *LogStream `json:"stderr,omitempty"`
+}
+
+// handleLogs returns the last lines of a daemon's logs, as many as the
+// lines parameter asks for or defaultLogLines
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	name, err := s.daemonName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	lines, err := logLines(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if lines == 0 {
+		lines = defaultLogLines
+	}
+	files, err := s.ops.LogFiles(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := logsResponse{Daemon: name}
+	for _, stream := range []struct {
+		path   string
+		target **LogStream
+	}{{files.Stdout, &response.Stdout}, {files.Stderr, &response.Stderr}} {
+		if stream.path == "" {
+			continue
+		}
+		t := &tailer{path: stream.path}
+		content, err := t.start(lines)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		*stream.target = &LogStream{Path: stream.path, Lines: content}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleLogStream streams new log lines as server-sent events named stdout
+// and stderr. The lines parameter sends that many existing lines first.
+func (s *Server) handleLogStream(w http.ResponseWriter, r *http.Request) {
+	lines, err := logLines(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	name, err := s.daemonName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	files, err := s.ops.LogFiles(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming is not supported by this connection"))
+		return
+	}
+
+	tailers := []*tailer{}
+	backlog := map[*tailer][]string{}
+	for _, t := range []*tailer{{event: "stdout", path: files.Stdout}, {event: "stderr", path: files.Stderr}} {
+		if t.path == "" {
+			continue
+		}
+		existing, err := t.start(lines)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		backlog[t] = existing
+		tailers = append(tailers, t)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, t := range tailers {
+		if err := sendEvents(w, t.event, backlog[t]); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-poll.C:
+			for _, t := range tailers {
+				fresh, err := t.poll()
+				if err != nil {
+					_ = sendEvents(w, "error", []string{err.Error()})
+					flusher.Flush()
+					return
+				}
+				if err := sendEvents(w, t.event, fresh); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// logLines reads the lines parameter, which is at most maxLogLines
+func logLines(r *http.Request) (int, error) {
+	lines, err := intParam(r, "lines")
+	if err != nil {
+		return 0, err
+	}
+	if lines > maxLogLines {
+		return 0, badRequest(fmt.Errorf("lines must be at most %d, got %d", maxLogLines, lines))
+	}
+	return lines, nil
+}
+
+// sendEvents writes each line as a server-sent event
+func sendEvents(w io.Writer, event string, lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tailer follows a log file, returning complete lines as they are
+// appended. A file that shrinks is assumed to have been truncated or
+// rotated and is read again from the start.
+type tailer struct {
+	event  string
+	path   string
+	offset int64
+}
+
+// start positions the tailer after the file's last complete line and
+// returns the last n lines before it. Only the end of the file is read, a
+// block at a time, until n lines are found. A missing file has no lines
+// yet.
+func (t *tailer) start(n int) ([]string, error) {
+	t.offset = 0
+	file, err := os.Open(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// data holds the file from pos to its end, and end is the offset after
+	// the last complete line once a newline has been read
+	var data []byte
+	pos, end := info.Size(), int64(-1)
+	for pos > 0 {
+		size := min(tailBlockSize, pos)
+		pos -= size
+		block := make([]byte, size)
+		if _, err := file.ReadAt(block, pos); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read %s: %w", t.path, err)
+		}
+		data = append(block, data...)
+
+		if end < 0 {
+			if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+				end = pos + int64(i) + 1
+			}
+		}
+		// The first line read may be partial, so one more newline than
+		// lines wanted means the last n are complete
+		if end >= 0 && bytes.Count(data[:end-pos], []byte{'\n'}) > n {
+			break
+		}
+	}
+	if end < 0 {
+		return []string{}, nil
+	}
+
+	t.offset = end
+	complete := data[:end-pos]
+	if pos > 0 {
+		complete = complete[bytes.IndexByte(complete, '\n')+1:]
+	}
+	lines := splitLines(complete)
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// poll returns the complete lines appended since the last call
+func (t *tailer) poll() ([]string, error) {
+	file, err := os.Open(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.offset = 0
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+	}
+	if info.Size() == t.offset {
+		return nil, nil
+	}
+
+	data := make([]byte, info.Size()-t.offset)
+	n, err := file.ReadAt(data, t.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	data = data[:n]
+
+	// Leave a partly written line for the next poll
+	end := bytes.LastIndexByte(data, '\n') + 1
+	t.offset += int64(end)
+	return splitLines(data[:end]), nil
+}
+
+// splitLines splits complete, newline-terminated lines
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return []string{}
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logServer serves logs for a daemon named web from the given files
+func logServer(t *testing.T, files LogFiles) *httptest.Server {
+	t.Helper()
+	ops := Operations{
+		Daemons:  func() []string { return []string{"web"} },
+		LogFiles: func(daemon string) (LogFiles, error) { return files, nil },
+	}
+	server := httptest.NewServer(New(ops, ""))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServer_Logs(t *testing.T) {
+	dir := t.TempDir()
+	stdout := filepath.Join(dir, "web.log")
+	require.NoError(t, os.WriteFile(stdout, []byte("one\ntwo\nthree\npartial"), 0600))
+	files := LogFiles{Stdout: stdout, Stderr: filepath.Join(dir, "missing.log")}
+
+	tests := []struct {
+		name       string
+		query      string
+		want       []string
+		wantStatus int
+	}{
+		{name: "default lines", want: []string{"one", "two", "three"}},
+		{name: "last lines", query: "?lines=2", want: []string{"two", "three"}},
+		{name: "too many lines", query: "?lines=100001", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := logServer(t, files)
+			resp, err := http.Get(server.URL + "/v1/daemons/web/logs" + tt.query)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			if tt.wantStatus != 0 {
+				assert.Equal(t, tt.wantStatus, resp.StatusCode)
+				return
+			}
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var body logsResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "web", body.Daemon)
+			require.NotNil(t, body.Stdout)
+			assert.Equal(t, tt.want, body.Stdout.Lines)
+			require.NotNil(t, body.Stderr)
+			assert.Empty(t, body.Stderr.Lines, "a missing log file has no lines")
+		})
+	}
+}
+
+func TestServer_LogStream(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+
+	dir := t.TempDir()
+	stdout := filepath.Join(dir, "web.log")
+	stderr := filepath.Join(dir, "web.err")
+	require.NoError(t, os.WriteFile(stdout, []byte("old 1\nold 2\n"), 0600))
+	server := logServer(t, LogFiles{Stdout: stdout, Stderr: stderr})
+
+	resp, err := http.Get(server.URL + "/v1/daemons/web/logs/stream?lines=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		var event string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				events <- event + ": " + strings.TrimPrefix(line, "data: ")
+			}
+		}
+		close(events)
+	}()
+
+	next := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+			return ""
+		}
+	}
+
+	assert.Equal(t, "stdout: old 2", next())
+
+	appendLine(t, stdout, "new 1\nhalf")
+	assert.Equal(t, "stdout: new 1", next())
+	appendLine(t, stdout, " done\n")
+	assert.Equal(t, "stdout: half done", next())
+
+	// The stderr log did not exist when the stream started
+	require.NoError(t, os.WriteFile(stderr, []byte("oops\n"), 0600))
+	assert.Equal(t, "stderr: oops", next())
+
+	// A truncated log is read from the start
+	require.NoError(t, os.WriteFile(stdout, []byte("rotated\n"), 0600))
+	assert.Equal(t, "stdout: rotated", next())
+}
+
+func TestTailer_Start(t *testing.T) {
+	defer func(size int64) { tailBlockSize = size }(tailBlockSize)
+	tailBlockSize = 4
+
+	tests := []struct {
+		name       string
+		content    string
+		n          int
+		want       []string
+		wantOffset int64
+	}{
+		{name: "last lines across blocks", content: "first line\nsecond line\nthird\n", n: 2, want: []string{"second line", "third"}, wantOffset: 29},
+		{name: "more lines than the file", content: "a\nbb\nccc\n", n: 10, want: []string{"a", "bb", "ccc"}, wantOffset: 9},
+		{name: "partial last line", content: "one\ntwo\npart", n: 1, want: []string{"two"}, wantOffset: 8},
+		{name: "no lines wanted", content: "one\ntwo\npart", n: 0, want: []string{}, wantOffset: 8},
+		{name: "no complete line", content: "partial", n: 3, want: []string{}, wantOffset: 0},
+		{name: "empty file", content: "", n: 3, want: []string{}, wantOffset: 0},
+		{name: "carriage returns", content: "one\r\ntwo\r\n", n: 5, want: []string{"one", "two"}, wantOffset: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "web.log")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			tl := &tailer{path: path}
+			lines, err := tl.start(tt.n)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, lines)
+			assert.Equal(t, tt.wantOffset, tl.offset)
+		})
+	}
+}
+
+func appendLine(t *testing.T, path, text string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString(text)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
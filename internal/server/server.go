@@ -0,0 +1,298 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/lock"
+)
+
+// Operations are the actions the API exposes. The cmd package supplies the
+// functions its commands run, so the API and the command line behave the
+// same way.
+type Operations struct {
+	// Daemons returns the names of the daemons the API may act on. Requests
+	// naming any other daemon are not found.
+	Daemons  func() []string
+	List     func(managed bool) (any, error)
+	Status   func(daemon string) (any, error)
+	Start    func(daemon string) (any, error)
+	Stop     func(daemon string) (any, error)
+	Restart  func(daemon string) (any, error)
+	Generate func(daemons []string, force bool) (any, error)
+	// LogFiles returns a daemon's log paths; either may be empty
+	LogFiles func(daemon string) (LogFiles, error)
+}
+
+// LogFiles are the log paths from a daemon's plist
+type LogFiles struct {
+	Stdout string
+	Stderr string
+}
+
+// GenerateRequest is the optional body of POST /v1/generate
+type GenerateRequest struct {
+	Daemons []string `json:"daemons"`
+	Force   bool     `json:"force"`
+}
+
+// errorResponse is the body of every failed request. ExitCode is the code
+// the equivalent command would exit with.
+type errorResponse struct {
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Server serves the control API
+type Server struct {
+	ops     Operations
+	mux     *http.ServeMux
+	handler http.Handler
+}
+
+// New creates an API server. If token is set, every request must carry it
+// as a bearer token. POST requests must send JSON, which a browser cannot
+// do across origins without asking first.
+func New(ops Operations, token string) *Server {
+	s := &Server{ops: ops, mux: http.NewServeMux()}
+	s.handler = RequireToken(token, requireJSON(s.mux))
+
+	s.mux.HandleFunc("GET /v1/daemons", s.handleList)
+	s.mux.HandleFunc("GET /v1/daemons/{name}", s.handleDaemon(ops.Status))
+	s.mux.HandleFunc("POST /v1/daemons/{name}/start", s.handleDaemon(ops.Start))
+	s.mux.HandleFunc("POST /v1/daemons/{name}/stop", s.handleDaemon(ops.Stop))
+	s.mux.HandleFunc("POST /v1/daemons/{name}/restart", s.handleDaemon(ops.Restart))
+	s.mux.HandleFunc("GET /v1/daemons/{name}/logs", s.handleLogs)
+	s.mux.HandleFunc("GET /v1/daemons/{name}/logs/stream", s.handleLogStream)
+	s.mux.HandleFunc("POST /v1/generate", s.handleGenerate)
+	return s
+}
+
+// Handle serves an additional route, such as /metrics, behind the same
+// token check
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP checks the bearer token and routes the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// RequireToken rejects requests that do not carry token as a bearer token.
+// An empty token only lets local requests through: a web page could
+// otherwise reach a loopback API through the browser, so requests sent with
+// an Origin header, and TCP requests for a Host other than the loopback
+// interface, as after DNS rebinding, are refused.
+func RequireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			if err := checkLocal(r); err != nil {
+				writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error(), ExitCode: errs.ExitFailure})
+				return
+			}
+		}
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="daemon-control"`)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing or invalid bearer token", ExitCode: errs.ExitFailure})
+			return
+		}
+		log.Debug().Str("method", r.Method).Str("path", r.URL.Path).Msg("API request")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkLocal refuses the requests a browser could send to an API without a
+// token. Browsers cannot connect to Unix sockets, so only the Origin header
+// is checked there.
+func checkLocal(r *http.Request) error {
+	if r.Header.Get("Origin") != "" {
+		return errors.New("requests from web pages are not allowed without a token")
+	}
+	if _, ok := r.Context().Value(http.LocalAddrContextKey).(*net.UnixAddr); ok {
+		return nil
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !isLoopback(strings.Trim(host, "[]")) {
+		return fmt.Errorf("host %q is not allowed without a token, use 127.0.0.1, ::1 or localhost", r.Host)
+	}
+	return nil
+}
+
+// requireJSON rejects POST requests whose body is not declared as JSON
+func requireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, errorResponse{Error: "POST requests must have Content-Type: application/json", ExitCode: errs.ExitUsage})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized reports whether the request carries the token
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	managed, err := boolParam(r, "managed")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := s.ops.List(managed)
+	respond(w, result, err)
+}
+
+// handleDaemon serves an operation on the daemon named in the path
+func (s *Server) handleDaemon(operation func(string) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, err := s.daemonName(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		result, err := operation(name)
+		respond(w, result, err)
+	}
+}
+
+// daemonName returns the daemon named in the path. The name is used in
+// file paths, and ServeMux decodes %2F inside it, so only the names of
+// known daemons are accepted.
+func (s *Server) daemonName(r *http.Request) (string, error) {
+	name := r.PathValue("name")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("%w: %q", errs.ErrNotFound, name)
+	}
+	for _, known := range s.ops.Daemons() {
+		if name == known {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", errs.ErrNotFound, name)
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, badRequest(fmt.Errorf("invalid request body: %w", err)))
+		return
+	}
+	result, err := s.ops.Generate(req.Daemons, req.Force)
+	respond(w, result, err)
+}
+
+// respond writes an operation's result, or its error
+func respond(w http.ResponseWriter, result any, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// requestError is a problem with the request itself
+type requestError struct {
+	err error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(err error) error {
+	return &requestError{err: err}
+}
+
+// statusCode maps an operation error to an HTTP status
+func statusCode(err error) int {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errs.ErrNotInstalled),
+		errors.Is(err, errs.ErrAlreadyInstalled),
+		errors.Is(err, errs.ErrAlreadyRunning),
+		errors.Is(err, errs.ErrNotRunning),
+		errors.Is(err, lock.ErrLocked):
+		return http.StatusConflict
+	case errors.Is(err, errs.ErrInvalidConfig), errors.Is(err, errs.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := errs.ExitCode(err)
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		code = errs.ExitUsage
+	}
+	writeJSON(w, statusCode(err), errorResponse{Error: err.Error(), ExitCode: code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(body); err != nil {
+		log.Warn().Err(err).Msg("Failed to write API response")
+	}
+}
+
+// boolParam reads an optional boolean query parameter
+func boolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, badRequest(fmt.Errorf("invalid %s: %q", name, value))
+	}
+	return b, nil
+}
+
+// intParam reads an optional non-negative integer query parameter
+func intParam(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, badRequest(fmt.Errorf("invalid %s: %q", name, value))
+	}
+	return n, nil
+}
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/errs"
+	"github.com/mjmorales/daemon-control/internal/lock"
+)
+
+// fakeOperations records calls, knows the daemons web and missing, and fails
+// for the daemon named "missing"
+func fakeOperations(calls *[]string) Operations {
+	daemonOp := func(action string) func(string) (any, error) {
+		return func(daemon string) (any, error) {
+			*calls = append(*calls, action+" "+daemon)
+			if daemon == "missing" {
+				return nil, fmt.Errorf("%w: %s", errs.ErrNotFound, daemon)
+			}
+			return map[string]string{"daemon": daemon, "action": action}, nil
+		}
+	}
+	return Operations{
+		Daemons: func() []string { return []string{"web", "missing"} },
+		List: func(managed bool) (any, error) {
+			*calls = append(*calls, fmt.Sprintf("list managed=%t", managed))
+			return []string{"web"}, nil
+		},
+		Status:  daemonOp("status"),
+		Start:   daemonOp("start"),
+		Stop:    daemonOp("stop"),
+		Restart: daemonOp("restart"),
+		Generate: func(daemons []string, force bool) (any, error) {
+			*calls = append(*calls, fmt.Sprintf("generate %v force=%t", daemons, force))
+			return map[string]int{"added": len(daemons)}, nil
+		},
+		LogFiles: func(daemon string) (LogFiles, error) {
+			return LogFiles{}, fmt.Errorf("%w: %s", errs.ErrNotFound, daemon)
+		},
+	}
+}
+
+// apiRequest builds a request as a local client sends it: to the loopback
+// interface, with POST bodies declared as JSON
+func apiRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, "http://127.0.0.1:8080"+path, strings.NewReader(body))
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+func TestServer_Routes(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCall   string
+	}{
+		{name: "list", method: http.MethodGet, path: "/v1/daemons", wantStatus: http.StatusOK, wantCall: "list managed=false"},
+		{name: "list managed", method: http.MethodGet, path: "/v1/daemons?managed=true", wantStatus: http.StatusOK, wantCall: "list managed=true"},
+		{name: "invalid managed", method: http.MethodGet, path: "/v1/daemons?managed=maybe", wantStatus: http.StatusBadRequest},
+		{name: "status", method: http.MethodGet, path: "/v1/daemons/web", wantStatus: http.StatusOK, wantCall: "status web"},
+		{name: "start", method: http.MethodPost, path: "/v1/daemons/web/start", wantStatus: http.StatusOK, wantCall: "start web"},
+		{name: "stop", method: http.MethodPost, path: "/v1/daemons/web/stop", wantStatus: http.StatusOK, wantCall: "stop web"},
+		{name: "restart", method: http.MethodPost, path: "/v1/daemons/web/restart", wantStatus: http.StatusOK, wantCall: "restart web"},
+		{name: "start needs POST", method: http.MethodGet, path: "/v1/daemons/web/start", wantStatus: http.StatusMethodNotAllowed},
+		{name: "generate all", method: http.MethodPost, path: "/v1/generate", wantStatus: http.StatusOK, wantCall: "generate [] force=false"},
+		{name: "generate some", method: http.MethodPost, path: "/v1/generate", body: `{"daemons":["web"],"force":true}`, wantStatus: http.StatusOK, wantCall: "generate [web] force=true"},
+		{name: "generate invalid body", method: http.MethodPost, path: "/v1/generate", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "unknown daemon", method: http.MethodGet, path: "/v1/daemons/missing", wantStatus: http.StatusNotFound, wantCall: "status missing"},
+		{name: "undefined daemon", method: http.MethodPost, path: "/v1/daemons/api/start", wantStatus: http.StatusNotFound},
+		{name: "encoded slash", method: http.MethodPost, path: "/v1/daemons/..%2F..%2Ftmp%2Fevil/start", wantStatus: http.StatusNotFound},
+		{name: "encoded backslash", method: http.MethodGet, path: "/v1/daemons/..%5Cevil", wantStatus: http.StatusNotFound},
+		{name: "encoded slash logs", method: http.MethodGet, path: "/v1/daemons/..%2Fevil/logs", wantStatus: http.StatusNotFound},
+		{name: "unknown route", method: http.MethodGet, path: "/v2/daemons", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			s := New(fakeOperations(&calls), "")
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, apiRequest(tt.method, tt.path, tt.body))
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCall == "" {
+				assert.Empty(t, calls)
+			} else {
+				assert.Equal(t, []string{tt.wantCall}, calls)
+			}
+		})
+	}
+}
+
+func TestServer_ErrorBody(t *testing.T) {
+	var calls []string
+	s := New(fakeOperations(&calls), "")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, apiRequest(http.MethodPost, "/v1/daemons/missing/start", ""))
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "daemon not found: missing", body.Error)
+	assert.Equal(t, errs.ExitNotFound, body.ExitCode)
+}
+
+func TestServer_Auth(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", header: "Bearer s3cret", wantStatus: http.StatusOK},
+		{name: "no token", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic s3cret", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			s := New(fakeOperations(&calls), "s3cret")
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/daemons", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.Empty(t, calls)
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestServer_LocalOnly(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		host       string
+		origin     string
+		unix       bool
+		wantStatus int
+	}{
+		{name: "loopback address", host: "127.0.0.1:8080", wantStatus: http.StatusOK},
+		{name: "localhost", host: "localhost:8080", wantStatus: http.StatusOK},
+		{name: "IPv6 loopback", host: "[::1]:8080", wantStatus: http.StatusOK},
+		{name: "rebound host", host: "attacker.example:8080", wantStatus: http.StatusForbidden},
+		{name: "origin", host: "127.0.0.1:8080", origin: "https://attacker.example", wantStatus: http.StatusForbidden},
+		{name: "unix socket", host: "unix", unix: true, wantStatus: http.StatusOK},
+		{name: "origin over unix socket", host: "unix", origin: "https://attacker.example", unix: true, wantStatus: http.StatusForbidden},
+		{name: "any host with token", token: "s3cret", host: "daemons.example:8080", origin: "https://console.example", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			s := New(fakeOperations(&calls), tt.token)
+
+			req := apiRequest(http.MethodGet, "/v1/daemons", "")
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.unix {
+				addr := &net.UnixAddr{Name: "/tmp/daemon-control.sock", Net: "unix"}
+				req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, addr))
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Empty(t, calls)
+			}
+		})
+	}
+}
+
+func TestServer_ContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantStatus  int
+	}{
+		{name: "json", contentType: "application/json", wantStatus: http.StatusOK},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", wantStatus: http.StatusOK},
+		{name: "missing", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "form", contentType: "application/x-www-form-urlencoded", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "text", contentType: "text/plain", wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			s := New(fakeOperations(&calls), "")
+
+			req := apiRequest(http.MethodPost, "/v1/daemons/web/start", "")
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.Empty(t, calls)
+			}
+		})
+	}
+}
+
+func TestServer_Handle(t *testing.T) {
+	var calls []string
+	s := New(fakeOperations(&calls), "s3cret")
+	s.Handle("GET /metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("daemon_control_up 1\n"))
+	}))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "added routes need the token too")
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "daemon_control_up 1\n", rec.Body.String())
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: web", errs.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: web", errs.ErrNotRunning), http.StatusConflict},
+		{fmt.Errorf("%w: web", errs.ErrAlreadyRunning), http.StatusConflict},
+		{fmt.Errorf("%w: held", lock.ErrLocked), http.StatusConflict},
+		{fmt.Errorf("%w: bad", errs.ErrInvalidConfig), http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: bad", errs.ErrValidation), http.StatusUnprocessableEntity},
+		{badRequest(fmt.Errorf("bad")), http.StatusBadRequest},
+		{errs.Backend("launchctl load", fmt.Errorf("exit 1")), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.want, statusCode(tt.err))
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mjmorales/daemon-control/internal/history"
+	"github.com/mjmorales/daemon-control/internal/utils"
+)
+
+// Version is the state file format written by this build
+const Version = 1
+
+// Entry records a daemon installed by daemon-control
+type Entry struct {
+	Daemon      string    `json:"daemon" yaml:"daemon"`
+	Label       string    `json:"label" yaml:"label"`
+	ConfigPath  string    `json:"config_path" yaml:"config_path"`
+	PlistPath   string    `json:"plist_path" yaml:"plist_path"`
+	PlistHash   string    `json:"plist_hash" yaml:"plist_hash"`
+	InstalledAt time.Time `json:"installed_at" yaml:"installed_at"`
+	Backend     string    `json:"backend" yaml:"backend"`
+	Domain      string    `json:"domain" yaml:"domain"`
+}
+
+// State is the set of daemons daemon-control has installed, by label
+type State struct {
+	Version int              `json:"version"`
+	Daemons map[string]Entry `json:"daemons"`
+}
+
+// Entries returns the recorded daemons sorted by name
+func (s *State) Entries() []Entry {
+	entries := make([]Entry, 0, len(s.Daemons))
+	for _, entry := range s.Daemons {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Daemon != entries[j].Daemon {
+			return entries[i].Daemon < entries[j].Daemon
+		}
+		return entries[i].Label < entries[j].Label
+	})
+	return entries
+}
+
+// Find returns the entry for a daemon name
+func (s *State) Find(daemon string) (Entry, bool) {
+	for _, entry := range s.Entries() {
+		if entry.Daemon == daemon {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Record adds or replaces the entry for its label. A daemon is installed
+// under one label at a time, so entries for the same daemon under another
+// label are dropped.
+func (s *State) Record(entry Entry) {
+	for label, existing := range s.Daemons {
+		if existing.Daemon == entry.Daemon && label != entry.Label {
+			delete(s.Daemons, label)
+		}
+	}
+	s.Daemons[entry.Label] = entry
+}
+
+// Remove drops the entry for a label
+func (s *State) Remove(label string) {
+	delete(s.Daemons, label)
+}
+
+// Store reads and writes the state file
+type Store struct {
+	path string
+}
+
+// NewStore creates a store for the state file at path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the state file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load reads the state file. A missing file is an empty state.
+func (s *Store) Load() (*State, error) {
+	state := &State{Version: Version, Daemons: map[string]Entry{}}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("corrupt state file %s: %w", s.path, err)
+	}
+	if state.Version > Version {
+		return nil, fmt.Errorf("state file %s has version %d, newer than supported version %d", s.path, state.Version, Version)
+	}
+	if state.Daemons == nil {
+		state.Daemons = map[string]Entry{}
+	}
+	return state, nil
+}
+
+// Save writes the state file atomically
+func (s *Store) Save(state *State) error {
+	state.Version = Version
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return utils.WriteFileAtomic(s.path, append(data, '\n'), 0600)
+}
+
+// Update loads the state, applies fn and saves the result
+func (s *Store) Update(fn func(*State)) error {
+	state, err := s.Load()
+	if err != nil {
+		return err
+	}
+	fn(state)
+	return s.Save(state)
+}
+
+// Drift describes how an installed daemon compares with its recorded state
+type Drift string
+
+const (
+	// DriftNone means the installed plist is the one daemon-control
+	// installed and matches the daemons directory
+	DriftNone Drift = "in-sync"
+	// DriftMissing means the installed plist was removed outside
+	// daemon-control
+	DriftMissing Drift = "missing"
+	// DriftModified means the installed plist was changed outside
+	// daemon-control
+	DriftModified Drift = "modified"
+	// DriftOutdated means the plist in the daemons directory has changed
+	// since it was installed
+	DriftOutdated Drift = "outdated"
+)
+
+// CheckDrift compares an entry with the installed plist and source, the
+// daemon's current plist in the daemons directory. A nil source is not
+// compared.
+func CheckDrift(entry Entry, source []byte) Drift {
+	installed, err := os.ReadFile(entry.PlistPath)
+	if err != nil {
+		return DriftMissing
+	}
+	if history.Hash(installed) != entry.PlistHash {
+		return DriftModified
+	}
+	if source != nil && history.Hash(source) != entry.PlistHash {
+		return DriftOutdated
+	}
+	return DriftNone
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjmorales/daemon-control/internal/history"
+)
+
+func TestStore_LoadSave(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "nested", "state.json"))
+
+	// A missing file is an empty state
+	st, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, st.Entries())
+
+	installedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Update(func(s *State) {
+		s.Record(Entry{Daemon: "web", Label: "com.example.web", PlistHash: "abc", InstalledAt: installedAt, Backend: "launchctl", Domain: "gui/501"})
+		s.Record(Entry{Daemon: "api", Label: "com.example.api"})
+	}))
+
+	st, err = store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, Version, st.Version)
+	entries := st.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "api", entries[0].Daemon)
+	assert.Equal(t, installedAt, entries[1].InstalledAt)
+
+	entry, ok := st.Find("web")
+	assert.True(t, ok)
+	assert.Equal(t, "com.example.web", entry.Label)
+	_, ok = st.Find("missing")
+	assert.False(t, ok)
+
+	require.NoError(t, store.Update(func(s *State) { s.Remove("com.example.api") }))
+	st, err = store.Load()
+	require.NoError(t, err)
+	assert.Len(t, st.Daemons, 1)
+}
+
+func TestStore_LoadInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantError string
+	}{
+		{name: "corrupt", content: "{", wantError: "corrupt state file"},
+		{name: "newer version", content: `{"version": 99}`, wantError: "newer than supported version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			_, err := NewStore(path).Load()
+			assert.ErrorContains(t, err, tt.wantError)
+		})
+	}
+}
+
+func TestState_RecordRelabel(t *testing.T) {
+	st := &State{Daemons: map[string]Entry{}}
+	st.Record(Entry{Daemon: "web", Label: "com.example.web"})
+	st.Record(Entry{Daemon: "web", Label: "com.example.website"})
+
+	assert.Len(t, st.Daemons, 1)
+	assert.Contains(t, st.Daemons, "com.example.website")
+}
+
+func TestCheckDrift(t *testing.T) {
+	installed := []byte("installed")
+	changed := []byte("changed")
+
+	tests := []struct {
+		name      string
+		installed []byte
+		source    []byte
+		want      Drift
+	}{
+		{name: "in sync", installed: installed, source: installed, want: DriftNone},
+		{name: "no source", installed: installed, want: DriftNone},
+		{name: "missing", source: installed, want: DriftMissing},
+		{name: "modified", installed: changed, source: installed, want: DriftModified},
+		{name: "outdated", installed: installed, source: changed, want: DriftOutdated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "com.example.web.plist")
+			if tt.installed != nil {
+				require.NoError(t, os.WriteFile(path, tt.installed, 0600))
+			}
+			entry := Entry{Daemon: "web", Label: "com.example.web", PlistPath: path, PlistHash: history.Hash(installed)}
+
+			assert.Equal(t, tt.want, CheckDrift(entry, tt.source))
+		})
+	}
+}
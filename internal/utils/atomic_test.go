@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.plist")
+
+	require.NoError(t, WriteFileAtomic(path, []byte("first"), 0600))
+	require.NoError(t, WriteFileAtomic(path, []byte("second"), 0644))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteFileAtomic_Failure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.plist")
+	require.NoError(t, os.WriteFile(path, []byte("original"), 0600))
+
+	// Renaming over a directory fails after the temporary file is written
+	target := filepath.Join(dir, "target")
+	require.NoError(t, os.Mkdir(target, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(target, "keep"), nil, 0600))
+	assert.Error(t, WriteFileAtomic(target, []byte("new"), 0600))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "temporary file should be removed")
+
+	// A missing directory fails without touching anything
+	assert.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "test.plist"), []byte("new"), 0600))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(data))
+}
@@ -0,0 +1,117 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher reports changes to a set of files once they have settled.
+// Editors often save by writing a new file and renaming it over the old
+// one, so the directories holding the files are watched rather than the
+// files themselves. A symlinked file is watched both where the link is and
+// where it points, since edits land in the target's directory.
+type Watcher struct {
+	files    map[string]bool
+	debounce time.Duration
+	watcher  *fsnotify.Watcher
+}
+
+// New starts watching files. Changes are reported once no further change
+// has been seen for the debounce interval.
+func New(files []string, debounce time.Duration) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start file watcher: %w", err)
+	}
+
+	w := &Watcher{files: map[string]bool{}, debounce: debounce, watcher: watcher}
+	dirs := map[string]bool{}
+	for _, file := range files {
+		paths, err := watchPaths(file)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		for _, path := range paths {
+			w.files[path] = true
+
+			dir := filepath.Dir(path)
+			if dirs[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				watcher.Close()
+				return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+			dirs[dir] = true
+		}
+	}
+	return w, nil
+}
+
+// watchPaths returns the absolute path of file and, when it differs, the
+// path with every symlink resolved. A file that does not exist yet is
+// only watched where it will be created.
+func watchPaths(file string) ([]string, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{path}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+		paths = append(paths, resolved)
+	}
+	return paths, nil
+}
+
+// Run calls onChange after each settled burst of changes until ctx is
+// done. onChange runs on the caller's goroutine; changes made while it
+// runs are reported by the next call.
+func (w *Watcher) Run(ctx context.Context, onChange func()) error {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if w.relevant(event) {
+				timer.Reset(w.debounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// Dropped events may have included a change
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return fmt.Errorf("file watcher failed: %w", err)
+			}
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
+
+// relevant reports whether an event may have changed a watched file
+func (w *Watcher) relevant(event fsnotify.Event) bool {
+	if !w.files[filepath.Clean(event.Name)] {
+		return false
+	}
+	return event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) || event.Has(fsnotify.Remove)
+}
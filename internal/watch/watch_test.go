@@ -0,0 +1,123 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDebounce = 100 * time.Millisecond
+
+// run starts w in the background and returns a channel that receives a
+// value for each reported change
+func run(t *testing.T, w *Watcher) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func() { changes <- struct{}{} })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+		assert.NoError(t, w.Close())
+	})
+	return changes
+}
+
+func TestWatcher_Debounce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "daemons.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("daemons: []\n"), 0600))
+
+	w, err := New([]string{path}, testDebounce)
+	require.NoError(t, err)
+	changes := run(t, w)
+
+	// A burst of writes is reported once
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(path, []byte("daemons: []\n"), 0600))
+		time.Sleep(testDebounce / 5)
+	}
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not reported")
+	}
+	select {
+	case <-changes:
+		t.Fatal("burst of writes was reported more than once")
+	case <-time.After(3 * testDebounce):
+	}
+}
+
+func TestWatcher_Rename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "daemons.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("daemons: []\n"), 0600))
+
+	w, err := New([]string{path}, testDebounce)
+	require.NoError(t, err)
+	changes := run(t, w)
+
+	// Editors save by renaming a new file over the old one
+	tmp := filepath.Join(dir, ".daemons.yaml.swp")
+	require.NoError(t, os.WriteFile(tmp, []byte("version: 1\n"), 0600))
+	require.NoError(t, os.Rename(tmp, path))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replaced file was not reported")
+	}
+}
+
+func TestWatcher_Symlink(t *testing.T) {
+	// The configuration lives in a dotfiles directory and is linked in
+	target := filepath.Join(t.TempDir(), "daemons.yaml")
+	require.NoError(t, os.WriteFile(target, []byte("daemons: []\n"), 0600))
+	link := filepath.Join(t.TempDir(), "daemons.yaml")
+	require.NoError(t, os.Symlink(target, link))
+
+	w, err := New([]string{link}, testDebounce)
+	require.NoError(t, err)
+	changes := run(t, w)
+
+	// Saving through the link replaces the target in its own directory
+	tmp := target + ".swp"
+	require.NoError(t, os.WriteFile(tmp, []byte("version: 1\n"), 0600))
+	require.NoError(t, os.Rename(tmp, target))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change to the link target was not reported")
+	}
+}
+
+func TestWatcher_IgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "daemons.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("daemons: []\n"), 0600))
+
+	w, err := New([]string{path}, testDebounce)
+	require.NoError(t, err)
+
+	var count atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), 5*testDebounce)
+	defer cancel()
+	go func() {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("x\n"), 0600))
+	}()
+	require.NoError(t, w.Run(ctx, func() { count.Add(1) }))
+	require.NoError(t, w.Close())
+
+	assert.Zero(t, count.Load())
+}